@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/quotestub"
+)
+
+// quotestub serves canned Alpha Vantage and Finnhub quotes for offline development.
+// Point the backend at it with ALPHA_VANTAGE_URL / FINNHUB_URL.
+func main() {
+	port := getEnv("QUOTESTUB_PORT", "8090")
+
+	server := quotestub.NewServer(nil)
+	if limit := os.Getenv("QUOTESTUB_RATE_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			log.Fatalf("Invalid QUOTESTUB_RATE_LIMIT: %v", err)
+		}
+		server.SetRateLimit(n)
+	}
+
+	log.Printf("🧪 Quote stub listening on :%s", port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), server); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
+
+func getEnv(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
@@ -2,42 +2,132 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/alert"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/corporate"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/history"
 	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
 	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/service"
 	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
 )
 
 func main() {
-	log.Println("Starting Portfolio Tracker HTTP Server...")
+	log.Println("Starting Portfolio Tracker Server...")
 
 	// Load configuration from environment
 	httpPort := getEnv("HTTP_PORT", "8080")
+	grpcPort := getEnv("GRPC_PORT", "50051")
 
-	// Use mock mode
-	log.Println("⚠️  Using mock data mode for testing")
+	mockMode, _ := strconv.ParseBool(getEnv("MOCK_MODE", "false"))
 
-	// Initialize repositories (use mock repos)
-	var stockRepo *repository.StockRepository
-	var alertRepo *repository.AlertRepository
-	stockRepo = nil
-	alertRepo = nil
+	appCtx, cancelApp := context.WithCancel(context.Background())
+	defer cancelApp()
 
-	// Initialize price manager (mock mode)
+	var stockRepo repository.StockStore
+	var alertRepo repository.AlertStore
+	var cashRepo repository.CashStore
+	var userRepo repository.UserStore
+	var historyRepo repository.PriceHistoryStore
+	var actionRepo repository.CorporateActionStore
+	var portfolioRepo repository.PortfolioStore
 	var priceManager *stream.PriceManager
-	priceManager = nil
+	var rdb *redis.Client
 
-	// Initialize HTTP service
-	portfolioService := service.NewPortfolioService(stockRepo, alertRepo, priceManager)
+	priceSource, err := newPriceSource()
+	if err != nil {
+		log.Fatalf("Failed to configure price source: %v", err)
+	}
+	fxSource, err := newFXSource()
+	if err != nil {
+		log.Fatalf("Failed to configure FX source: %v", err)
+	}
+	retention, err := retentionPolicy()
+	if err != nil {
+		log.Fatalf("Failed to configure history retention: %v", err)
+	}
+
+	if mockMode {
+		log.Println("⚠️  MOCK_MODE enabled - using in-memory storage instead of Postgres and Redis")
+		memStocks := repository.NewMemoryStockRepository()
+		memAlerts := repository.NewMemoryAlertRepository()
+		memCash := repository.NewMemoryCashRepository()
+		memPortfolios := repository.NewMemoryPortfolioRepository(memStocks, memAlerts, memCash)
+		if err := seedDemoData(appCtx, memPortfolios, memStocks, memAlerts, memCash); err != nil {
+			log.Fatalf("Failed to seed demo data: %v", err)
+		}
+		memHistory := repository.NewMemoryPriceHistoryRepository()
+		stockRepo = memStocks
+		alertRepo = memAlerts
+		cashRepo = memCash
+		portfolioRepo = memPortfolios
+		userRepo = repository.NewMemoryUserRepository()
+		historyRepo = memHistory
+		actionRepo = repository.NewMemoryCorporateActionRepository(memStocks, memAlerts, memCash, memHistory)
+		priceManager = stream.NewPriceManager(nil, priceSource)
+	} else {
+		db, err := connectPostgres(appCtx)
+		if err != nil {
+			log.Fatalf("Failed to connect to Postgres: %v", err)
+		}
+		defer db.Close()
+
+		rdb, err = connectRedis(appCtx)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		defer rdb.Close()
+
+		stockRepo = repository.NewStockRepository(db)
+		alertRepo = repository.NewAlertRepository(db)
+		cashRepo = repository.NewCashRepository(db)
+		portfolioRepo = repository.NewPortfolioRepository(db)
+		userRepo = repository.NewUserRepository(db)
+		historyRepo = repository.NewPriceHistoryRepository(db)
+		actionRepo = repository.NewCorporateActionRepository(db)
+
+		switch fanout := getEnv("PRICE_FANOUT", "local"); fanout {
+		case "local":
+			priceManager = stream.NewPriceManager(rdb, priceSource)
+		case "redis":
+			// One replica runs priceSource and publishes to Redis; every replica consumes from it
+			log.Println("📡 Sharing prices across instances through Redis")
+			go stream.NewRedisProducer(rdb, priceSource).Run(appCtx)
+			priceManager = stream.NewPriceManager(rdb, stream.NewRedisSource(rdb))
+		default:
+			log.Fatalf("Unknown PRICE_FANOUT %q", fanout)
+		}
+	}
+
+	go priceManager.Start(appCtx)
+	go history.NewRecorder(historyRepo, priceManager, retention).Start(appCtx)
+
+	alertEngine := alert.NewEngine(alertRepo, priceManager, rdb)
+	go alertEngine.Start(appCtx)
+
+	corporateActions := corporate.NewProcessor(actionRepo, priceManager, alertEngine)
+	go corporateActions.Start(appCtx)
+
+	// Initialize service (serves both REST and gRPC)
+	portfolioService := service.NewPortfolioService(stockRepo, alertRepo, cashRepo, portfolioRepo, userRepo, historyRepo, priceManager, fxSource, alertEngine, corporateActions)
 
 	// Create HTTP server with Gorilla Mux
 	router := mux.NewRouter()
@@ -61,7 +151,77 @@ func main() {
 
 	// API routes with CORS
 	router.HandleFunc("/api/portfolio", corsWrapper(portfolioService.GetPortfolioHTTP)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/portfolios", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			portfolioService.GetPortfoliosHTTP(w, r)
+		} else if r.Method == "POST" {
+			portfolioService.CreatePortfolioHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/api/portfolios/{id}", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			portfolioService.UpdatePortfolioHTTP(w, r)
+		} else if r.Method == "DELETE" {
+			portfolioService.DeletePortfolioHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("PUT", "DELETE", "OPTIONS")
 	router.HandleFunc("/api/stocks", corsWrapper(portfolioService.AddStockHTTP)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/stocks/{symbol}", corsWrapper(portfolioService.RemoveStockHTTP)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/transactions", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			portfolioService.GetTransactionsHTTP(w, r)
+		} else if r.Method == "POST" {
+			portfolioService.RecordTransactionHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/api/transactions/{id}", corsWrapper(portfolioService.DeleteTransactionHTTP)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/cash", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			portfolioService.GetCashEntriesHTTP(w, r)
+		} else if r.Method == "POST" {
+			portfolioService.RecordCashEntryHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/api/cash/{id}", corsWrapper(portfolioService.DeleteCashEntryHTTP)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/corporate-actions", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			portfolioService.GetCorporateActionsHTTP(w, r)
+		} else if r.Method == "POST" {
+			portfolioService.CreateCorporateActionHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/api/settings", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			portfolioService.GetUserSettingsHTTP(w, r)
+		} else if r.Method == "PUT" {
+			portfolioService.UpdateUserSettingsHTTP(w, r)
+		} else if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})).Methods("GET", "PUT", "OPTIONS")
+	router.HandleFunc("/api/gains", corsWrapper(portfolioService.GetRealizedGainsHTTP)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/alerts", corsWrapper(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			portfolioService.GetAlertsHTTP(w, r)
@@ -84,6 +244,23 @@ func main() {
 		Handler: router,
 	}
 
+	// Create gRPC server (reached by the frontend through Envoy's gRPC-Web filter)
+	grpcServer := grpc.NewServer()
+	pb.RegisterPortfolioServiceServer(grpcServer, portfolioService)
+	reflection.Register(grpcServer)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+	if err != nil {
+		log.Fatalf("Failed to listen on :%s: %v", grpcPort, err)
+	}
+
+	go func() {
+		log.Printf("🚀 gRPC Server listening on :%s", grpcPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("Failed to serve gRPC: %v", err)
+		}
+	}()
+
 	go func() {
 		log.Printf("🚀 HTTP Server listening on :%s", httpPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -93,11 +270,24 @@ func main() {
 
 	<-stop
 	log.Println("\n🛑 Shutting down gracefully...")
+	cancelApp()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
+
+	// GracefulStop waits for open streams, so fall back to a hard stop once the deadline passes
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 	log.Println("✓ Server stopped")
 }
 
@@ -107,3 +297,285 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// newPriceSource builds the PriceSource selected by PRICE_SOURCE
+func newPriceSource() (stream.PriceSource, error) {
+	interval, err := time.ParseDuration(getEnv("PRICE_UPDATE_INTERVAL", "2s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PRICE_UPDATE_INTERVAL: %w", err)
+	}
+
+	switch name := getEnv("PRICE_SOURCE", "random"); name {
+	case "random":
+		return stream.NewRandomWalkSource(stream.DefaultBasePrices, interval), nil
+	case "simulator":
+		seed, err := strconv.ParseInt(getEnv("SIMULATOR_SEED", "42"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SIMULATOR_SEED: %w", err)
+		}
+		config := stream.DefaultSimulatorConfig(seed, interval)
+		config.Live = true
+		if step := os.Getenv("SIMULATOR_STEP"); step != "" {
+			if config.Step, err = time.ParseDuration(step); err != nil {
+				return nil, fmt.Errorf("invalid SIMULATOR_STEP: %w", err)
+			}
+		}
+		return stream.NewSimulatorSource(config), nil
+	case "replay":
+		config := stream.ReplayConfig{Path: os.Getenv("REPLAY_FILE")}
+		if config.Speed, err = strconv.ParseFloat(getEnv("REPLAY_SPEED", "1"), 64); err != nil {
+			return nil, fmt.Errorf("invalid REPLAY_SPEED: %w", err)
+		}
+		if config.Loop, err = strconv.ParseBool(getEnv("REPLAY_LOOP", "false")); err != nil {
+			return nil, fmt.Errorf("invalid REPLAY_LOOP: %w", err)
+		}
+		if start := os.Getenv("REPLAY_START"); start != "" {
+			if config.Start, err = time.Parse(time.RFC3339, start); err != nil {
+				return nil, fmt.Errorf("invalid REPLAY_START: %w", err)
+			}
+		}
+		if end := os.Getenv("REPLAY_END"); end != "" {
+			if config.End, err = time.Parse(time.RFC3339, end); err != nil {
+				return nil, fmt.Errorf("invalid REPLAY_END: %w", err)
+			}
+		}
+		return stream.NewReplaySource(config)
+	case "alphavantage":
+		rpm, err := strconv.Atoi(getEnv("ALPHA_VANTAGE_REQUESTS_PER_MINUTE", strconv.Itoa(stream.AlphaVantageFreeRequestsPerMinute)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALPHA_VANTAGE_REQUESTS_PER_MINUTE: %w", err)
+		}
+		client := stream.NewAlphaVantageClient(getEnv("ALPHA_VANTAGE_API_KEY", "demo"), os.Getenv("ALPHA_VANTAGE_URL"))
+		return stream.NewPollingSource(client, rpm, defaultSymbols()), nil
+	case "finnhub":
+		apiKey := os.Getenv("FINNHUB_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("FINNHUB_API_KEY is required for the finnhub price source")
+		}
+		rpm, err := strconv.Atoi(getEnv("FINNHUB_REQUESTS_PER_MINUTE", strconv.Itoa(stream.FinnhubFreeRequestsPerMinute)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid FINNHUB_REQUESTS_PER_MINUTE: %w", err)
+		}
+		client := stream.NewFinnhubClient(apiKey, os.Getenv("FINNHUB_URL"))
+		return stream.NewPollingSource(client, rpm, defaultSymbols()), nil
+	default:
+		return nil, fmt.Errorf("unknown PRICE_SOURCE %q", name)
+	}
+}
+
+// newFXSource builds the fx.RateSource selected by FX_SOURCE. FX_RATES overrides what one
+// unit of a currency is worth in USD, e.g. "EUR=1.10,GBP=1.25".
+func newFXSource() (fx.RateSource, error) {
+	rates := make(map[string]float64, len(fx.DefaultUSDRates))
+	for currency, rate := range fx.DefaultUSDRates {
+		rates[currency] = rate
+	}
+	if value := os.Getenv("FX_RATES"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			code, rate, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid FX_RATES entry %q", pair)
+			}
+			currency, err := fx.ParseCurrency(code)
+			if err != nil {
+				return nil, fmt.Errorf("invalid FX_RATES entry %q: %w", pair, err)
+			}
+			usd, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+			if err != nil || usd <= 0 {
+				return nil, fmt.Errorf("invalid FX_RATES entry %q", pair)
+			}
+			rates[currency] = usd
+		}
+	}
+
+	switch name := getEnv("FX_SOURCE", "fixed"); name {
+	case "fixed":
+		return fx.NewFixedSource(rates), nil
+	case "simulated":
+		seed, err := strconv.ParseInt(getEnv("FX_SEED", "42"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FX_SEED: %w", err)
+		}
+		swing, err := strconv.ParseFloat(getEnv("FX_SWING", "0.1"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FX_SWING: %w", err)
+		}
+		return fx.NewSimulatedSource(rates, seed, swing), nil
+	default:
+		return nil, fmt.Errorf("unknown FX_SOURCE %q", name)
+	}
+}
+
+// retentionPolicy reads HISTORY_RETENTION_TICKS and HISTORY_RETENTION_<interval>
+// (e.g. HISTORY_RETENTION_1M) on top of the default policy; 0 keeps data forever
+func retentionPolicy() (history.RetentionPolicy, error) {
+	policy := history.DefaultRetentionPolicy()
+
+	if value := os.Getenv("HISTORY_RETENTION_TICKS"); value != "" {
+		keep, err := time.ParseDuration(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid HISTORY_RETENTION_TICKS: %w", err)
+		}
+		policy.Ticks = keep
+	}
+	for _, interval := range history.Intervals {
+		key := "HISTORY_RETENTION_" + strings.ToUpper(string(interval))
+		if value := os.Getenv(key); value != "" {
+			keep, err := time.ParseDuration(value)
+			if err != nil {
+				return policy, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			policy.Bars[interval] = keep
+		}
+	}
+
+	return policy, nil
+}
+
+// defaultSymbols lists the symbols every price source tracks from startup
+func defaultSymbols() []string {
+	symbols := make([]string, 0, len(stream.DefaultBasePrices))
+	for symbol := range stream.DefaultBasePrices {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// seedDemoData loads the same demo portfolio as the migrations into the in-memory stores
+func seedDemoData(ctx context.Context, portfolios repository.PortfolioStore, stocks repository.StockStore, alerts repository.AlertStore, cash repository.CashStore) error {
+	const demoUser = "demo-user-1"
+	now := time.Now().Unix()
+
+	portfolio, err := portfolios.DefaultPortfolio(ctx, demoUser)
+	if err != nil {
+		return err
+	}
+
+	holdings := []struct {
+		symbol, name  string
+		quantity      float64
+		purchasePrice float64
+	}{
+		{"AAPL", "Apple Inc.", 10, 150.00},
+		{"GOOGL", "Alphabet Inc.", 5, 2800.00},
+		{"MSFT", "Microsoft Corporation", 8, 300.00},
+		{"TSLA", "Tesla Inc.", 15, 200.00},
+		{"JPM", "JPMorgan Chase & Co.", 12, 180.00},
+		{"JNJ", "Johnson & Johnson", 20, 140.00},
+		{"AMZN", "Amazon.com Inc.", 25, 3200.00},
+		{"NVDA", "NVIDIA Corporation", 30, 450.00},
+		{"META", "Meta Platforms Inc.", 18, 330.00},
+		{"NFLX", "Netflix Inc.", 22, 380.00},
+		{"V", "Visa Inc.", 40, 220.00},
+		{"WMT", "Walmart Inc.", 35, 140.00},
+	}
+	opening := 0.0
+	for _, h := range holdings {
+		if _, err := stocks.AddStock(ctx, demoUser, portfolio.Id, h.symbol, h.name, h.quantity, h.purchasePrice, now); err != nil {
+			return err
+		}
+		opening += h.quantity * h.purchasePrice
+	}
+
+	// Matches the opening deposit migrations/005_cash.sql gives existing holdings
+	_, err = cash.RecordCashEntry(ctx, demoUser, &pb.CashEntry{
+		Type:        pb.CashEntryType_DEPOSIT,
+		Amount:      opening,
+		Description: "Opening balance for trades recorded before the cash account",
+		Timestamp:   now,
+		Currency:    fx.USD,
+		PortfolioId: portfolio.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := alerts.CreateAlert(ctx, demoUser, portfolio.Id, "AAPL", 180.00, repository.AlertCondition_ABOVE); err != nil {
+		return err
+	}
+	if _, err := alerts.CreateAlert(ctx, demoUser, portfolio.Id, "GOOGL", 130.00, repository.AlertCondition_BELOW); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// connectPostgres opens the database described by the DB_* variables and waits until it answers
+func connectPostgres(ctx context.Context) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("DB_HOST", "localhost"),
+		getEnv("DB_PORT", "5432"),
+		getEnv("DB_USER", "portfolio_user"),
+		getEnv("DB_PASSWORD", "portfolio_pass"),
+		getEnv("DB_NAME", "portfolio_db"),
+		getEnv("DB_SSLMODE", "disable"),
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if err := retryWithBackoff(ctx, "Postgres", db.PingContext); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("✓ Connected to Postgres")
+	return db, nil
+}
+
+// connectRedis opens the Redis instance described by the REDIS_* variables and waits until it answers
+func connectRedis(ctx context.Context) (*redis.Client, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+		Password: getEnv("REDIS_PASSWORD", ""),
+	})
+
+	err := retryWithBackoff(ctx, "Redis", func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+	if err != nil {
+		rdb.Close()
+		return nil, err
+	}
+
+	log.Println("✓ Connected to Redis")
+	return rdb, nil
+}
+
+// retryWithBackoff calls ping until it succeeds, doubling the wait between attempts
+func retryWithBackoff(ctx context.Context, name string, ping func(context.Context) error) error {
+	const (
+		maxAttempts = 10
+		maxDelay    = 10 * time.Second
+	)
+
+	delay := 500 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err = ping(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("⏳ %s not ready (attempt %d/%d): %v", name, attempt, maxAttempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+
+	return fmt.Errorf("%s unreachable after %d attempts: %w", name, maxAttempts, err)
+}
@@ -0,0 +1,273 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// triggeredChannel is the Redis pub/sub channel used to share triggered alerts between instances
+const triggeredChannel = "alerts:triggered"
+
+// reloadInterval controls how often alerts created on other instances are picked up
+const reloadInterval = time.Minute
+
+// Event is published once for every alert that fires
+type Event struct {
+	UserID string
+	Alert  *pb.Alert
+}
+
+// Engine evaluates active price alerts against every PriceManager tick
+type Engine struct {
+	store        repository.AlertStore
+	priceManager *stream.PriceManager
+	rdb          *redis.Client
+
+	mu           sync.Mutex
+	alerts       map[string][]*repository.Alert // active alerts by symbol
+	subscription *stream.Subscription           // prices for every symbol with active alerts
+
+	listenersMu sync.RWMutex
+	listeners   map[string][]chan *Event // by user ID
+}
+
+// NewEngine creates an alert engine. rdb may be nil, in which case triggered
+// alerts are only delivered to listeners in this process.
+func NewEngine(store repository.AlertStore, priceManager *stream.PriceManager, rdb *redis.Client) *Engine {
+	return &Engine{
+		store:        store,
+		priceManager: priceManager,
+		rdb:          rdb,
+		alerts:       make(map[string][]*repository.Alert),
+		listeners:    make(map[string][]chan *Event),
+	}
+}
+
+// Start loads the active alerts for every tracked symbol and keeps them fresh until ctx is done
+func (e *Engine) Start(ctx context.Context) {
+	if e.rdb != nil {
+		go e.consumeTriggered(ctx)
+	}
+
+	e.reloadAll(ctx)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	log.Println("🔔 Alert engine started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.mu.Lock()
+			if e.subscription != nil {
+				e.priceManager.Unsubscribe(e.subscription)
+				e.subscription = nil
+			}
+			e.mu.Unlock()
+			return
+		case <-ticker.C:
+			e.reloadAll(ctx)
+		}
+	}
+}
+
+// Reload refreshes the active alerts for a symbol; call it after an alert is created
+func (e *Engine) Reload(ctx context.Context, symbol string) error {
+	alerts, err := e.store.GetActiveAlerts(ctx, symbol)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(alerts) == 0 {
+		delete(e.alerts, symbol)
+		if e.subscription != nil {
+			e.subscription.Remove(symbol)
+		}
+		return nil
+	}
+
+	e.alerts[symbol] = alerts
+	if e.subscription == nil {
+		// Alerts must see every crossing, so keep a deep buffer and shed the stalest ticks
+		e.subscription = e.priceManager.Subscribe(stream.SubscribeOptions{Policy: stream.DropOldest, Buffer: 1000})
+		go e.watch(e.subscription)
+	}
+	e.subscription.Add(symbol)
+
+	return nil
+}
+
+// Subscribe returns a channel of triggered alerts belonging to userID
+func (e *Engine) Subscribe(userID string) chan *Event {
+	e.listenersMu.Lock()
+	defer e.listenersMu.Unlock()
+
+	ch := make(chan *Event, 10)
+	e.listeners[userID] = append(e.listeners[userID], ch)
+
+	return ch
+}
+
+// Unsubscribe removes and closes a channel returned by Subscribe
+func (e *Engine) Unsubscribe(userID string, ch chan *Event) {
+	e.listenersMu.Lock()
+	defer e.listenersMu.Unlock()
+
+	subs := e.listeners[userID]
+	for i, sub := range subs {
+		if sub == ch {
+			close(ch)
+			e.listeners[userID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(e.listeners[userID]) == 0 {
+		delete(e.listeners, userID)
+	}
+}
+
+func (e *Engine) reloadAll(ctx context.Context) {
+	symbols := make(map[string]bool)
+	for _, symbol := range e.priceManager.Symbols() {
+		symbols[symbol] = true
+	}
+	e.mu.Lock()
+	for symbol := range e.alerts {
+		symbols[symbol] = true
+	}
+	e.mu.Unlock()
+
+	for symbol := range symbols {
+		if err := e.Reload(ctx, symbol); err != nil {
+			log.Printf("Failed to load alerts for %s: %v", symbol, err)
+		}
+	}
+}
+
+// watch evaluates alerts for every update on a PriceManager subscription
+func (e *Engine) watch(sub *stream.Subscription) {
+	for update := range sub.C() {
+		e.evaluate(update)
+	}
+}
+
+func (e *Engine) evaluate(update *pb.PriceUpdate) {
+	e.mu.Lock()
+	var fired, remaining []*repository.Alert
+	for _, a := range e.alerts[update.Symbol] {
+		if crossed(a, update.CurrentPrice) {
+			fired = append(fired, a)
+		} else {
+			remaining = append(remaining, a)
+		}
+	}
+	if len(fired) > 0 {
+		e.alerts[update.Symbol] = remaining
+	}
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, a := range fired {
+		err := e.store.TriggerAlert(ctx, a.ID, update.CurrentPrice, update.Timestamp)
+		if errors.Is(err, repository.ErrAlertAlreadyTriggered) || errors.Is(err, repository.ErrAlertNotFound) {
+			// Another instance fired it first, or it was deleted
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to trigger alert %s: %v", a.ID, err)
+			e.mu.Lock()
+			e.alerts[update.Symbol] = append(e.alerts[update.Symbol], a)
+			e.mu.Unlock()
+			continue
+		}
+
+		log.Printf("🔔 Alert %s triggered: %s at %.2f", a.ID, a.Symbol, update.CurrentPrice)
+		e.publish(ctx, &Event{
+			UserID: a.UserID,
+			Alert: &pb.Alert{
+				Id:             a.ID,
+				Symbol:         a.Symbol,
+				TargetPrice:    a.TargetPrice,
+				TriggeredPrice: update.CurrentPrice,
+				Condition:      pb.AlertCondition(a.Condition),
+				CreatedAt:      a.CreatedAt,
+				TriggeredAt:    update.Timestamp,
+				IsTriggered:    true,
+				PortfolioId:    a.PortfolioID,
+			},
+		})
+	}
+}
+
+// publish delivers an event to every instance through Redis, or locally without Redis
+func (e *Engine) publish(ctx context.Context, event *Event) {
+	if e.rdb == nil {
+		e.dispatch(event)
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal alert event: %v", err)
+		return
+	}
+	if err := e.rdb.Publish(ctx, triggeredChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish alert event: %v", err)
+		e.dispatch(event)
+	}
+}
+
+// consumeTriggered delivers alerts fired by any instance to local listeners
+func (e *Engine) consumeTriggered(ctx context.Context) {
+	pubsub := e.rdb.Subscribe(ctx, triggeredChannel)
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	for msg := range pubsub.Channel() {
+		var event Event
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			log.Printf("Failed to decode alert event: %v", err)
+			continue
+		}
+		e.dispatch(&event)
+	}
+}
+
+func (e *Engine) dispatch(event *Event) {
+	e.listenersMu.RLock()
+	defer e.listenersMu.RUnlock()
+
+	for _, ch := range e.listeners[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("⚠️  Alert listener channel full for %s", event.UserID)
+		}
+	}
+}
+
+// crossed reports whether price satisfies the alert's condition
+func crossed(a *repository.Alert, price float64) bool {
+	if a.Condition == int32(repository.AlertCondition_BELOW) {
+		return price <= a.TargetPrice
+	}
+	return price >= a.TargetPrice
+}
@@ -0,0 +1,167 @@
+package corporate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/alert"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// processInterval is how often actions whose ex-date has passed are looked for
+const processInterval = time.Minute
+
+// Processor applies splits and symbol changes once their ex-date passes. Every
+// instance runs one; the store makes sure each action is applied exactly once.
+type Processor struct {
+	store        repository.CorporateActionStore
+	priceManager *stream.PriceManager
+	alertEngine  *alert.Engine
+
+	mu sync.Mutex // one pass at a time, so actions apply in ex-date order
+}
+
+// NewProcessor creates a corporate action processor. priceManager and alertEngine may be
+// nil; otherwise prices follow each split and symbol change and adjusted alerts are reloaded.
+func NewProcessor(store repository.CorporateActionStore, priceManager *stream.PriceManager, alertEngine *alert.Engine) *Processor {
+	return &Processor{
+		store:        store,
+		priceManager: priceManager,
+		alertEngine:  alertEngine,
+	}
+}
+
+// Start applies due actions until ctx is done
+func (p *Processor) Start(ctx context.Context) {
+	ticker := time.NewTicker(processInterval)
+	defer ticker.Stop()
+
+	log.Println("🏢 Corporate action processor started")
+
+	for {
+		if err := p.Process(ctx); err != nil {
+			log.Printf("Failed to process corporate actions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule stores a new action and, if its ex-date has already passed, applies it
+// straight away. The returned action says whether it was applied; if it could not be,
+// it stays scheduled and the error says why.
+func (p *Processor) Schedule(ctx context.Context, action *pb.CorporateAction) (*pb.CorporateAction, error) {
+	stored, err := p.store.CreateCorporateAction(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	if stored.ExDate > time.Now().Unix() {
+		return stored, nil
+	}
+
+	processErr := p.Process(ctx)
+	actions, err := p.store.GetCorporateActions(ctx, stored.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		if action.Id == stored.Id {
+			stored = action
+		}
+	}
+	if stored.AppliedAt == 0 && processErr != nil {
+		return stored, processErr
+	}
+
+	return stored, nil
+}
+
+// Actions returns the actions on symbol, or every action if it is empty, by ex-date
+func (p *Processor) Actions(ctx context.Context, symbol string) ([]*pb.CorporateAction, error) {
+	return p.store.GetCorporateActions(ctx, symbol)
+}
+
+// Process applies every action whose ex-date has passed, oldest first. An action that
+// fails stays scheduled, along with every later action on its symbols, so actions on
+// a symbol never apply out of order; it is retried on the next pass.
+func (p *Processor) Process(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now().Unix()
+	due, err := p.store.DueCorporateActions(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	blocked := make(map[string]bool)
+	var errs []error
+	for _, action := range due {
+		if blocked[action.Symbol] || (action.NewSymbol != "" && blocked[action.NewSymbol]) {
+			continue
+		}
+
+		applied, err := p.store.ApplyCorporateAction(ctx, action.Id, now)
+		if errors.Is(err, repository.ErrCorporateActionApplied) {
+			continue // another instance got there first
+		}
+		if err != nil {
+			blocked[action.Symbol] = true
+			if action.NewSymbol != "" {
+				blocked[action.NewSymbol] = true
+			}
+			errs = append(errs, fmt.Errorf("failed to apply %s on %s: %w", action.Type, action.Symbol, err))
+			continue
+		}
+
+		log.Printf("🏢 Applied %s on %s: %d transactions, %d alerts, %d history rows, %d cash-in-lieu sales",
+			applied.Type, applied.Symbol, applied.TransactionsAdjusted, applied.AlertsAdjusted,
+			applied.HistoryAdjusted, applied.CashInLieuSales)
+		p.refresh(ctx, applied)
+	}
+
+	return errors.Join(errs...)
+}
+
+// refresh brings the price feed and alert engine in line with an applied action. The
+// price is adjusted before alerts reload, so split alert targets are never compared
+// with a pre-split price.
+func (p *Processor) refresh(ctx context.Context, action *pb.CorporateAction) {
+	symbols := []string{action.Symbol}
+	switch action.Type {
+	case pb.CorporateActionType_SPLIT:
+		if p.priceManager != nil {
+			p.priceManager.ApplySplit(ctx, action.Symbol, action.SplitTo/action.SplitFrom)
+		}
+	case pb.CorporateActionType_SYMBOL_CHANGE:
+		symbols = append(symbols, action.NewSymbol)
+		if p.priceManager != nil {
+			// Sources without a quote of their own carry on from the old symbol's price
+			basePrice := 0.0
+			if price, err := p.priceManager.GetCurrentPrice(ctx, action.Symbol); err == nil {
+				basePrice = price.CurrentPrice
+			}
+			p.priceManager.AddSymbol(action.NewSymbol, basePrice)
+			p.priceManager.RemoveSymbol(ctx, action.Symbol)
+		}
+	}
+
+	if p.alertEngine == nil {
+		return
+	}
+	for _, symbol := range symbols {
+		if err := p.alertEngine.Reload(ctx, symbol); err != nil {
+			log.Printf("Failed to reload alerts for %s: %v", symbol, err)
+		}
+	}
+}
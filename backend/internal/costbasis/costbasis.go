@@ -0,0 +1,204 @@
+// Package costbasis matches sells to the buy lots they close and works out the
+// realized gain on each, split into short-term and long-term holdings.
+package costbasis
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+var (
+	// ErrInsufficientShares is returned when a sell closes more shares than are held
+	ErrInsufficientShares = errors.New("insufficient shares")
+	// ErrLotUnavailable is returned when a specific-lot sell names a lot that is not
+	// open in the symbol and portfolio at the time of the sale or holds too few shares
+	ErrLotUnavailable = errors.New("lot not available")
+)
+
+// quantityEpsilon absorbs float rounding when shares are bought and sold back to zero
+const quantityEpsilon = 1e-9
+
+// Lot is the part of a BUY that is still held
+type Lot struct {
+	ID           string // the BUY transaction
+	PortfolioID  string
+	Symbol       string
+	PurchaseDate int64
+	Quantity     float64
+	// CostPerShare is the buy price plus the buy fees spread over the shares bought
+	CostPerShare float64
+}
+
+// Gain is the realized result of selling shares out of one lot
+type Gain struct {
+	SellID       string
+	LotID        string
+	PortfolioID  string
+	Symbol       string
+	Quantity     float64
+	PurchaseDate int64
+	SaleDate     int64
+	CostBasis    float64
+	// Proceeds is the sale value less its share of the sell fees
+	Proceeds float64
+	LongTerm bool
+}
+
+// Amount is the gain, negative for a loss
+func (g Gain) Amount() float64 {
+	return g.Proceeds - g.CostBasis
+}
+
+// Book is a ledger after matching: the lots still open and the gains realized
+type Book struct {
+	// Lots holds each symbol's open lots across every portfolio in the ledger, oldest first
+	Lots map[string][]*Lot
+	// Gains are in sale order, one per lot a sell drew from
+	Gains []Gain
+
+	open map[pool][]*Lot // during matching
+}
+
+// pool is the open lots of one symbol in one portfolio; a sell only closes lots in its own
+type pool struct {
+	portfolioID, symbol string
+}
+
+// Match replays ledger, oldest first, closing lots with each sell's LotMethod
+func Match(ledger []*pb.Transaction) (*Book, error) {
+	book := &Book{Lots: make(map[string][]*Lot), open: make(map[pool][]*Lot)}
+
+	for _, txn := range ledger {
+		switch txn.Type {
+		case pb.TransactionType_BUY:
+			key := pool{txn.PortfolioId, txn.Symbol}
+			book.open[key] = append(book.open[key], &Lot{
+				ID:           txn.Id,
+				PortfolioID:  txn.PortfolioId,
+				Symbol:       txn.Symbol,
+				PurchaseDate: txn.Timestamp,
+				Quantity:     txn.Quantity,
+				CostPerShare: (txn.Quantity*txn.Price + txn.Fees) / txn.Quantity,
+			})
+
+		case pb.TransactionType_SELL:
+			if err := book.sell(txn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for key, lots := range book.open {
+		book.Lots[key.symbol] = append(book.Lots[key.symbol], lots...)
+	}
+	for _, lots := range book.Lots {
+		sort.SliceStable(lots, func(i, j int) bool {
+			if lots[i].PurchaseDate != lots[j].PurchaseDate {
+				return lots[i].PurchaseDate < lots[j].PurchaseDate
+			}
+			return lots[i].PortfolioID < lots[j].PortfolioID
+		})
+	}
+
+	return book, nil
+}
+
+// sell closes txn.Quantity shares from the open lots of the symbol in the sell's portfolio
+func (b *Book) sell(txn *pb.Transaction) error {
+	key := pool{txn.PortfolioId, txn.Symbol}
+	lots := b.open[key]
+	held := 0.0
+	for _, lot := range lots {
+		held += lot.Quantity
+	}
+	if txn.Quantity > held+quantityEpsilon {
+		return fmt.Errorf("%w: selling %g %s at %d but only %g held",
+			ErrInsufficientShares, txn.Quantity, txn.Symbol, txn.Timestamp, held)
+	}
+
+	order, err := pickLots(lots, txn)
+	if err != nil {
+		return err
+	}
+
+	// Sell fees are shared across the lots in proportion to the shares taken from each
+	netPerShare := (txn.Quantity*txn.Price - txn.Fees) / txn.Quantity
+	remaining := txn.Quantity
+	for _, lot := range order {
+		if remaining < quantityEpsilon {
+			break
+		}
+		taken := min(lot.Quantity, remaining)
+		remaining -= taken
+		lot.Quantity -= taken
+
+		b.Gains = append(b.Gains, Gain{
+			SellID:       txn.Id,
+			LotID:        lot.ID,
+			PortfolioID:  lot.PortfolioID,
+			Symbol:       txn.Symbol,
+			Quantity:     taken,
+			PurchaseDate: lot.PurchaseDate,
+			SaleDate:     txn.Timestamp,
+			CostBasis:    taken * lot.CostPerShare,
+			Proceeds:     taken * netPerShare,
+			LongTerm:     IsLongTerm(lot.PurchaseDate, txn.Timestamp),
+		})
+	}
+
+	open := lots[:0]
+	for _, lot := range lots {
+		if lot.Quantity >= quantityEpsilon {
+			open = append(open, lot)
+		}
+	}
+	b.open[key] = open
+	if len(open) == 0 {
+		delete(b.open, key)
+	}
+
+	return nil
+}
+
+// pickLots returns lots in the order the sell draws from them
+func pickLots(lots []*Lot, txn *pb.Transaction) ([]*Lot, error) {
+	order := append([]*Lot(nil), lots...)
+
+	switch txn.LotMethod {
+	case pb.LotMethod_FIFO:
+		// lots are already oldest first
+	case pb.LotMethod_LIFO:
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	case pb.LotMethod_HIFO:
+		sort.SliceStable(order, func(i, j int) bool {
+			return order[i].CostPerShare > order[j].CostPerShare
+		})
+	case pb.LotMethod_SPECIFIC_LOT:
+		for _, lot := range lots {
+			if lot.ID == txn.LotId {
+				if txn.Quantity > lot.Quantity+quantityEpsilon {
+					return nil, fmt.Errorf("%w: lot %s holds %g %s, fewer than the %g sold",
+						ErrLotUnavailable, lot.ID, lot.Quantity, txn.Symbol, txn.Quantity)
+				}
+				return []*Lot{lot}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no open %s lot %s at %d", ErrLotUnavailable, txn.Symbol, txn.LotId, txn.Timestamp)
+	default:
+		return nil, fmt.Errorf("unknown lot method %v", txn.LotMethod)
+	}
+
+	return order, nil
+}
+
+// IsLongTerm reports whether shares bought at purchase and sold at sale were held
+// for more than a year
+func IsLongTerm(purchase, sale int64) bool {
+	return time.Unix(sale, 0).UTC().After(time.Unix(purchase, 0).UTC().AddDate(1, 0, 0))
+}
@@ -0,0 +1,269 @@
+package costbasis
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const tolerance = 1e-9
+
+const day = int64(24 * time.Hour / time.Second)
+
+func buy(id string, ts int64, quantity, price, fees float64) *pb.Transaction {
+	return &pb.Transaction{Id: id, Symbol: "AAPL", Type: pb.TransactionType_BUY, Quantity: quantity, Price: price, Fees: fees, Timestamp: ts, PortfolioId: "p1"}
+}
+
+func sell(id string, ts int64, quantity, price, fees float64, method pb.LotMethod, lotID string) *pb.Transaction {
+	return &pb.Transaction{Id: id, Symbol: "AAPL", Type: pb.TransactionType_SELL, Quantity: quantity, Price: price, Fees: fees, Timestamp: ts, LotMethod: method, LotId: lotID, PortfolioId: "p1"}
+}
+
+// inPortfolio moves txn to portfolioID
+func inPortfolio(portfolioID string, txn *pb.Transaction) *pb.Transaction {
+	txn.PortfolioId = portfolioID
+	return txn
+}
+
+// lots bought at 100, 120 and 110, then sold 15 at 130 with each method
+func threeLots(method pb.LotMethod, lotID string) []*pb.Transaction {
+	return []*pb.Transaction{
+		buy("b1", 1*day, 10, 100, 0),
+		buy("b2", 2*day, 10, 120, 0),
+		buy("b3", 3*day, 10, 110, 0),
+		sell("s1", 4*day, 15, 130, 0, method, lotID),
+	}
+}
+
+type wantGain struct {
+	lotID    string
+	quantity float64
+	amount   float64
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		ledger []*pb.Transaction
+		gains  []wantGain
+		open   map[string]float64 // lot ID -> shares still held
+	}{
+		{
+			name:   "FIFO",
+			ledger: threeLots(pb.LotMethod_FIFO, ""),
+			// 10 at 100 and 5 at 120
+			gains: []wantGain{{"b1", 10, 300}, {"b2", 5, 50}},
+			open:  map[string]float64{"b2": 5, "b3": 10},
+		},
+		{
+			name:   "LIFO",
+			ledger: threeLots(pb.LotMethod_LIFO, ""),
+			// 10 at 110 and 5 at 120
+			gains: []wantGain{{"b3", 10, 200}, {"b2", 5, 50}},
+			open:  map[string]float64{"b1": 10, "b2": 5},
+		},
+		{
+			name:   "HIFO",
+			ledger: threeLots(pb.LotMethod_HIFO, ""),
+			// 10 at 120 and 5 at 110
+			gains: []wantGain{{"b2", 10, 100}, {"b3", 5, 100}},
+			open:  map[string]float64{"b1": 10, "b3": 5},
+		},
+		{
+			name: "specific lot",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				buy("b2", 2*day, 10, 120, 0),
+				sell("s1", 3*day, 4, 130, 0, pb.LotMethod_SPECIFIC_LOT, "b2"),
+			},
+			gains: []wantGain{{"b2", 4, 40}},
+			open:  map[string]float64{"b1": 10, "b2": 6},
+		},
+		{
+			name: "fees",
+			ledger: []*pb.Transaction{
+				// Cost 101 a share with the buy fee
+				buy("b1", 1*day, 10, 100, 10),
+				buy("b2", 2*day, 10, 100, 0),
+				// Proceeds 119 a share net of the sell fee, shared across both lots
+				sell("s1", 3*day, 15, 120, 15, pb.LotMethod_FIFO, ""),
+			},
+			gains: []wantGain{{"b1", 10, 180}, {"b2", 5, 95}},
+			open:  map[string]float64{"b2": 5},
+		},
+		{
+			name: "sold out and bought again",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				sell("s1", 2*day, 10, 90, 0, pb.LotMethod_FIFO, ""),
+				buy("b2", 3*day, 5, 80, 0),
+			},
+			gains: []wantGain{{"b1", 10, -100}},
+			open:  map[string]float64{"b2": 5},
+		},
+		{
+			name: "portfolios are separate pools",
+			ledger: []*pb.Transaction{
+				inPortfolio("p1", buy("b1", 1*day, 10, 100, 0)),
+				inPortfolio("p2", buy("b2", 2*day, 10, 50, 0)),
+				// FIFO in p2 skips the older lot in p1
+				inPortfolio("p2", sell("s1", 3*day, 4, 60, 0, pb.LotMethod_FIFO, "")),
+				inPortfolio("p1", sell("s2", 4*day, 10, 110, 0, pb.LotMethod_FIFO, "")),
+			},
+			gains: []wantGain{{"b2", 4, 40}, {"b1", 10, 100}},
+			open:  map[string]float64{"b2": 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := Match(tt.ledger)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(book.Gains) != len(tt.gains) {
+				t.Fatalf("got %d gains, want %d: %+v", len(book.Gains), len(tt.gains), book.Gains)
+			}
+			for i, want := range tt.gains {
+				got := book.Gains[i]
+				if got.LotID != want.lotID || math.Abs(got.Quantity-want.quantity) > tolerance || math.Abs(got.Amount()-want.amount) > tolerance {
+					t.Errorf("gain %d: %g from %s for %.4f, want %g from %s for %.4f",
+						i, got.Quantity, got.LotID, got.Amount(), want.quantity, want.lotID, want.amount)
+				}
+			}
+
+			open := make(map[string]float64)
+			for _, lot := range book.Lots["AAPL"] {
+				open[lot.ID] = lot.Quantity
+			}
+			if len(open) != len(tt.open) {
+				t.Errorf("open lots %v, want %v", open, tt.open)
+			}
+			for id, quantity := range tt.open {
+				if math.Abs(open[id]-quantity) > tolerance {
+					t.Errorf("lot %s holds %g, want %g", id, open[id], quantity)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ledger []*pb.Transaction
+		want   error
+	}{
+		{
+			name: "selling more than held",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				sell("s1", 2*day, 11, 100, 0, pb.LotMethod_FIFO, ""),
+			},
+			want: ErrInsufficientShares,
+		},
+		{
+			name: "selling before buying",
+			ledger: []*pb.Transaction{
+				sell("s1", 1*day, 1, 100, 0, pb.LotMethod_FIFO, ""),
+				buy("b1", 2*day, 10, 100, 0),
+			},
+			want: ErrInsufficientShares,
+		},
+		{
+			name: "selling from another portfolio's shares",
+			ledger: []*pb.Transaction{
+				inPortfolio("p1", buy("b1", 1*day, 10, 100, 0)),
+				inPortfolio("p2", sell("s1", 2*day, 1, 100, 0, pb.LotMethod_FIFO, "")),
+			},
+			want: ErrInsufficientShares,
+		},
+		{
+			name: "lot already sold",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				buy("b2", 2*day, 10, 100, 0),
+				sell("s1", 3*day, 10, 100, 0, pb.LotMethod_FIFO, ""),
+				sell("s2", 4*day, 1, 100, 0, pb.LotMethod_SPECIFIC_LOT, "b1"),
+			},
+			want: ErrLotUnavailable,
+		},
+		{
+			name: "more than the lot holds",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				buy("b2", 2*day, 10, 100, 0),
+				sell("s1", 3*day, 11, 100, 0, pb.LotMethod_SPECIFIC_LOT, "b1"),
+			},
+			want: ErrLotUnavailable,
+		},
+		{
+			name: "lot bought after the sale",
+			ledger: []*pb.Transaction{
+				buy("b1", 1*day, 10, 100, 0),
+				sell("s1", 2*day, 1, 100, 0, pb.LotMethod_SPECIFIC_LOT, "b2"),
+				buy("b2", 3*day, 10, 100, 0),
+			},
+			want: ErrLotUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Match(tt.ledger); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLongTerm(t *testing.T) {
+	purchase := time.Date(2023, 3, 15, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		sale time.Time
+		want bool
+	}{
+		{"same day", purchase, false},
+		{"a day short of a year", purchase.AddDate(1, 0, -1), false},
+		{"exactly a year", purchase.AddDate(1, 0, 0), false},
+		{"a second over a year", purchase.AddDate(1, 0, 0).Add(time.Second), true},
+		{"two years", purchase.AddDate(2, 0, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLongTerm(purchase.Unix(), tt.sale.Unix()); got != tt.want {
+				t.Errorf("IsLongTerm(%s, %s) = %v, want %v", purchase, tt.sale, got, tt.want)
+			}
+		})
+	}
+
+	// A leap day purchase turns long-term after 1 March the next year
+	leap := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC).Unix()
+	if IsLongTerm(leap, time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC).Unix()) {
+		t.Error("leap day purchase long-term on 28 February")
+	}
+	if !IsLongTerm(leap, time.Date(2025, 3, 1, 1, 0, 0, 0, time.UTC).Unix()) {
+		t.Error("leap day purchase still short-term on 1 March")
+	}
+}
+
+func TestMatchMarksLongTermGains(t *testing.T) {
+	start := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC).Unix()
+	book, err := Match([]*pb.Transaction{
+		buy("b1", start, 10, 100, 0),
+		buy("b2", start+200*day, 10, 100, 0),
+		sell("s1", start+400*day, 20, 150, 0, pb.LotMethod_FIFO, ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(book.Gains) != 2 || !book.Gains[0].LongTerm || book.Gains[1].LongTerm {
+		t.Errorf("got %+v, want b1 long-term and b2 short-term", book.Gains)
+	}
+}
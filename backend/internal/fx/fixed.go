@@ -0,0 +1,29 @@
+package fx
+
+import (
+	"context"
+	"time"
+)
+
+// FixedSource quotes the same rates at every point in time, so holdings show no currency effect
+type FixedSource struct {
+	usdRates map[string]float64
+}
+
+// NewFixedSource quotes usdRates, the value of one unit of each currency in USD
+func NewFixedSource(usdRates map[string]float64) *FixedSource {
+	rates := make(map[string]float64, len(usdRates)+1)
+	for currency, rate := range usdRates {
+		rates[currency] = rate
+	}
+	rates[USD] = 1
+
+	return &FixedSource{usdRates: rates}
+}
+
+func (s *FixedSource) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	return crossRate(from, to, func(currency string) (float64, bool) {
+		rate, ok := s.usdRates[currency]
+		return rate, ok
+	})
+}
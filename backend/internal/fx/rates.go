@@ -0,0 +1,95 @@
+// Package fx converts amounts between currencies. A RateSource is chosen with FX_SOURCE
+// the way a stream.PriceSource is chosen with PRICE_SOURCE.
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// USD is the base currency of users who have not picked one, and of symbols without
+// an exchange suffix
+const USD = "USD"
+
+// ErrUnsupportedCurrency is returned for a currency the rate source does not quote
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// RateSource quotes exchange rates
+type RateSource interface {
+	// Rate returns how many units of to one unit of from was worth at t. It returns
+	// ErrUnsupportedCurrency if either currency is not quoted.
+	Rate(ctx context.Context, from, to string, at time.Time) (float64, error)
+}
+
+// DefaultUSDRates are the currencies the demo sources quote and what one unit of each is worth in USD
+var DefaultUSDRates = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"CHF": 1.12,
+	"SEK": 0.095,
+	"NOK": 0.094,
+	"DKK": 0.145,
+	"JPY": 0.0067,
+	"CNY": 0.138,
+	"HKD": 0.128,
+	"KRW": 0.00075,
+	"TWD": 0.031,
+	"SGD": 0.74,
+	"INR": 0.012,
+	"AUD": 0.66,
+	"CAD": 0.74,
+}
+
+// listingCurrencies maps exchange suffixes, as in "SAP.DE" or "7203.T", to the currency
+// the exchange quotes in
+var listingCurrencies = map[string]string{
+	"DE": "EUR", "F": "EUR", "PA": "EUR", "AS": "EUR", "MI": "EUR", "MC": "EUR", "BR": "EUR", "HE": "EUR",
+	"L": "GBP", "SW": "CHF", "ST": "SEK", "OL": "NOK", "CO": "DKK",
+	"T": "JPY", "SS": "CNY", "SZ": "CNY", "HK": "HKD", "KS": "KRW", "TW": "TWD", "SI": "SGD",
+	"NS": "INR", "BO": "INR", "AX": "AUD", "TO": "CAD", "V": "CAD",
+}
+
+// ListingCurrency returns the currency symbol is quoted in, going by its exchange suffix.
+// Symbols without a known suffix are taken to be US listings.
+func ListingCurrency(symbol string) string {
+	if i := strings.LastIndexByte(symbol, '.'); i >= 0 {
+		if currency, ok := listingCurrencies[symbol[i+1:]]; ok {
+			return currency
+		}
+	}
+	return USD
+}
+
+// ParseCurrency returns code trimmed and upper-cased, or why it is not an ISO 4217 code
+func ParseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("currency must be a three-letter ISO 4217 code, got %q", code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("currency must be a three-letter ISO 4217 code, got %q", code)
+		}
+	}
+	return code, nil
+}
+
+// crossRate divides the USD values of from and to, read with usd
+func crossRate(from, to string, usd func(currency string) (float64, bool)) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromUSD, ok := usd(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+	}
+	toUSD, ok := usd(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+	return fromUSD / toUSD, nil
+}
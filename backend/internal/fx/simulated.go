@@ -0,0 +1,67 @@
+package fx
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+const (
+	// simulatedCycle and simulatedRipple are the periods of the slow swing and the
+	// faster wobble every simulated rate follows
+	simulatedCycle  = 180 * 24 * time.Hour
+	simulatedRipple = 11 * 24 * time.Hour
+)
+
+// SimulatedSource moves every rate around its base value along a smooth path that is
+// a pure function of the seed, the currency and the time. Asking again for the same
+// moment always gives the same rate, so the rate on a past purchase date is stable.
+type SimulatedSource struct {
+	usdRates map[string]float64
+	seed     int64
+	swing    float64 // largest move of a rate's log, up or down
+}
+
+// NewSimulatedSource simulates rates around usdRates, moving each by up to about swing
+// (e.g. 0.1 for ±10%) either side
+func NewSimulatedSource(usdRates map[string]float64, seed int64, swing float64) *SimulatedSource {
+	rates := make(map[string]float64, len(usdRates)+1)
+	for currency, rate := range usdRates {
+		rates[currency] = rate
+	}
+	rates[USD] = 1
+
+	return &SimulatedSource{usdRates: rates, seed: seed, swing: swing}
+}
+
+func (s *SimulatedSource) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	return crossRate(from, to, func(currency string) (float64, bool) {
+		base, ok := s.usdRates[currency]
+		if !ok || currency == USD {
+			return base, ok
+		}
+		return base * math.Exp(s.offset(currency, at)), true
+	})
+}
+
+// offset is the log distance of currency's rate from its base at t
+func (s *SimulatedSource) offset(currency string, at time.Time) float64 {
+	h := fnv.New64a()
+	var seed [8]byte
+	for i := range seed {
+		seed[i] = byte(s.seed >> (8 * i))
+	}
+	h.Write(seed[:])
+	h.Write([]byte(currency))
+	sum := h.Sum64()
+
+	// Each currency gets its own phases, so rates don't all move together
+	phase1 := float64(sum&0xffff) / 0xffff * 2 * math.Pi
+	phase2 := float64(sum>>16&0xffff) / 0xffff * 2 * math.Pi
+	t := float64(at.Unix())
+
+	cycle := math.Sin(2*math.Pi*t/simulatedCycle.Seconds() + phase1)
+	ripple := math.Sin(2*math.Pi*t/simulatedRipple.Seconds() + phase2)
+	return s.swing * (2*cycle + ripple) / 3
+}
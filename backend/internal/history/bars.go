@@ -0,0 +1,194 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Interval is the width of an OHLCV bar, named as in HistoricalDataRequest
+type Interval string
+
+const (
+	Minute      Interval = "1m"
+	FiveMinutes Interval = "5m"
+	Hour        Interval = "1h"
+	Day         Interval = "1d"
+)
+
+// Intervals lists the stored bar widths from finest to coarsest. Each one is
+// downsampled from the one before it; 1m bars are built from ticks.
+var Intervals = []Interval{Minute, FiveMinutes, Hour, Day}
+
+// ParseInterval validates an interval name
+func ParseInterval(name string) (Interval, error) {
+	for _, interval := range Intervals {
+		if string(interval) == name {
+			return interval, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported interval %q (want 1m, 5m, 1h or 1d)", name)
+}
+
+// Seconds returns the width of the interval in seconds
+func (i Interval) Seconds() int64 {
+	switch i {
+	case Minute:
+		return 60
+	case FiveMinutes:
+		return 5 * 60
+	case Hour:
+		return 60 * 60
+	case Day:
+		return 24 * 60 * 60
+	default:
+		return 0
+	}
+}
+
+// Duration returns the width of the interval
+func (i Interval) Duration() time.Duration {
+	return time.Duration(i.Seconds()) * time.Second
+}
+
+// Truncate returns the start of the bar containing the Unix timestamp ts. Days start at 00:00 UTC.
+func (i Interval) Truncate(ts int64) int64 {
+	width := i.Seconds()
+	start := ts - ts%width
+	if ts < 0 && ts%width != 0 {
+		start -= width
+	}
+	return start
+}
+
+// source returns the interval this one is downsampled from, or "" for bars built from ticks
+func (i Interval) source() Interval {
+	for n := 1; n < len(Intervals); n++ {
+		if Intervals[n] == i {
+			return Intervals[n-1]
+		}
+	}
+	return ""
+}
+
+// volumeSince returns the shares traded between two updates that carry the day's
+// cumulative volume. A drop in the cumulative volume means a new session started.
+func volumeSince(previous, cumulative float64) float64 {
+	if cumulative < previous {
+		return cumulative
+	}
+	return cumulative - previous
+}
+
+// BuildBars aggregates ticks, oldest first, into bars of interval for each symbol
+func BuildBars(ticks []*repository.PriceTick, interval Interval) map[string][]*pb.HistoricalPrice {
+	bars := make(map[string][]*pb.HistoricalPrice)
+	for _, tick := range ticks {
+		start := interval.Truncate(tick.Timestamp)
+		symbolBars := bars[tick.Symbol]
+
+		if n := len(symbolBars); n > 0 && symbolBars[n-1].Timestamp == start {
+			bar := symbolBars[n-1]
+			bar.High = math.Max(bar.High, tick.Price)
+			bar.Low = math.Min(bar.Low, tick.Price)
+			bar.Close = tick.Price
+			bar.Volume += tick.Volume
+			continue
+		}
+
+		bars[tick.Symbol] = append(symbolBars, &pb.HistoricalPrice{
+			Timestamp: start,
+			Open:      tick.Price,
+			High:      tick.Price,
+			Low:       tick.Price,
+			Close:     tick.Price,
+			Volume:    tick.Volume,
+		})
+	}
+
+	return bars
+}
+
+// Downsample merges bars, oldest first, into wider bars of interval
+func Downsample(bars []*pb.HistoricalPrice, interval Interval) []*pb.HistoricalPrice {
+	var merged []*pb.HistoricalPrice
+	for _, bar := range bars {
+		start := interval.Truncate(bar.Timestamp)
+
+		if n := len(merged); n > 0 && merged[n-1].Timestamp == start {
+			last := merged[n-1]
+			last.High = math.Max(last.High, bar.High)
+			last.Low = math.Min(last.Low, bar.Low)
+			last.Close = bar.Close
+			last.Volume += bar.Volume
+			continue
+		}
+
+		merged = append(merged, &pb.HistoricalPrice{
+			Timestamp: start,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+			Volume:    bar.Volume,
+		})
+	}
+
+	return merged
+}
+
+// LiveBar folds price updates into the bar of interval they fall in, starting a new
+// bar at each boundary. It is the streaming counterpart of BuildBars for charts that
+// update on every tick.
+type LiveBar struct {
+	interval   Interval
+	bar        *pb.HistoricalPrice
+	lastVolume float64
+	seen       bool
+}
+
+// NewLiveBar continues from last, the newest stored bar, which may be nil
+func NewLiveBar(interval Interval, last *pb.HistoricalPrice) *LiveBar {
+	live := &LiveBar{interval: interval}
+	if last != nil {
+		live.bar = proto.Clone(last).(*pb.HistoricalPrice)
+	}
+	return live
+}
+
+// Add applies update and returns a copy of the bar it belongs to, or nil when the
+// update is older than that bar. The first update adds no volume, since the
+// volume traded before it is unknown.
+func (b *LiveBar) Add(update *pb.PriceUpdate) *pb.HistoricalPrice {
+	volume := 0.0
+	if b.seen {
+		volume = volumeSince(b.lastVolume, update.Volume)
+	}
+	b.lastVolume, b.seen = update.Volume, true
+
+	start := b.interval.Truncate(update.Timestamp)
+	switch {
+	case b.bar != nil && start < b.bar.Timestamp:
+		return nil
+	case b.bar != nil && start == b.bar.Timestamp:
+		b.bar.High = math.Max(b.bar.High, update.CurrentPrice)
+		b.bar.Low = math.Min(b.bar.Low, update.CurrentPrice)
+		b.bar.Close = update.CurrentPrice
+		b.bar.Volume += volume
+	default:
+		b.bar = &pb.HistoricalPrice{
+			Timestamp: start,
+			Open:      update.CurrentPrice,
+			High:      update.CurrentPrice,
+			Low:       update.CurrentPrice,
+			Close:     update.CurrentPrice,
+			Volume:    volume,
+		}
+	}
+
+	return proto.Clone(b.bar).(*pb.HistoricalPrice)
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// ohlcv formats bars as "start open/high/low/close volume" for comparison
+func ohlcv(bars []*pb.HistoricalPrice) []string {
+	out := make([]string, len(bars))
+	for i, bar := range bars {
+		out[i] = fmt.Sprintf("%d %g/%g/%g/%g %g", bar.Timestamp, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume)
+	}
+	return out
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		interval Interval
+		ts       int64
+		want     int64
+	}{
+		{Minute, 119, 60},
+		{Minute, 120, 120},
+		{FiveMinutes, 899, 600},
+		{Hour, 7199, 3600},
+		{Day, 86400 + 3600*23, 86400},
+		// Before the epoch bars still start on the boundary at or before ts
+		{Minute, -1, -60},
+		{Minute, -60, -60},
+		{Day, -3600, -86400},
+	}
+
+	for _, tt := range tests {
+		if got := tt.interval.Truncate(tt.ts); got != tt.want {
+			t.Errorf("%s.Truncate(%d) = %d, want %d", tt.interval, tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	for _, interval := range Intervals {
+		if got, err := ParseInterval(string(interval)); err != nil || got != interval {
+			t.Errorf("ParseInterval(%q) = %q, %v", interval, got, err)
+		}
+	}
+	if _, err := ParseInterval("15m"); err == nil {
+		t.Error("ParseInterval(\"15m\") accepted an interval that isn't stored")
+	}
+
+	// Each interval is downsampled from the next finer one
+	if Minute.source() != "" || FiveMinutes.source() != Minute || Day.source() != Hour {
+		t.Errorf("sources %q, %q, %q", Minute.source(), FiveMinutes.source(), Day.source())
+	}
+}
+
+func TestBuildBars(t *testing.T) {
+	ticks := []*repository.PriceTick{
+		{Symbol: "AAPL", Price: 100, Volume: 5, Timestamp: 60},
+		{Symbol: "MSFT", Price: 300, Volume: 1, Timestamp: 61},
+		{Symbol: "AAPL", Price: 103, Volume: 2, Timestamp: 80},
+		{Symbol: "AAPL", Price: 99, Volume: 1, Timestamp: 100},
+		{Symbol: "AAPL", Price: 101, Volume: 4, Timestamp: 119},
+		// The boundary opens the next bar, and an empty minute gets no bar
+		{Symbol: "AAPL", Price: 102, Volume: 3, Timestamp: 120},
+		{Symbol: "AAPL", Price: 104, Volume: 1, Timestamp: 250},
+	}
+
+	bars := BuildBars(ticks, Minute)
+	want := []string{"60 100/103/99/101 12", "120 102/102/102/102 3", "240 104/104/104/104 1"}
+	if got := ohlcv(bars["AAPL"]); !slices.Equal(got, want) {
+		t.Errorf("AAPL bars %v, want %v", got, want)
+	}
+	if got := ohlcv(bars["MSFT"]); !slices.Equal(got, []string{"60 300/300/300/300 1"}) {
+		t.Errorf("MSFT bars %v, want its own bar", got)
+	}
+
+	// Building 5m bars from ticks matches downsampling the 1m bars
+	direct := ohlcv(BuildBars(ticks, FiveMinutes)["AAPL"])
+	if got := ohlcv(Downsample(bars["AAPL"], FiveMinutes)); !slices.Equal(got, direct) || len(direct) != 1 {
+		t.Errorf("downsampled %v, built from ticks %v", got, direct)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	hours := []*pb.HistoricalPrice{
+		{Timestamp: 0, Open: 10, High: 12, Low: 9, Close: 11, Volume: 100},
+		{Timestamp: 3600, Open: 11, High: 15, Low: 10, Close: 14, Volume: 50},
+		{Timestamp: 23 * 3600, Open: 14, High: 14, Low: 8, Close: 9, Volume: 25},
+		{Timestamp: 24 * 3600, Open: 9, High: 10, Low: 9, Close: 10, Volume: 10},
+	}
+
+	// Open of the first bar, close of the last, the extremes and the total volume
+	want := []string{"0 10/15/8/9 175", "86400 9/10/9/10 10"}
+	if got := ohlcv(Downsample(hours, Day)); !slices.Equal(got, want) {
+		t.Errorf("daily bars %v, want %v", got, want)
+	}
+	if hours[0].High != 12 || hours[0].Volume != 100 {
+		t.Errorf("downsampling changed its input: %v", ohlcv(hours[:1]))
+	}
+}
+
+func TestLiveBar(t *testing.T) {
+	live := NewLiveBar(Minute, &pb.HistoricalPrice{Timestamp: 60, Open: 100, High: 101, Low: 99, Close: 100, Volume: 40})
+
+	steps := []struct {
+		price, volume float64
+		ts            int64
+		want          string
+	}{
+		// The first update adds no volume, since what traded before it is unknown
+		{102, 1000, 90, "60 100/102/99/102 40"},
+		{98, 1010, 100, "60 100/102/98/98 50"},
+		{97, 1015, 130, "120 97/97/97/97 5"},
+		// Cumulative volume falls at the start of a new session
+		{96, 20, 170, "120 97/97/96/96 25"},
+		{95, 30, 110, ""},
+	}
+
+	for i, step := range steps {
+		bar := live.Add(&pb.PriceUpdate{CurrentPrice: step.price, Volume: step.volume, Timestamp: step.ts})
+		switch {
+		case step.want == "" && bar != nil:
+			t.Errorf("update %d: got %v for an update older than the bar", i, ohlcv([]*pb.HistoricalPrice{bar}))
+		case step.want != "" && (bar == nil || ohlcv([]*pb.HistoricalPrice{bar})[0] != step.want):
+			t.Errorf("update %d: got %v, want %s", i, bar, step.want)
+		}
+	}
+}
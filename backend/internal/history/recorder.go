@@ -0,0 +1,217 @@
+package history
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const (
+	// flushInterval is how often recorded ticks are written and the bars they touch rebuilt
+	flushInterval = 5 * time.Second
+	// maxPendingTicks forces an early flush when the stream is busy
+	maxPendingTicks = 1000
+	// maxUnsavedTicks caps the ticks kept for retrying while the store is failing
+	maxUnsavedTicks = 100000
+	// pruneInterval is how often the retention policy is applied
+	pruneInterval = time.Hour
+)
+
+// RetentionPolicy says how long each kind of history is kept. A zero duration keeps data forever.
+// Each level must outlive the width of the level built from it, so bars can always be rebuilt.
+type RetentionPolicy struct {
+	Ticks time.Duration
+	Bars  map[Interval]time.Duration
+}
+
+// DefaultRetentionPolicy keeps raw ticks for two days and progressively coarser bars for longer
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Ticks: 48 * time.Hour,
+		Bars: map[Interval]time.Duration{
+			Minute:      7 * 24 * time.Hour,
+			FiveMinutes: 60 * 24 * time.Hour,
+			Hour:        2 * 365 * 24 * time.Hour,
+			Day:         0,
+		},
+	}
+}
+
+// Recorder persists every PriceManager update and keeps the 1m/5m/1h/1d bars built
+// from them up to date. Retention is measured against the newest recorded tick
+// rather than the wall clock, so simulated and replayed tapes age the same way.
+type Recorder struct {
+	store        repository.PriceHistoryStore
+	priceManager *stream.PriceManager
+	policy       RetentionPolicy
+
+	pending    []*repository.PriceTick
+	lastVolume map[string]float64 // cumulative day volume of each symbol's previous tick
+	dirtyFrom  int64              // oldest tick timestamp whose bars need rebuilding
+	latest     int64              // newest tick timestamp recorded
+}
+
+func NewRecorder(store repository.PriceHistoryStore, priceManager *stream.PriceManager, policy RetentionPolicy) *Recorder {
+	return &Recorder{
+		store:        store,
+		priceManager: priceManager,
+		policy:       policy,
+		lastVolume:   make(map[string]float64),
+	}
+}
+
+// Start records updates until ctx is done, then flushes what is left
+func (r *Recorder) Start(ctx context.Context) {
+	sub := r.priceManager.Subscribe(stream.SubscribeOptions{Policy: stream.DropOldest, Buffer: 10000}, stream.AllSymbols)
+	defer r.priceManager.Unsubscribe(sub)
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	log.Println("💾 Tick recorder started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			r.flush(shutdownCtx)
+			cancel()
+			return
+		case update, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			r.record(update)
+			if len(r.pending) >= maxPendingTicks {
+				r.flush(ctx)
+			}
+		case <-flush.C:
+			r.flush(ctx)
+		case <-prune.C:
+			r.Prune(ctx)
+		}
+	}
+}
+
+// record queues an update for the next flush
+func (r *Recorder) record(update *pb.PriceUpdate) {
+	// Updates carry the day's cumulative volume; store what traded since the last tick
+	volume := 0.0
+	if last, ok := r.lastVolume[update.Symbol]; ok {
+		volume = volumeSince(last, update.Volume)
+	}
+	r.lastVolume[update.Symbol] = update.Volume
+
+	r.pending = append(r.pending, &repository.PriceTick{
+		Symbol:    update.Symbol,
+		Sequence:  update.Sequence,
+		Price:     update.CurrentPrice,
+		Volume:    volume,
+		Timestamp: update.Timestamp,
+	})
+
+	if r.dirtyFrom == 0 || update.Timestamp < r.dirtyFrom {
+		r.dirtyFrom = update.Timestamp
+	}
+	if update.Timestamp > r.latest {
+		r.latest = update.Timestamp
+	}
+}
+
+// flush writes pending ticks and rebuilds every bar they fall into
+func (r *Recorder) flush(ctx context.Context) {
+	if len(r.pending) > 0 {
+		if err := r.store.SaveTicks(ctx, r.pending); err != nil {
+			// Keep the ticks and try again on the next flush, dropping the oldest once
+			// too many have piled up
+			log.Printf("Failed to record ticks: %v", err)
+			if dropped := len(r.pending) - maxUnsavedTicks; dropped > 0 {
+				r.pending = slices.Delete(r.pending, 0, dropped)
+				log.Printf("Dropped %d unsaved ticks", dropped)
+			}
+			return
+		}
+		r.pending = r.pending[:0]
+	}
+
+	if r.dirtyFrom == 0 {
+		return
+	}
+	if err := r.rebuild(ctx, r.dirtyFrom, r.latest); err != nil {
+		log.Printf("Failed to build price bars: %v", err)
+		return
+	}
+	r.dirtyFrom = 0
+}
+
+// rebuild recomputes the bars of every interval covering [from, to] from the level
+// below. Bars are replaced rather than merged, so rebuilding is always safe to repeat.
+func (r *Recorder) rebuild(ctx context.Context, from, to int64) error {
+	for _, interval := range Intervals {
+		start := interval.Truncate(from)
+		end := interval.Truncate(to) + interval.Seconds()
+
+		var bars map[string][]*pb.HistoricalPrice
+		if source := interval.source(); source == "" {
+			ticks, err := r.store.GetTicks(ctx, start, end)
+			if err != nil {
+				return err
+			}
+			bars = BuildBars(ticks, interval)
+		} else {
+			finer, err := r.store.GetAllBars(ctx, string(source), start, end)
+			if err != nil {
+				return err
+			}
+			bars = make(map[string][]*pb.HistoricalPrice, len(finer))
+			for symbol, symbolBars := range finer {
+				bars[symbol] = Downsample(symbolBars, interval)
+			}
+		}
+
+		for symbol, symbolBars := range bars {
+			if err := r.store.SaveBars(ctx, symbol, string(interval), symbolBars); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Prune deletes ticks and bars that have aged out of the retention policy
+func (r *Recorder) Prune(ctx context.Context) {
+	if r.latest == 0 {
+		return
+	}
+	now := time.Unix(r.latest, 0)
+
+	if r.policy.Ticks > 0 {
+		deleted, err := r.store.DeleteTicksBefore(ctx, now.Add(-r.policy.Ticks).Unix())
+		if err != nil {
+			log.Printf("Failed to prune ticks: %v", err)
+		} else if deleted > 0 {
+			log.Printf("🧹 Pruned %d ticks", deleted)
+		}
+	}
+
+	for _, interval := range Intervals {
+		keep := r.policy.Bars[interval]
+		if keep <= 0 {
+			continue
+		}
+		deleted, err := r.store.DeleteBarsBefore(ctx, string(interval), now.Add(-keep).Unix())
+		if err != nil {
+			log.Printf("Failed to prune %s bars: %v", interval, err)
+		} else if deleted > 0 {
+			log.Printf("🧹 Pruned %d %s bars", deleted, interval)
+		}
+	}
+}
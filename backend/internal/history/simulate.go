@@ -0,0 +1,57 @@
+package history
+
+import (
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// simulationSeed keeps simulated history the same on every request and every instance
+const simulationSeed = 42
+
+// Simulate generates bars of interval for symbol over [start, end) with the market
+// simulator, starting from basePrice. Only trading sessions produce bars.
+func Simulate(symbol string, basePrice float64, interval Interval, start, end int64) []*pb.HistoricalPrice {
+	// Step finely enough for every bar to see several ticks
+	step := time.Minute
+	switch interval {
+	case Hour:
+		step = 5 * time.Minute
+	case Day:
+		step = 30 * time.Minute
+	}
+
+	sim := stream.NewSimulatorSource(stream.SimulatorConfig{
+		Seed:    simulationSeed,
+		Step:    step,
+		Start:   time.Unix(start, 0).UTC(),
+		Symbols: map[string]stream.SymbolParams{symbol: stream.DefaultSymbolParams(basePrice)},
+	})
+
+	var ticks []*repository.PriceTick
+	lastVolume := 0.0
+	for {
+		update := sim.Step()[0]
+		if update.Timestamp >= end {
+			break
+		}
+
+		volume := volumeSince(lastVolume, update.Volume)
+		lastVolume = update.Volume
+		// The simulator starts at the open of start's trading day
+		if update.Timestamp < start {
+			continue
+		}
+
+		ticks = append(ticks, &repository.PriceTick{
+			Symbol:    symbol,
+			Price:     update.CurrentPrice,
+			Volume:    volume,
+			Timestamp: update.Timestamp,
+		})
+	}
+
+	return BuildBars(ticks, interval)[symbol]
+}
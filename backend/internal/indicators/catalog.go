@@ -0,0 +1,267 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPeriod bounds the lookback a caller can ask for
+const maxPeriod = 1000
+
+// Spec selects an indicator and its parameters, written like "bb(20,2)" or "rsi".
+// Omitted trailing parameters take the indicator's defaults.
+type Spec struct {
+	Name   string
+	Params []float64
+	// Label is the spec as the caller wrote it, normalized; it names the output lines
+	Label string
+}
+
+// Line is one output line of an indicator. Name is empty for an indicator's main line.
+type Line struct {
+	Name   string
+	Points []Point
+}
+
+type param struct {
+	name    string
+	value   float64
+	integer bool // a period, counted in candles
+}
+
+type definition struct {
+	params  []param
+	compute func(s Series, p []float64) []Line
+	// live builds the incremental form, for indicators that have one
+	live func(p []float64) stepper
+}
+
+func period(name string, value float64) param {
+	return param{name: name, value: value, integer: true}
+}
+
+var catalog = map[string]definition{
+	"sma": {
+		params: []param{period("period", 20)},
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: SMA(s, int(p[0]))}}
+		},
+		live: func(p []float64) stepper {
+			return &smaStep{period: int(p[0])}
+		},
+	},
+	"ema": {
+		params: []param{period("period", 20)},
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: EMA(s, int(p[0]))}}
+		},
+		live: func(p []float64) stepper {
+			return &emaStep{ema: newEMA(int(p[0]))}
+		},
+	},
+	"rsi": {
+		params: []param{period("period", 14)},
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: RSI(s, int(p[0]))}}
+		},
+		live: func(p []float64) stepper {
+			return &rsiStep{gains: newWilder(int(p[0])), losses: newWilder(int(p[0]))}
+		},
+	},
+	"macd": {
+		params: []param{period("fast", 12), period("slow", 26), period("signal", 9)},
+		compute: func(s Series, p []float64) []Line {
+			r := MACD(s, int(p[0]), int(p[1]), int(p[2]))
+			return []Line{{Points: r.MACD}, {"signal", r.Signal}, {"histogram", r.Histogram}}
+		},
+		live: func(p []float64) stepper {
+			return &macdStep{fast: newEMA(int(p[0])), slow: newEMA(int(p[1])), signal: newEMA(int(p[2]))}
+		},
+	},
+	"bb": {
+		params: []param{period("period", 20), {name: "k", value: 2}},
+		compute: func(s Series, p []float64) []Line {
+			r := BollingerBands(s, int(p[0]), p[1])
+			return []Line{{"upper", r.Upper}, {"middle", r.Middle}, {"lower", r.Lower}}
+		},
+	},
+	"stoch": {
+		params: []param{period("k", 14), period("d", 3)},
+		compute: func(s Series, p []float64) []Line {
+			r := Stochastic(s, int(p[0]), int(p[1]))
+			return []Line{{"k", r.K}, {"d", r.D}}
+		},
+	},
+	"atr": {
+		params: []param{period("period", 14)},
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: ATR(s, int(p[0]))}}
+		},
+	},
+	"vwap": {
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: VWAP(s)}}
+		},
+	},
+	"obv": {
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: OBV(s)}}
+		},
+	},
+	"adx": {
+		params: []param{period("period", 14)},
+		compute: func(s Series, p []float64) []Line {
+			r := ADX(s, int(p[0]))
+			return []Line{{Points: r.ADX}, {"plus_di", r.PlusDI}, {"minus_di", r.MinusDI}}
+		},
+	},
+	"ichimoku": {
+		params: []param{period("tenkan", 9), period("kijun", 26), period("senkou_b", 52)},
+		compute: func(s Series, p []float64) []Line {
+			r := Ichimoku(s, int(p[0]), int(p[1]), int(p[2]))
+			return []Line{{"tenkan", r.Tenkan}, {"kijun", r.Kijun}, {"senkou_a", r.SenkouA}, {"senkou_b", r.SenkouB}, {"chikou", r.Chikou}}
+		},
+	},
+	"sar": {
+		params: []param{{name: "step", value: 0.02}, {name: "max", value: 0.2}},
+		compute: func(s Series, p []float64) []Line {
+			return []Line{{Points: ParabolicSAR(s, p[0], p[1])}}
+		},
+	},
+}
+
+var aliases = map[string]string{
+	"bollinger":  "bb",
+	"stochastic": "stoch",
+	"psar":       "sar",
+}
+
+// Names lists the supported indicators
+func Names() []string {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSpecs parses a comma-separated list such as "bb(20,2),rsi(7),vwap"
+func ParseSpecs(text string) ([]Spec, error) {
+	var specs []Spec
+	depth, start := 0, 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) {
+			switch text[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		if item := strings.TrimSpace(text[start:i]); item != "" {
+			spec, err := ParseSpec(item)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, spec)
+		}
+		start = i + 1
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", text)
+	}
+
+	return specs, nil
+}
+
+// ParseSpec parses one indicator such as "bb(20,2)" and fills in default parameters
+func ParseSpec(text string) (Spec, error) {
+	text = strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	name, args := text, ""
+	if open := strings.IndexByte(text, '('); open >= 0 {
+		if !strings.HasSuffix(text, ")") {
+			return Spec{}, fmt.Errorf("invalid indicator %q", text)
+		}
+		name, args = text[:open], text[open+1:len(text)-1]
+	}
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	def, ok := catalog[name]
+	if !ok {
+		return Spec{}, fmt.Errorf("unknown indicator %q (supported: %s)", name, strings.Join(Names(), ", "))
+	}
+
+	var given []string
+	if args != "" {
+		given = strings.Split(args, ",")
+	}
+	if len(given) > len(def.params) {
+		return Spec{}, fmt.Errorf("%s takes at most %d parameters", name, len(def.params))
+	}
+
+	spec := Spec{Name: name, Params: make([]float64, len(def.params)), Label: text}
+	for i, p := range def.params {
+		spec.Params[i] = p.value
+		if i >= len(given) {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(given[i], 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) || value <= 0 {
+			return Spec{}, fmt.Errorf("%s: %s must be a positive number", name, p.name)
+		}
+		if p.integer && (value != math.Trunc(value) || value > maxPeriod) {
+			return Spec{}, fmt.Errorf("%s: %s must be a whole number of candles up to %d", name, p.name, maxPeriod)
+		}
+		spec.Params[i] = value
+	}
+
+	switch {
+	case name == "macd" && spec.Params[0] >= spec.Params[1]:
+		return Spec{}, fmt.Errorf("macd: fast period must be shorter than slow period")
+	case name == "sar" && spec.Params[0] > spec.Params[1]:
+		return Spec{}, fmt.Errorf("sar: step must not exceed max")
+	}
+
+	return spec, nil
+}
+
+// Lines computes the indicator over s
+func (spec Spec) Lines(s Series) []Line {
+	return catalog[spec.Name].compute(s, spec.Params)
+}
+
+// Compute evaluates specs over s. Each line is keyed by the spec's label, with the
+// line name appended for secondary lines: "macd", "macd_signal", "bb(20,2)_upper".
+func Compute(s Series, specs []Spec) map[string][]Point {
+	result := make(map[string][]Point)
+	for _, spec := range specs {
+		for _, line := range spec.Lines(s) {
+			result[spec.key(line.Name)] = line.Points
+		}
+	}
+	return result
+}
+
+// key names one of the spec's output lines
+func (spec Spec) key(line string) string {
+	if line == "" {
+		return spec.Label
+	}
+	return spec.Label + "_" + line
+}
@@ -0,0 +1,199 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+)
+
+// Incremental keeps an indicator's value at the newest candle up to date without
+// rescanning history. Feed it every revision of the forming candle: a candle with
+// the same Time replaces the forming one, and a later Time closes it and starts the
+// next. Its values match the batch functions over the same candles.
+type Incremental struct {
+	spec    Spec
+	step    stepper
+	forming *Candle
+}
+
+// stepper advances an indicator one closed candle at a time
+type stepper interface {
+	// peek returns each warmed-up line's value with c as the next candle, keyed by
+	// line name, without advancing
+	peek(c Candle) map[string]float64
+	push(c Candle)
+}
+
+// Incremental returns a live version of the indicator, primed with the closed and
+// forming candles in history. Not every indicator in the catalog supports it.
+func (spec Spec) Incremental(history Series) (*Incremental, error) {
+	def := catalog[spec.Name]
+	if def.live == nil {
+		return nil, fmt.Errorf("%s is not available on live charts", spec.Name)
+	}
+
+	inc := &Incremental{spec: spec, step: def.live(spec.Params)}
+	for _, c := range history {
+		inc.Update(c)
+	}
+	return inc, nil
+}
+
+// Update takes the latest state of the forming candle and returns the indicator's
+// values at it, keyed like Compute. Candles older than the forming one are ignored.
+func (inc *Incremental) Update(c Candle) map[string]float64 {
+	if inc.forming != nil {
+		switch {
+		case c.Time < inc.forming.Time:
+			return nil
+		case c.Time > inc.forming.Time:
+			inc.step.push(*inc.forming)
+		}
+	}
+	inc.forming = &c
+
+	values := make(map[string]float64)
+	for line, v := range inc.step.peek(c) {
+		values[inc.spec.key(line)] = v
+	}
+	return values
+}
+
+// smoother is a running exponential average seeded with the SMA of its first
+// period inputs, the incremental form of emaValues and wilderValues
+type smoother struct {
+	period int
+	k      float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+func newEMA(period int) *smoother {
+	return &smoother{period: period, k: 2 / (float64(period) + 1)}
+}
+
+func newWilder(period int) *smoother {
+	return &smoother{period: period, k: 1 / float64(period)}
+}
+
+// next returns the average with x as the next input, without advancing
+func (m *smoother) next(x float64) (float64, bool) {
+	switch {
+	case m.count+1 < m.period:
+		return math.NaN(), false
+	case m.count+1 == m.period:
+		return (m.sum + x) / float64(m.period), true
+	default:
+		return x*m.k + m.value*(1-m.k), true
+	}
+}
+
+func (m *smoother) push(x float64) {
+	m.value, _ = m.next(x)
+	m.count++
+	if m.count < m.period {
+		m.sum += x
+	}
+}
+
+func (m *smoother) ready() bool {
+	return m.count >= m.period
+}
+
+// smaStep holds the last period-1 closed closes
+type smaStep struct {
+	period int
+	window []float64
+	sum    float64
+}
+
+func (st *smaStep) peek(c Candle) map[string]float64 {
+	if len(st.window) < st.period-1 {
+		return nil
+	}
+	return map[string]float64{"": (st.sum + c.Close) / float64(st.period)}
+}
+
+func (st *smaStep) push(c Candle) {
+	if st.period < 2 {
+		return
+	}
+	st.window = append(st.window, c.Close)
+	st.sum += c.Close
+	if len(st.window) > st.period-1 {
+		st.sum -= st.window[0]
+		st.window = st.window[1:]
+	}
+}
+
+type emaStep struct {
+	ema *smoother
+}
+
+func (st *emaStep) peek(c Candle) map[string]float64 {
+	if v, ok := st.ema.next(c.Close); ok {
+		return map[string]float64{"": v}
+	}
+	return nil
+}
+
+func (st *emaStep) push(c Candle) {
+	st.ema.push(c.Close)
+}
+
+type rsiStep struct {
+	gains, losses *smoother
+	prevClose     float64
+	started       bool
+}
+
+func (st *rsiStep) peek(c Candle) map[string]float64 {
+	if !st.started {
+		return nil
+	}
+	change := c.Close - st.prevClose
+	gain, ok := st.gains.next(math.Max(change, 0))
+	loss, _ := st.losses.next(math.Max(-change, 0))
+	if !ok {
+		return nil
+	}
+	return map[string]float64{"": rsiFromAverages(gain, loss)}
+}
+
+func (st *rsiStep) push(c Candle) {
+	if st.started {
+		change := c.Close - st.prevClose
+		st.gains.push(math.Max(change, 0))
+		st.losses.push(math.Max(-change, 0))
+	}
+	st.prevClose, st.started = c.Close, true
+}
+
+type macdStep struct {
+	fast, slow, signal *smoother
+}
+
+func (st *macdStep) peek(c Candle) map[string]float64 {
+	fast, fastOK := st.fast.next(c.Close)
+	slow, slowOK := st.slow.next(c.Close)
+	if !fastOK || !slowOK {
+		return nil
+	}
+
+	macd := fast - slow
+	values := map[string]float64{"": macd}
+	if signal, ok := st.signal.next(macd); ok {
+		values["signal"] = signal
+		values["histogram"] = macd - signal
+	}
+	return values
+}
+
+func (st *macdStep) push(c Candle) {
+	st.fast.push(c.Close)
+	st.slow.push(c.Close)
+	// The signal line averages MACD from the first candle both EMAs have a value
+	if st.fast.ready() && st.slow.ready() {
+		st.signal.push(st.fast.value - st.slow.value)
+	}
+}
@@ -0,0 +1,57 @@
+package indicators
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestIncrementalMatchesBatch(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	closes := make([]float64, 80)
+	price := 100.0
+	for i := range closes {
+		price *= 1 + (rng.Float64()-0.5)*0.04
+		closes[i] = price
+	}
+	s := candles(closes...)
+
+	specs, err := ParseSpecs("sma(5),ema(7),rsi(14),rsi(3),macd(3,6,4),macd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.Label, func(t *testing.T) {
+			inc, err := spec.Incremental(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, c := range s {
+				// A revision of the forming candle must not leak into the next one
+				revised := c
+				revised.Close *= 1.01
+				inc.Update(revised)
+				got := inc.Update(c)
+
+				batch := Compute(s[:i+1], []Spec{spec})
+				for key, points := range batch {
+					value, ok := got[key]
+					warmedUp := len(points) > 0 && points[len(points)-1].Time == c.Time
+					switch {
+					case warmedUp && !ok:
+						t.Fatalf("candle %d: %s missing, batch has %.6f", i, key, points[len(points)-1].Value)
+					case !warmedUp && ok:
+						t.Fatalf("candle %d: %s = %.6f before the batch line starts", i, key, value)
+					case warmedUp && math.Abs(value-points[len(points)-1].Value) > 1e-6:
+						t.Fatalf("candle %d: %s = %.6f, batch has %.6f", i, key, value, points[len(points)-1].Value)
+					}
+				}
+				if len(got) > len(batch) {
+					t.Fatalf("candle %d: incremental has %d lines, batch has %d", i, len(got), len(batch))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+const tolerance = 1e-9
+
+// candles builds a series with one-minute candles closing at closes
+func candles(closes ...float64) Series {
+	s := make(Series, len(closes))
+	for i, c := range closes {
+		s[i] = Candle{Time: int64(i) * 60, Open: c, High: c, Low: c, Close: c, Volume: 1000}
+	}
+	return s
+}
+
+// assertPoints checks points against want, the expected values from candle first on
+func assertPoints(t *testing.T, name string, s Series, points []Point, first int, want []float64, tol float64) {
+	t.Helper()
+	if len(points) != len(want) {
+		t.Fatalf("%s: got %d points, want %d", name, len(points), len(want))
+	}
+	for i, p := range points {
+		if p.Time != s[first+i].Time {
+			t.Errorf("%s[%d]: time %d, want %d (candle %d)", name, i, p.Time, s[first+i].Time, first+i)
+		}
+		if math.Abs(p.Value-want[i]) > tol {
+			t.Errorf("%s[%d]: got %.6f, want %.6f", name, i, p.Value, want[i])
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	s := candles(10, 11, 12, 13, 20)
+	assertPoints(t, "sma(3)", s, SMA(s, 3), 2, []float64{11, 12, 15}, tolerance)
+	assertPoints(t, "sma(1)", s, SMA(s, 1), 0, []float64{10, 11, 12, 13, 20}, tolerance)
+
+	if points := SMA(s, 6); len(points) != 0 {
+		t.Errorf("sma longer than the series: got %d points, want none", len(points))
+	}
+}
+
+func TestEMA(t *testing.T) {
+	s := candles(10, 11, 12, 13, 20)
+	// Seeded with the SMA of the first three closes, then k = 2/(3+1)
+	assertPoints(t, "ema(3)", s, EMA(s, 3), 2, []float64{11, 12, 16}, tolerance)
+}
+
+func TestWilderSeeding(t *testing.T) {
+	values := wilderValues([]float64{10, 11, 12, 13, 20}, 3)
+	want := []float64{math.NaN(), math.NaN(), 11, 35.0 / 3, 130.0 / 9}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(values[i]) || (!math.IsNaN(want[i]) && math.Abs(values[i]-want[i]) > tolerance) {
+			t.Errorf("wilder[%d]: got %v, want %v", i, values[i], want[i])
+		}
+	}
+}
+
+func TestEMASkipsLeadingNaNs(t *testing.T) {
+	values := emaValues([]float64{math.NaN(), math.NaN(), 1, 2, 3}, 2)
+	if !math.IsNaN(values[2]) {
+		t.Errorf("ema[2]: got %v, want NaN while warming up", values[2])
+	}
+	if math.Abs(values[3]-1.5) > tolerance || math.Abs(values[4]-2.5) > tolerance {
+		t.Errorf("ema after NaNs: got %v, %v, want 1.5, 2.5", values[3], values[4])
+	}
+}
+
+func TestRSI(t *testing.T) {
+	t.Run("all gains", func(t *testing.T) {
+		closes := make([]float64, 20)
+		for i := range closes {
+			closes[i] = float64(100 + i)
+		}
+		s := candles(closes...)
+		assertPoints(t, "rsi(14)", s, RSI(s, 14), 14, []float64{100, 100, 100, 100, 100, 100}, tolerance)
+	})
+
+	t.Run("flat", func(t *testing.T) {
+		closes := make([]float64, 16)
+		for i := range closes {
+			closes[i] = 42
+		}
+		s := candles(closes...)
+		assertPoints(t, "rsi(14)", s, RSI(s, 14), 14, []float64{50, 50}, tolerance)
+	})
+
+	t.Run("textbook", func(t *testing.T) {
+		// The StockCharts worked example. Its table rounds the averages and reads 70.53
+		// for the first value; unrounded, 3.34 of gains and 1.40 of losses give 70.46.
+		s := candles(
+			44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+			45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+		)
+		want := []float64{70.46, 66.25, 66.48, 69.35, 66.29, 57.92}
+		assertPoints(t, "rsi(14)", s, RSI(s, 14), 14, want, 0.01)
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		s := candles(1, 2, 3)
+		if points := RSI(s, 3); len(points) != 0 {
+			t.Errorf("got %d points from 2 changes, want none", len(points))
+		}
+	})
+}
+
+func TestMACD(t *testing.T) {
+	closes := []float64{22, 23, 21, 24, 25, 27, 26, 28, 30, 29, 31, 33, 32, 34, 36}
+	s := candles(closes...)
+	fast, slow := EMA(s, 3), EMA(s, 5)
+	r := MACD(s, 3, 5, 2)
+
+	// MACD starts when the slow EMA does, at candle slow-1
+	want := make([]float64, 0, len(s)-4)
+	for i := 4; i < len(s); i++ {
+		want = append(want, fast[i-2].Value-slow[i-4].Value)
+	}
+	assertPoints(t, "macd", s, r.MACD, 4, want, tolerance)
+
+	// The signal line needs signal MACD values, so it starts signal-1 candles later
+	signal := emaValues(want, 2)[1:]
+	assertPoints(t, "signal", s, r.Signal, 5, signal, tolerance)
+
+	histogram := make([]float64, len(signal))
+	for i := range signal {
+		histogram[i] = want[i+1] - signal[i]
+	}
+	assertPoints(t, "histogram", s, r.Histogram, 5, histogram, tolerance)
+}
+
+// bars builds one-minute candles from high, low and close triples
+func bars(hlc ...[3]float64) Series {
+	s := make(Series, len(hlc))
+	for i, c := range hlc {
+		s[i] = Candle{Time: int64(i) * 60, Open: c[2], High: c[0], Low: c[1], Close: c[2], Volume: 1000}
+	}
+	return s
+}
+
+// swing rises for three candles, then falls for two
+func swing() Series {
+	return bars([3]float64{10, 8, 9}, [3]float64{12, 9, 11}, [3]float64{13, 10, 12}, [3]float64{12, 8, 9}, [3]float64{9, 7, 8})
+}
+
+// assertTimed checks points against want, time and value, for lines that skip
+// candles or are plotted ahead of or behind them
+func assertTimed(t *testing.T, name string, points, want []Point) {
+	t.Helper()
+	if len(points) != len(want) {
+		t.Fatalf("%s: got %v, want %v", name, points, want)
+	}
+	for i, p := range points {
+		if p.Time != want[i].Time || math.Abs(p.Value-want[i].Value) > 1e-6 {
+			t.Errorf("%s[%d]: got %.6f at %d, want %.6f at %d", name, i, p.Value, p.Time, want[i].Value, want[i].Time)
+		}
+	}
+}
+
+func TestBollingerBands(t *testing.T) {
+	s := candles(1, 2, 3, 4, 5)
+	r := BollingerBands(s, 3, 2)
+
+	// Every window is three consecutive integers: variance 2/3 about the middle one
+	width := 2 * math.Sqrt(2.0/3)
+	assertPoints(t, "middle", s, r.Middle, 2, []float64{2, 3, 4}, tolerance)
+	assertPoints(t, "upper", s, r.Upper, 2, []float64{2 + width, 3 + width, 4 + width}, tolerance)
+	assertPoints(t, "lower", s, r.Lower, 2, []float64{2 - width, 3 - width, 4 - width}, tolerance)
+
+	flat := candles(7, 7, 7)
+	r = BollingerBands(flat, 3, 2)
+	assertPoints(t, "flat upper", flat, r.Upper, 2, []float64{7}, tolerance)
+	assertPoints(t, "flat lower", flat, r.Lower, 2, []float64{7}, tolerance)
+}
+
+func TestStochastic(t *testing.T) {
+	s := swing()
+	r := Stochastic(s, 3, 2)
+
+	// Closes of 12, 9 and 8 within ranges of 8-13, 8-13 and 7-13
+	assertPoints(t, "k", s, r.K, 2, []float64{80, 20, 100.0 / 6}, tolerance)
+	assertPoints(t, "d", s, r.D, 3, []float64{50, 110.0 / 6}, tolerance)
+
+	flat := candles(5, 5, 5)
+	assertPoints(t, "flat k", flat, Stochastic(flat, 3, 2).K, 2, []float64{50}, tolerance)
+}
+
+func TestATR(t *testing.T) {
+	s := swing()
+	// True ranges 3, 3, 4 and 2; the gap down into candle 3 counts from the close of 12
+	assertPoints(t, "atr(3)", s, ATR(s, 3), 3, []float64{10.0 / 3, 26.0 / 9}, tolerance)
+}
+
+func TestADX(t *testing.T) {
+	s := swing()
+	r := ADX(s, 2)
+
+	// Smoothed true ranges 3, 3.5 and 2.75 against +DM of 1.5, 0.75 and 0.375
+	// and -DM of 0, 1 and 1
+	assertPoints(t, "+di", s, r.PlusDI, 2, []float64{50, 150.0 / 7, 150.0 / 11}, tolerance)
+	assertPoints(t, "-di", s, r.MinusDI, 2, []float64{0, 200.0 / 7, 400.0 / 11}, tolerance)
+	// DX of 100, 100/7 and 500/11, smoothed from candle 2*period-1
+	assertPoints(t, "adx", s, r.ADX, 3, []float64{400.0 / 7, 7900.0 / 154}, tolerance)
+}
+
+func TestIchimoku(t *testing.T) {
+	r := Ichimoku(swing(), 2, 3, 4)
+
+	assertTimed(t, "tenkan", r.Tenkan, []Point{{60, 10}, {120, 11}, {180, 10.5}, {240, 9.5}})
+	assertTimed(t, "kijun", r.Kijun, []Point{{120, 10.5}, {180, 10.5}, {240, 10}})
+	// The cloud is plotted kijun candles ahead, past the end of the series
+	assertTimed(t, "senkou a", r.SenkouA, []Point{{300, 10.75}, {360, 10.5}, {420, 9.75}})
+	assertTimed(t, "senkou b", r.SenkouB, []Point{{360, 10.5}, {420, 10}})
+	// and the lagging span kijun candles behind
+	assertTimed(t, "chikou", r.Chikou, []Point{{0, 9}, {60, 8}})
+}
+
+func TestParabolicSAR(t *testing.T) {
+	s := append(swing(), Candle{Time: 300, High: 7, Low: 6, Close: 6}, Candle{Time: 360, High: 6, Low: 5, Close: 5})
+
+	// Rising from the first low: the stop is held under the last two lows, then
+	// candle 3 breaks it and the stop flips to the high of 13. Falling, the factor
+	// reaches its 0.2 limit at candle 4 and stays there: 12 + 0.2*(6-12) at candle 6.
+	want := []float64{8, 8, 13, 13, 12, 10.8}
+	assertPoints(t, "sar", s, ParabolicSAR(s, 0.1, 0.2), 1, want, tolerance)
+
+	if points := ParabolicSAR(s[:1], 0.02, 0.2); len(points) != 0 {
+		t.Errorf("got %d points from one candle, want none", len(points))
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	const midnight = 86400
+	s := Series{
+		{Time: midnight - 120, High: 10, Low: 10, Close: 10, Volume: 100},
+		{Time: midnight - 60, High: 13, Low: 10, Close: 10, Volume: 300},
+		// A new day with nothing traded yet
+		{Time: midnight, High: 20, Low: 20, Close: 20, Volume: 0},
+		{Time: midnight + 60, High: 30, Low: 30, Close: 30, Volume: 100},
+	}
+
+	// Typical prices 10 and 11 weighted 1:3, then restarted at midnight
+	assertTimed(t, "vwap", VWAP(s), []Point{{midnight - 120, 10}, {midnight - 60, 10.75}, {midnight + 60, 30}})
+}
+
+func TestOBV(t *testing.T) {
+	s := candles(10, 11, 11, 9, 12)
+	for i := range s {
+		s[i].Volume = float64(100 * (i + 1))
+	}
+
+	// Up 200, unchanged, down 400, up 500
+	assertPoints(t, "obv", s, OBV(s), 0, []float64{0, 200, 200, -200, 300}, tolerance)
+}
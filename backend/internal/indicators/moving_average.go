@@ -0,0 +1,100 @@
+package indicators
+
+import "math"
+
+// SMA is the simple moving average of the closes over period candles
+func SMA(s Series, period int) []Point {
+	return s.points(smaValues(s.Closes(), period))
+}
+
+// EMA is the exponential moving average of the closes, seeded with the SMA of the
+// first period candles. The first point is aligned with candle period-1.
+func EMA(s Series, period int) []Point {
+	return s.points(emaValues(s.Closes(), period))
+}
+
+// smaValues returns one value per input, index-aligned with values. Leading NaNs
+// in values are skipped, and the first period-1 values after them are NaN.
+func smaValues(values []float64, period int) []float64 {
+	out := nanSlice(len(values))
+	if period < 1 {
+		return out
+	}
+
+	first := leadingNaNs(values)
+	sum := 0.0
+	for i := first; i < len(values); i++ {
+		sum += values[i]
+		if i-first >= period {
+			sum -= values[i-period]
+		}
+		if i-first >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// emaValues returns one value per input, index-aligned with values. Leading NaNs in
+// values (the warm-up of another indicator) are skipped before seeding.
+func emaValues(values []float64, period int) []float64 {
+	out := nanSlice(len(values))
+	if period < 1 {
+		return out
+	}
+
+	first := leadingNaNs(values)
+	if len(values)-first < period {
+		return out
+	}
+
+	seed := 0.0
+	for _, v := range values[first : first+period] {
+		seed += v
+	}
+	ema := seed / float64(period)
+	out[first+period-1] = ema
+
+	k := 2 / (float64(period) + 1)
+	for i := first + period; i < len(values); i++ {
+		ema = values[i]*k + ema*(1-k)
+		out[i] = ema
+	}
+	return out
+}
+
+// leadingNaNs counts the NaNs before the first value
+func leadingNaNs(values []float64) int {
+	n := 0
+	for n < len(values) && math.IsNaN(values[n]) {
+		n++
+	}
+	return n
+}
+
+// wilderValues is Wilder's smoothing (an EMA with k = 1/period) seeded with the
+// SMA of the first period values, index-aligned with values
+func wilderValues(values []float64, period int) []float64 {
+	out := nanSlice(len(values))
+	if period < 1 {
+		return out
+	}
+
+	first := leadingNaNs(values)
+	if len(values)-first < period {
+		return out
+	}
+
+	avg := 0.0
+	for _, v := range values[first : first+period] {
+		avg += v
+	}
+	avg /= float64(period)
+	out[first+period-1] = avg
+
+	for i := first + period; i < len(values); i++ {
+		avg = (avg*float64(period-1) + values[i]) / float64(period)
+		out[i] = avg
+	}
+	return out
+}
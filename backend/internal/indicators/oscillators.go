@@ -0,0 +1,110 @@
+package indicators
+
+import "math"
+
+// RSI is Wilder's relative strength index over period candles. The first point is
+// aligned with candle period, the first with period price changes behind it.
+// A window with no losses reads 100, and one with no movement at all reads 50.
+func RSI(s Series, period int) []Point {
+	return s.points(rsiValues(s.Closes(), period))
+}
+
+func rsiValues(closes []float64, period int) []float64 {
+	out := nanSlice(len(closes))
+	if period < 1 || len(closes) <= period {
+		return out
+	}
+
+	// gains[i] and losses[i] hold the change into candle i
+	gains := nanSlice(len(closes))
+	losses := nanSlice(len(closes))
+	for i := 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		gains[i] = math.Max(change, 0)
+		losses[i] = math.Max(-change, 0)
+	}
+
+	avgGains := wilderValues(gains, period)
+	avgLosses := wilderValues(losses, period)
+	for i := period; i < len(closes); i++ {
+		out[i] = rsiFromAverages(avgGains[i], avgLosses[i])
+	}
+	return out
+}
+
+// rsiFromAverages converts average gain and loss to RSI without dividing by zero
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	switch {
+	case avgLoss == 0 && avgGain == 0:
+		return 50
+	case avgLoss == 0:
+		return 100
+	default:
+		return 100 - 100/(1+avgGain/avgLoss)
+	}
+}
+
+// MACDResult holds the three MACD lines; each is aligned with the candles by Time
+type MACDResult struct {
+	MACD      []Point `json:"macd"`
+	Signal    []Point `json:"signal"`
+	Histogram []Point `json:"histogram"`
+}
+
+// MACD is the fast EMA minus the slow EMA of the closes, with a signal line that is
+// the signal-period EMA of MACD and a histogram of MACD minus signal.
+// The usual parameters are 12, 26 and 9.
+func MACD(s Series, fast, slow, signal int) MACDResult {
+	macd, sig, hist := macdValues(s.Closes(), fast, slow, signal)
+	return MACDResult{
+		MACD:      s.points(macd),
+		Signal:    s.points(sig),
+		Histogram: s.points(hist),
+	}
+}
+
+func macdValues(closes []float64, fast, slow, signal int) (macd, sig, hist []float64) {
+	fastEMA := emaValues(closes, fast)
+	slowEMA := emaValues(closes, slow)
+
+	// NaN propagates, so MACD starts once both averages have warmed up
+	macd = make([]float64, len(closes))
+	for i := range closes {
+		macd[i] = fastEMA[i] - slowEMA[i]
+	}
+
+	sig = emaValues(macd, signal)
+	hist = make([]float64, len(closes))
+	for i := range closes {
+		hist[i] = macd[i] - sig[i]
+	}
+	return macd, sig, hist
+}
+
+// StochasticResult holds the %K and %D lines
+type StochasticResult struct {
+	K []Point `json:"k"`
+	D []Point `json:"d"`
+}
+
+// Stochastic is the fast stochastic oscillator: %K places the close within the
+// range of the last kPeriod candles (50 when the range is flat) and %D is the
+// dPeriod SMA of %K. The usual parameters are 14 and 3.
+func Stochastic(s Series, kPeriod, dPeriod int) StochasticResult {
+	k := nanSlice(len(s))
+	for i := range s {
+		if kPeriod < 1 || i < kPeriod-1 {
+			continue
+		}
+		high, low := highest(s, i, kPeriod), lowest(s, i, kPeriod)
+		k[i] = 50
+		if high > low {
+			k[i] = 100 * (s[i].Close - low) / (high - low)
+		}
+	}
+
+	return StochasticResult{
+		K: s.points(k),
+		D: s.points(smaValues(k, dPeriod)),
+	}
+}
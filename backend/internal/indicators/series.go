@@ -0,0 +1,77 @@
+// Package indicators computes technical indicators over a typed candle series.
+package indicators
+
+import (
+	"math"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// Candle is one OHLCV bar; Time is the Unix start of the bar
+type Candle struct {
+	Time   int64   `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+}
+
+// Series is a run of candles, oldest first
+type Series []Candle
+
+// Point is one indicator value, aligned with the candle at Time
+type Point struct {
+	Time  int64   `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// FromBar converts a stored bar into a Candle
+func FromBar(bar *pb.HistoricalPrice) Candle {
+	return Candle{
+		Time:   bar.Timestamp,
+		Open:   bar.Open,
+		High:   bar.High,
+		Low:    bar.Low,
+		Close:  bar.Close,
+		Volume: bar.Volume,
+	}
+}
+
+// FromBars converts stored bars into a Series
+func FromBars(bars []*pb.HistoricalPrice) Series {
+	series := make(Series, len(bars))
+	for i, bar := range bars {
+		series[i] = FromBar(bar)
+	}
+	return series
+}
+
+// Closes returns the closing prices
+func (s Series) Closes() []float64 {
+	closes := make([]float64, len(s))
+	for i, c := range s {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+// points pairs values with candle times, skipping the NaN warm-up values
+func (s Series) points(values []float64) []Point {
+	points := make([]Point, 0, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			points = append(points, Point{Time: s[i].Time, Value: v})
+		}
+	}
+	return points
+}
+
+// nanSlice returns n NaNs, the placeholder for indexes an indicator has no value for yet
+func nanSlice(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
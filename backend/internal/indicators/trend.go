@@ -0,0 +1,158 @@
+package indicators
+
+import "math"
+
+// ADXResult holds the average directional index and the two directional indicators
+type ADXResult struct {
+	ADX     []Point `json:"adx"`
+	PlusDI  []Point `json:"plus_di"`
+	MinusDI []Point `json:"minus_di"`
+}
+
+// ADX is Wilder's average directional index over period candles. +DI and -DI start
+// at candle period and ADX, a smoothed average of their spread, at candle 2*period-1.
+func ADX(s Series, period int) ADXResult {
+	plusDM := nanSlice(len(s))
+	minusDM := nanSlice(len(s))
+	for i := 1; i < len(s); i++ {
+		up := s[i].High - s[i-1].High
+		down := s[i-1].Low - s[i].Low
+		plusDM[i], minusDM[i] = 0, 0
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+
+	atr := wilderValues(trueRanges(s), period)
+	avgPlus := wilderValues(plusDM, period)
+	avgMinus := wilderValues(minusDM, period)
+
+	plusDI := nanSlice(len(s))
+	minusDI := nanSlice(len(s))
+	dx := nanSlice(len(s))
+	for i := range s {
+		if math.IsNaN(atr[i]) {
+			continue
+		}
+		if atr[i] > 0 {
+			plusDI[i] = 100 * avgPlus[i] / atr[i]
+			minusDI[i] = 100 * avgMinus[i] / atr[i]
+		} else {
+			plusDI[i], minusDI[i] = 0, 0
+		}
+		dx[i] = 0
+		if sum := plusDI[i] + minusDI[i]; sum > 0 {
+			dx[i] = 100 * math.Abs(plusDI[i]-minusDI[i]) / sum
+		}
+	}
+
+	return ADXResult{
+		ADX:     s.points(wilderValues(dx, period)),
+		PlusDI:  s.points(plusDI),
+		MinusDI: s.points(minusDI),
+	}
+}
+
+// IchimokuResult holds the five Ichimoku Kinko Hyo lines
+type IchimokuResult struct {
+	Tenkan  []Point `json:"tenkan"`
+	Kijun   []Point `json:"kijun"`
+	SenkouA []Point `json:"senkou_a"`
+	SenkouB []Point `json:"senkou_b"`
+	Chikou  []Point `json:"chikou"`
+}
+
+// Ichimoku computes the conversion (tenkan) and base (kijun) lines, the two cloud
+// spans plotted kijun candles ahead, and the lagging span plotted kijun candles
+// behind. The usual parameters are 9, 26 and 52. Cloud points past the last
+// candle are timed by extending the spacing of the last two candles.
+func Ichimoku(s Series, tenkan, kijun, senkouB int) IchimokuResult {
+	midpoint := func(i, period int) float64 {
+		if period < 1 || i < period-1 {
+			return math.NaN()
+		}
+		return (highest(s, i, period) + lowest(s, i, period)) / 2
+	}
+
+	var result IchimokuResult
+	for i := range s {
+		conversion := midpoint(i, tenkan)
+		base := midpoint(i, kijun)
+		if !math.IsNaN(conversion) {
+			result.Tenkan = append(result.Tenkan, Point{Time: s[i].Time, Value: conversion})
+		}
+		if !math.IsNaN(base) {
+			result.Kijun = append(result.Kijun, Point{Time: s[i].Time, Value: base})
+		}
+		if !math.IsNaN(conversion) && !math.IsNaN(base) {
+			result.SenkouA = append(result.SenkouA, Point{Time: s.timeAt(i + kijun), Value: (conversion + base) / 2})
+		}
+		if span := midpoint(i, senkouB); !math.IsNaN(span) {
+			result.SenkouB = append(result.SenkouB, Point{Time: s.timeAt(i + kijun), Value: span})
+		}
+		if i >= kijun {
+			result.Chikou = append(result.Chikou, Point{Time: s[i-kijun].Time, Value: s[i].Close})
+		}
+	}
+
+	return result
+}
+
+// timeAt returns the time of candle i, extrapolating past the end of the series
+func (s Series) timeAt(i int) int64 {
+	n := len(s)
+	if i < n {
+		return s[i].Time
+	}
+	step := int64(0)
+	if n > 1 {
+		step = s[n-1].Time - s[n-2].Time
+	}
+	return s[n-1].Time + int64(i-n+1)*step
+}
+
+// ParabolicSAR is Wilder's stop and reverse. The acceleration factor starts at step,
+// grows by step with each new extreme up to limit, and resets when the trend flips.
+// The usual parameters are 0.02 and 0.2. The first point is aligned with candle 1.
+func ParabolicSAR(s Series, step, limit float64) []Point {
+	out := nanSlice(len(s))
+	if len(s) < 2 {
+		return s.points(out)
+	}
+
+	rising := s[1].Close >= s[0].Close
+	sar, extreme := s[0].High, s[1].Low
+	if rising {
+		sar, extreme = s[0].Low, s[1].High
+	}
+	af := step
+	out[1] = sar
+
+	for i := 2; i < len(s); i++ {
+		sar += af * (extreme - sar)
+
+		if rising {
+			// The stop may not rise above the last two lows
+			sar = math.Min(sar, math.Min(s[i-1].Low, s[i-2].Low))
+			if s[i].Low < sar {
+				rising, sar, extreme, af = false, extreme, s[i].Low, step
+			} else if s[i].High > extreme {
+				extreme, af = s[i].High, math.Min(af+step, limit)
+			}
+		} else {
+			sar = math.Max(sar, math.Max(s[i-1].High, s[i-2].High))
+			if s[i].High > sar {
+				rising, sar, extreme, af = true, extreme, s[i].High, step
+			} else if s[i].Low < extreme {
+				extreme, af = s[i].Low, math.Min(af+step, limit)
+			}
+		}
+
+		out[i] = sar
+	}
+
+	return s.points(out)
+}
@@ -0,0 +1,71 @@
+package indicators
+
+import "math"
+
+// BollingerResult holds the three Bollinger Bands lines
+type BollingerResult struct {
+	Upper  []Point `json:"upper"`
+	Middle []Point `json:"middle"`
+	Lower  []Point `json:"lower"`
+}
+
+// BollingerBands is the period SMA of the closes with bands k population standard
+// deviations above and below it. The usual parameters are 20 and 2.
+func BollingerBands(s Series, period int, k float64) BollingerResult {
+	closes := s.Closes()
+	middle := smaValues(closes, period)
+	upper := nanSlice(len(closes))
+	lower := nanSlice(len(closes))
+
+	for i := range closes {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		variance := 0.0
+		for _, c := range closes[i-period+1 : i+1] {
+			variance += (c - middle[i]) * (c - middle[i])
+		}
+		sd := math.Sqrt(variance / float64(period))
+		upper[i] = middle[i] + k*sd
+		lower[i] = middle[i] - k*sd
+	}
+
+	return BollingerResult{
+		Upper:  s.points(upper),
+		Middle: s.points(middle),
+		Lower:  s.points(lower),
+	}
+}
+
+// ATR is Wilder's average true range over period candles. The first point is
+// aligned with candle period, since true range needs the previous close.
+func ATR(s Series, period int) []Point {
+	return s.points(wilderValues(trueRanges(s), period))
+}
+
+// trueRanges returns the true range into each candle; the first is NaN
+func trueRanges(s Series) []float64 {
+	tr := nanSlice(len(s))
+	for i := 1; i < len(s); i++ {
+		prevClose := s[i-1].Close
+		tr[i] = math.Max(s[i].High-s[i].Low, math.Max(math.Abs(s[i].High-prevClose), math.Abs(s[i].Low-prevClose)))
+	}
+	return tr
+}
+
+// highest and lowest return the extreme high and low of the period candles ending at i
+func highest(s Series, i, period int) float64 {
+	high := s[i].High
+	for _, c := range s[i-period+1 : i] {
+		high = math.Max(high, c.High)
+	}
+	return high
+}
+
+func lowest(s Series, i, period int) float64 {
+	low := s[i].Low
+	for _, c := range s[i-period+1 : i] {
+		low = math.Min(low, c.Low)
+	}
+	return low
+}
@@ -0,0 +1,42 @@
+package indicators
+
+import "math"
+
+// VWAP is the volume-weighted average of the typical price (high+low+close)/3,
+// restarting at the first candle of each UTC day. Candles before any volume has
+// traded that day have no value.
+func VWAP(s Series) []Point {
+	out := nanSlice(len(s))
+
+	day := int64(math.MinInt64)
+	var priceVolume, volume float64
+	for i, c := range s {
+		if d := c.Time / 86400; d != day {
+			day, priceVolume, volume = d, 0, 0
+		}
+		priceVolume += (c.High + c.Low + c.Close) / 3 * c.Volume
+		volume += c.Volume
+		if volume > 0 {
+			out[i] = priceVolume / volume
+		}
+	}
+
+	return s.points(out)
+}
+
+// OBV is on-balance volume: a running total that adds a candle's volume when it
+// closes up and subtracts it when it closes down, starting from zero
+func OBV(s Series) []Point {
+	out := make([]float64, len(s))
+	for i := 1; i < len(s); i++ {
+		out[i] = out[i-1]
+		switch {
+		case s[i].Close > s[i-1].Close:
+			out[i] += s[i].Volume
+		case s[i].Close < s[i-1].Close:
+			out[i] -= s[i].Volume
+		}
+	}
+
+	return s.points(out)
+}
@@ -0,0 +1,197 @@
+// Package quotestub is a fake Alpha Vantage and Finnhub quote API serving canned
+// responses, so the vendor price sources can be exercised without network access
+// or API keys.
+package quotestub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quote is a canned quote served for one symbol
+type Quote struct {
+	Open          float64
+	High          float64
+	Low           float64
+	Price         float64
+	Volume        float64
+	PreviousClose float64
+	Timestamp     int64
+}
+
+// DefaultQuotes are served when the stub is created without quotes of its own
+var DefaultQuotes = map[string]Quote{
+	"AAPL":  {Open: 174.20, High: 176.10, Low: 173.85, Price: 175.50, Volume: 52341200, PreviousClose: 173.90, Timestamp: 1704229200},
+	"GOOGL": {Open: 139.60, High: 141.00, Low: 139.10, Price: 140.25, Volume: 21877300, PreviousClose: 139.70, Timestamp: 1704229200},
+	"MSFT":  {Open: 376.40, High: 381.90, Low: 375.80, Price: 380.75, Volume: 19842100, PreviousClose: 376.04, Timestamp: 1704229200},
+	"AMZN":  {Open: 151.10, High: 153.20, Low: 150.60, Price: 152.30, Volume: 40388500, PreviousClose: 151.94, Timestamp: 1704229200},
+	"TSLA":  {Open: 247.90, High: 249.50, Low: 240.10, Price: 242.80, Volume: 104654200, PreviousClose: 248.48, Timestamp: 1704229200},
+	"NVDA":  {Open: 492.40, High: 498.10, Low: 489.20, Price: 495.60, Volume: 41125600, PreviousClose: 495.22, Timestamp: 1704229200},
+}
+
+// Server serves GET /query?function=GLOBAL_QUOTE (Alpha Vantage) and GET /api/v1/quote (Finnhub)
+type Server struct {
+	mu     sync.Mutex
+	quotes map[string]Quote
+
+	// requestsPerMinute of zero disables the simulated quota
+	requestsPerMinute int
+	windowStart       time.Time
+	windowCount       int
+}
+
+func NewServer(quotes map[string]Quote) *Server {
+	if quotes == nil {
+		quotes = DefaultQuotes
+	}
+
+	copied := make(map[string]Quote, len(quotes))
+	for symbol, q := range quotes {
+		copied[symbol] = q
+	}
+
+	return &Server{quotes: copied}
+}
+
+// SetQuote replaces the canned quote for a symbol
+func (s *Server) SetQuote(symbol string, quote Quote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quotes[strings.ToUpper(symbol)] = quote
+}
+
+// SetRateLimit makes the stub reject requests beyond n per minute the way each vendor does
+func (s *Server) SetRateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requestsPerMinute = n
+	s.windowStart = time.Time{}
+	s.windowCount = 0
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/query":
+		s.alphaVantage(w, r)
+	case "/api/v1/quote":
+		s.finnhub(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) alphaVantage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("apikey") == "" {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"Error Message": "the parameter apikey is invalid or missing.",
+		})
+		return
+	}
+	if q.Get("function") != "GLOBAL_QUOTE" {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"Error Message": "This API function (" + q.Get("function") + ") does not exist.",
+		})
+		return
+	}
+	if !s.allow() {
+		// Alpha Vantage reports quota problems with a 200 and a Note
+		writeJSON(w, http.StatusOK, map[string]string{
+			"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute.",
+		})
+		return
+	}
+
+	symbol := strings.ToUpper(q.Get("symbol"))
+	quote, ok := s.quote(symbol)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]map[string]string{"Global Quote": {}})
+		return
+	}
+
+	change := quote.Price - quote.PreviousClose
+	writeJSON(w, http.StatusOK, map[string]map[string]string{
+		"Global Quote": {
+			"01. symbol":             symbol,
+			"02. open":               fmt.Sprintf("%.4f", quote.Open),
+			"03. high":               fmt.Sprintf("%.4f", quote.High),
+			"04. low":                fmt.Sprintf("%.4f", quote.Low),
+			"05. price":              fmt.Sprintf("%.4f", quote.Price),
+			"06. volume":             fmt.Sprintf("%.0f", quote.Volume),
+			"07. latest trading day": time.Unix(quote.Timestamp, 0).UTC().Format("2006-01-02"),
+			"08. previous close":     fmt.Sprintf("%.4f", quote.PreviousClose),
+			"09. change":             fmt.Sprintf("%.4f", change),
+			"10. change percent":     fmt.Sprintf("%.4f%%", change/quote.PreviousClose*100),
+		},
+	})
+}
+
+func (s *Server) finnhub(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("token") == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Please use an API key."})
+		return
+	}
+	if !s.allow() {
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "API limit reached. Please try again later."})
+		return
+	}
+
+	// Finnhub answers unknown symbols with an all-zero quote
+	quote, _ := s.quote(strings.ToUpper(q.Get("symbol")))
+
+	change := quote.Price - quote.PreviousClose
+	changePercent := 0.0
+	if quote.PreviousClose != 0 {
+		changePercent = change / quote.PreviousClose * 100
+	}
+	writeJSON(w, http.StatusOK, map[string]float64{
+		"c":  quote.Price,
+		"d":  change,
+		"dp": changePercent,
+		"h":  quote.High,
+		"l":  quote.Low,
+		"o":  quote.Open,
+		"pc": quote.PreviousClose,
+		"t":  float64(quote.Timestamp),
+	})
+}
+
+func (s *Server) quote(symbol string) (Quote, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote, ok := s.quotes[symbol]
+	return quote, ok
+}
+
+// allow counts a request against the simulated per-minute quota
+func (s *Server) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.requestsPerMinute == 0 {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+
+	return s.windowCount <= s.requestsPerMinute
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
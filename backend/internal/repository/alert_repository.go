@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	// pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
@@ -12,6 +13,8 @@ import (
 // Simple alert struct to replace protobuf
 type Alert struct {
 	ID             string
+	UserID         string
+	PortfolioID    string
 	Symbol         string
 	TargetPrice    float64
 	Condition      int32 // 0 = ABOVE, 1 = BELOW
@@ -28,6 +31,13 @@ const (
 	AlertCondition_BELOW AlertCondition = 1
 )
 
+var (
+	// ErrAlertNotFound is returned when an alert does not exist
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrAlertAlreadyTriggered is returned when another caller triggered the alert first
+	ErrAlertAlreadyTriggered = errors.New("alert already triggered")
+)
+
 type AlertRepository struct {
 	db *sql.DB
 }
@@ -36,7 +46,7 @@ func NewAlertRepository(db *sql.DB) *AlertRepository {
 	return &AlertRepository{db: db}
 }
 
-func (r *AlertRepository) CreateAlert(ctx context.Context, userID, symbol string, targetPrice float64, condition AlertCondition) (string, error) {
+func (r *AlertRepository) CreateAlert(ctx context.Context, userID, portfolioID, symbol string, targetPrice float64, condition AlertCondition) (string, error) {
 	alertID := uuid.New().String()
 	var conditionStr string
 	if condition == AlertCondition_ABOVE {
@@ -46,11 +56,11 @@ func (r *AlertRepository) CreateAlert(ctx context.Context, userID, symbol string
 	}
 
 	query := `
-		INSERT INTO price_alerts (id, user_id, symbol, target_price, condition, is_triggered)
-		VALUES ($1, $2, $3, $4, $5, false)
+		INSERT INTO price_alerts (id, user_id, portfolio_id, symbol, target_price, condition, is_triggered)
+		VALUES ($1, $2, $3, $4, $5, $6, false)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, alertID, userID, symbol, targetPrice, conditionStr)
+	_, err := r.db.ExecContext(ctx, query, alertID, userID, portfolioID, symbol, targetPrice, conditionStr)
 	if err != nil {
 		return "", fmt.Errorf("failed to create alert: %w", err)
 	}
@@ -60,7 +70,8 @@ func (r *AlertRepository) CreateAlert(ctx context.Context, userID, symbol string
 
 func (r *AlertRepository) GetActiveAlerts(ctx context.Context, symbol string) ([]*Alert, error) {
 	query := `
-		SELECT id, user_id, symbol, target_price, condition, created_at
+		SELECT id, user_id, portfolio_id, symbol, target_price, condition,
+		       EXTRACT(EPOCH FROM created_at)::BIGINT
 		FROM price_alerts
 		WHERE symbol = $1 AND is_triggered = false
 	`
@@ -75,11 +86,11 @@ func (r *AlertRepository) GetActiveAlerts(ctx context.Context, symbol string) ([
 	for rows.Next() {
 		var alert Alert
 		var conditionStr string
-		var userID string
 
 		err := rows.Scan(
 			&alert.ID,
-			&userID,
+			&alert.UserID,
+			&alert.PortfolioID,
 			&alert.Symbol,
 			&alert.TargetPrice,
 			&conditionStr,
@@ -107,27 +118,45 @@ func (r *AlertRepository) TriggerAlert(ctx context.Context, alertID string, trig
 	query := `
 		UPDATE price_alerts
 		SET is_triggered = true, triggered_price = $1, triggered_at = $2
-		WHERE id = $3
+		WHERE id = $3 AND is_triggered = false
 	`
 
-	_, err := r.db.ExecContext(ctx, query, triggeredPrice, triggeredAt, alertID)
+	result, err := r.db.ExecContext(ctx, query, triggeredPrice, triggeredAt, alertID)
 	if err != nil {
 		return fmt.Errorf("failed to trigger alert: %w", err)
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		// Either the alert is gone or another instance won the race
+		var exists bool
+		err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM price_alerts WHERE id = $1)`, alertID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check alert: %w", err)
+		}
+		if exists {
+			return ErrAlertAlreadyTriggered
+		}
+		return ErrAlertNotFound
+	}
+
 	return nil
 }
 
-func (r *AlertRepository) GetUserAlerts(ctx context.Context, userID string) ([]*Alert, error) {
+func (r *AlertRepository) GetUserAlerts(ctx context.Context, userID, portfolioID string) ([]*Alert, error) {
 	query := `
-		SELECT id, symbol, target_price, condition, is_triggered, 
-		       triggered_price, triggered_at, created_at
+		SELECT id, portfolio_id, symbol, target_price, condition, is_triggered,
+		       triggered_price, triggered_at, EXTRACT(EPOCH FROM created_at)::BIGINT
 		FROM price_alerts
-		WHERE user_id = $1
+		WHERE user_id = $1 AND ($2::text = '' OR portfolio_id = $2::text)
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, portfolioID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user alerts: %w", err)
 	}
@@ -140,8 +169,10 @@ func (r *AlertRepository) GetUserAlerts(ctx context.Context, userID string) ([]*
 		var triggeredPrice sql.NullFloat64
 		var triggeredAt sql.NullInt64
 
+		alert.UserID = userID
 		err := rows.Scan(
 			&alert.ID,
+			&alert.PortfolioID,
 			&alert.Symbol,
 			&alert.TargetPrice,
 			&conditionStr,
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+// ErrCashEntryNotFound is returned when a cash entry does not exist or belongs to another user
+var ErrCashEntryNotFound = errors.New("cash entry not found or unauthorized")
+
+type CashRepository struct {
+	db *sql.DB
+}
+
+func NewCashRepository(db *sql.DB) *CashRepository {
+	return &CashRepository{db: db}
+}
+
+func (r *CashRepository) RecordCashEntry(ctx context.Context, userID string, entry *pb.CashEntry) (*pb.CashEntry, error) {
+	stored := copyCashEntry(entry)
+	stored.Id = uuid.New().String()
+
+	query := `
+		INSERT INTO cash_entries (id, user_id, type, amount, symbol, description, timestamp, currency, portfolio_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query, stored.Id, userID, stored.Type.String(), stored.Amount,
+		stored.Symbol, stored.Description, stored.Timestamp, stored.Currency, stored.PortfolioId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record cash entry: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (r *CashRepository) GetCashEntries(ctx context.Context, userID, portfolioID string) ([]*pb.CashEntry, error) {
+	query := `
+		SELECT id, type, amount, COALESCE(symbol, ''), COALESCE(description, ''), timestamp, currency, portfolio_id
+		FROM cash_entries
+		WHERE user_id = $1 AND ($2::text = '' OR portfolio_id = $2::text)
+		ORDER BY timestamp, created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cash entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*pb.CashEntry
+	for rows.Next() {
+		var entry pb.CashEntry
+		var entryType string
+		err := rows.Scan(
+			&entry.Id,
+			&entryType,
+			&entry.Amount,
+			&entry.Symbol,
+			&entry.Description,
+			&entry.Timestamp,
+			&entry.Currency,
+			&entry.PortfolioId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cash entry: %w", err)
+		}
+		entry.Type = pb.CashEntryType(pb.CashEntryType_value[entryType])
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *CashRepository) DeleteCashEntry(ctx context.Context, userID, entryID string) error {
+	query := `DELETE FROM cash_entries WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, entryID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cash entry: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCashEntryNotFound
+	}
+
+	return nil
+}
+
+func copyCashEntry(entry *pb.CashEntry) *pb.CashEntry {
+	return &pb.CashEntry{
+		Id:          entry.Id,
+		Type:        entry.Type,
+		Amount:      entry.Amount,
+		Symbol:      entry.Symbol,
+		Description: entry.Description,
+		Timestamp:   entry.Timestamp,
+		Currency:    entry.Currency,
+		PortfolioId: entry.PortfolioId,
+	}
+}
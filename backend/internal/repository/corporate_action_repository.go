@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+// CorporateActionRepository stores corporate actions and applies them to every
+// user's ledger, alerts and cash entries and to the recorded price history
+type CorporateActionRepository struct {
+	db *sql.DB
+}
+
+func NewCorporateActionRepository(db *sql.DB) *CorporateActionRepository {
+	return &CorporateActionRepository{db: db}
+}
+
+const selectCorporateActions = `
+	SELECT id, symbol, type, ex_date, COALESCE(split_to, 0), COALESCE(split_from, 0),
+	       COALESCE(cash_in_lieu_price, 0), COALESCE(new_symbol, ''), COALESCE(applied_at, 0),
+	       transactions_adjusted, alerts_adjusted, history_adjusted, cash_in_lieu_sales
+	FROM corporate_actions
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *CorporateActionRepository) CreateCorporateAction(ctx context.Context, action *pb.CorporateAction) (*pb.CorporateAction, error) {
+	stored := copyCorporateAction(action)
+	stored.Id = uuid.New().String()
+	stored.AppliedAt = 0
+	stored.TransactionsAdjusted = 0
+	stored.AlertsAdjusted = 0
+	stored.HistoryAdjusted = 0
+	stored.CashInLieuSales = 0
+
+	query := `
+		INSERT INTO corporate_actions (id, symbol, type, ex_date, split_to, split_from, cash_in_lieu_price, new_symbol)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, 0), NULLIF($8, ''))
+	`
+	_, err := r.db.ExecContext(ctx, query, stored.Id, stored.Symbol, stored.Type.String(), stored.ExDate,
+		stored.SplitTo, stored.SplitFrom, stored.CashInLieuPrice, stored.NewSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create corporate action: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (r *CorporateActionRepository) GetCorporateActions(ctx context.Context, symbol string) ([]*pb.CorporateAction, error) {
+	query := selectCorporateActions + `
+		WHERE $1::text = '' OR symbol = $1::text OR new_symbol = $1::text
+		ORDER BY ex_date, created_at, id
+	`
+	return r.query(ctx, query, symbol)
+}
+
+func (r *CorporateActionRepository) DueCorporateActions(ctx context.Context, asOf int64) ([]*pb.CorporateAction, error) {
+	query := selectCorporateActions + `
+		WHERE applied_at IS NULL AND ex_date <= $1
+		ORDER BY ex_date, created_at, id
+	`
+	return r.query(ctx, query, asOf)
+}
+
+func (r *CorporateActionRepository) ApplyCorporateAction(ctx context.Context, actionID string, appliedAt int64) (*pb.CorporateAction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes a second instance wait, then see the action as applied
+	action, err := scanCorporateAction(tx.QueryRowContext(ctx, selectCorporateActions+` WHERE id = $1 FOR UPDATE`, actionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCorporateActionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if action.AppliedAt != 0 {
+		return nil, ErrCorporateActionApplied
+	}
+
+	switch action.Type {
+	case pb.CorporateActionType_SPLIT:
+		err = applySplit(ctx, tx, action)
+	case pb.CorporateActionType_SYMBOL_CHANGE:
+		err = applySymbolChange(ctx, tx, action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	action.AppliedAt = appliedAt
+	query := `
+		UPDATE corporate_actions
+		SET applied_at = $2, transactions_adjusted = $3, alerts_adjusted = $4,
+		    history_adjusted = $5, cash_in_lieu_sales = $6
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, action.Id, action.AppliedAt, action.TransactionsAdjusted,
+		action.AlertsAdjusted, action.HistoryAdjusted, action.CashInLieuSales)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record corporate action: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return action, nil
+}
+
+// applySplit restates trades and history before the ex-date in post-split shares, rescales
+// active alerts and sells any fractional shares left over for cash in lieu
+func applySplit(ctx context.Context, tx *sql.Tx, action *pb.CorporateAction) error {
+	ratio := splitRatio(action)
+
+	users, err := lockHolders(ctx, tx, action.Symbol, action.Symbol)
+	if err != nil {
+		return err
+	}
+
+	action.TransactionsAdjusted, err = execCount(ctx, tx,
+		`UPDATE transactions SET quantity = quantity * $3, price = price / $3 WHERE symbol = $1 AND timestamp < $2`,
+		action.Symbol, action.ExDate, ratio)
+	if err != nil {
+		return fmt.Errorf("failed to adjust transactions: %w", err)
+	}
+
+	// A triggered alert records the price it fired at, so only untriggered ones move
+	action.AlertsAdjusted, err = execCount(ctx, tx,
+		`UPDATE price_alerts SET target_price = target_price / $2 WHERE symbol = $1 AND is_triggered = false`,
+		action.Symbol, ratio)
+	if err != nil {
+		return fmt.Errorf("failed to adjust alerts: %w", err)
+	}
+
+	ticks, err := execCount(ctx, tx,
+		`UPDATE price_ticks SET price = price / $3, volume = volume * $3 WHERE symbol = $1 AND timestamp < $2`,
+		action.Symbol, action.ExDate, ratio)
+	if err != nil {
+		return fmt.Errorf("failed to adjust ticks: %w", err)
+	}
+	bars, err := execCount(ctx, tx, `
+		UPDATE price_bars
+		SET open = open / $3, high = high / $3, low = low / $3, close = close / $3, volume = volume * $3
+		WHERE symbol = $1 AND timestamp < $2`,
+		action.Symbol, action.ExDate, ratio)
+	if err != nil {
+		return fmt.Errorf("failed to adjust bars: %w", err)
+	}
+	action.HistoryAdjusted = ticks + bars
+
+	for _, userID := range users {
+		ledger, err := loadLedger(ctx, tx, userID, "", action.Symbol)
+		if err != nil {
+			return err
+		}
+		sells, err := cashInLieu(ledger, action)
+		if err != nil {
+			return err
+		}
+		if len(sells) == 0 {
+			continue
+		}
+
+		for _, sell := range sells {
+			sell.Id = uuid.New().String()
+			if err := insertTransaction(ctx, tx, userID, sell); err != nil {
+				return err
+			}
+		}
+		// Later sells may not depend on the fractions just paid out
+		if ledger, err = loadLedger(ctx, tx, userID, "", action.Symbol); err != nil {
+			return err
+		}
+		if _, err := PositionsFromLedger(ledger); err != nil {
+			return fmt.Errorf("failed to pay cash in lieu to %s: %w", userID, err)
+		}
+		action.CashInLieuSales += int64(len(sells))
+	}
+
+	return nil
+}
+
+// applySymbolChange moves every trade, alert, dividend and price recorded under the old
+// symbol to the new one. Where both symbols have history at the same time, the new
+// symbol's is kept.
+func applySymbolChange(ctx context.Context, tx *sql.Tx, action *pb.CorporateAction) error {
+	if _, err := lockHolders(ctx, tx, action.Symbol, action.Symbol, action.NewSymbol); err != nil {
+		return err
+	}
+
+	var err error
+	action.TransactionsAdjusted, err = execCount(ctx, tx,
+		`UPDATE transactions SET symbol = $2 WHERE symbol = $1`, action.Symbol, action.NewSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to adjust transactions: %w", err)
+	}
+	action.AlertsAdjusted, err = execCount(ctx, tx,
+		`UPDATE price_alerts SET symbol = $2 WHERE symbol = $1`, action.Symbol, action.NewSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to adjust alerts: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE cash_entries SET symbol = $2 WHERE symbol = $1`, action.Symbol, action.NewSymbol); err != nil {
+		return fmt.Errorf("failed to adjust cash entries: %w", err)
+	}
+
+	ticks, err := execCount(ctx, tx, `
+		UPDATE price_ticks t SET symbol = $2
+		WHERE t.symbol = $1 AND NOT EXISTS (
+			SELECT 1 FROM price_ticks n WHERE n.symbol = $2 AND n.timestamp = t.timestamp AND n.sequence = t.sequence
+		)`,
+		action.Symbol, action.NewSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to adjust ticks: %w", err)
+	}
+	bars, err := execCount(ctx, tx, `
+		UPDATE price_bars b SET symbol = $2
+		WHERE b.symbol = $1 AND NOT EXISTS (
+			SELECT 1 FROM price_bars n WHERE n.symbol = $2 AND n.timeframe = b.timeframe AND n.timestamp = b.timestamp
+		)`,
+		action.Symbol, action.NewSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to adjust bars: %w", err)
+	}
+	action.HistoryAdjusted = ticks + bars
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM price_ticks WHERE symbol = $1`, action.Symbol); err != nil {
+		return fmt.Errorf("failed to delete ticks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM price_bars WHERE symbol = $1`, action.Symbol); err != nil {
+		return fmt.Errorf("failed to delete bars: %w", err)
+	}
+
+	return nil
+}
+
+// lockHolders takes the ledger lock in each of symbols for every user with trades in
+// holding, in a fixed order, and returns those users
+func lockHolders(ctx context.Context, tx *sql.Tx, holding string, symbols ...string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT user_id FROM transactions WHERE symbol = $1 ORDER BY user_id`, holding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holders: %w", err)
+	}
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan holder: %w", err)
+		}
+		users = append(users, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get holders: %w", err)
+	}
+
+	for _, userID := range users {
+		for _, symbol := range symbols {
+			if err := lockLedger(ctx, tx, userID, symbol); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// execCount runs a statement and returns how many rows it changed
+func execCount(ctx context.Context, tx *sql.Tx, query string, args ...any) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *CorporateActionRepository) query(ctx context.Context, query string, args ...any) ([]*pb.CorporateAction, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []*pb.CorporateAction
+	for rows.Next() {
+		action, err := scanCorporateAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
+
+func scanCorporateAction(row rowScanner) (*pb.CorporateAction, error) {
+	var action pb.CorporateAction
+	var actionType string
+	err := row.Scan(
+		&action.Id,
+		&action.Symbol,
+		&actionType,
+		&action.ExDate,
+		&action.SplitTo,
+		&action.SplitFrom,
+		&action.CashInLieuPrice,
+		&action.NewSymbol,
+		&action.AppliedAt,
+		&action.TransactionsAdjusted,
+		&action.AlertsAdjusted,
+		&action.HistoryAdjusted,
+		&action.CashInLieuSales,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan corporate action: %w", err)
+	}
+	action.Type = pb.CorporateActionType(pb.CorporateActionType_value[actionType])
+
+	return &action, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"math"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/costbasis"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+var (
+	// ErrCorporateActionNotFound is returned when a corporate action does not exist
+	ErrCorporateActionNotFound = errors.New("corporate action not found")
+	// ErrCorporateActionApplied is returned when a corporate action was already applied
+	ErrCorporateActionApplied = errors.New("corporate action already applied")
+)
+
+// fractionTolerance absorbs float error in split quantities, so 3 shares split 1 for 3
+// is one whole share rather than 0.9999999 and a fraction
+const fractionTolerance = 1e-6
+
+// splitRatio is the number of shares each pre-split share becomes
+func splitRatio(action *pb.CorporateAction) float64 {
+	return action.SplitTo / action.SplitFrom
+}
+
+// splitTransaction restates a pre-split trade in post-split shares; what it cost is unchanged
+func splitTransaction(txn *pb.Transaction, ratio float64) {
+	txn.Quantity *= ratio
+	txn.Price /= ratio
+}
+
+// splitBar restates a pre-split bar in post-split prices and share volume
+func splitBar(bar *pb.HistoricalPrice, ratio float64) {
+	bar.Open /= ratio
+	bar.High /= ratio
+	bar.Low /= ratio
+	bar.Close /= ratio
+	bar.Volume *= ratio
+}
+
+// cashInLieu returns the SELLs that pay out the fractional share a split left in each of a
+// user's portfolios, none if the action pays no cash in lieu or every position is whole.
+// ledger is the user's trades in the symbol, already restated by the split.
+func cashInLieu(ledger []*pb.Transaction, action *pb.CorporateAction) ([]*pb.Transaction, error) {
+	if action.Type != pb.CorporateActionType_SPLIT || action.CashInLieuPrice <= 0 {
+		return nil, nil
+	}
+
+	var before []*pb.Transaction
+	currency := ""
+	for _, txn := range ledger {
+		if txn.Timestamp < action.ExDate {
+			before = append(before, txn)
+		}
+		currency = txn.Currency
+	}
+	book, err := costbasis.Match(before)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]float64)
+	var portfolios []string
+	for _, lot := range book.Lots[action.Symbol] {
+		if _, ok := held[lot.PortfolioID]; !ok {
+			portfolios = append(portfolios, lot.PortfolioID)
+		}
+		held[lot.PortfolioID] += lot.Quantity
+	}
+
+	var sells []*pb.Transaction
+	for _, portfolioID := range portfolios {
+		fraction := held[portfolioID] - math.Floor(held[portfolioID]+fractionTolerance)
+		if fraction < fractionTolerance {
+			continue
+		}
+		sells = append(sells, &pb.Transaction{
+			Symbol:      action.Symbol,
+			Type:        pb.TransactionType_SELL,
+			Quantity:    fraction,
+			Price:       action.CashInLieuPrice,
+			Timestamp:   action.ExDate,
+			LotMethod:   pb.LotMethod_FIFO,
+			Currency:    currency,
+			PortfolioId: portfolioID,
+		})
+	}
+
+	return sells, nil
+}
+
+func copyCorporateAction(action *pb.CorporateAction) *pb.CorporateAction {
+	return &pb.CorporateAction{
+		Id:                   action.Id,
+		Symbol:               action.Symbol,
+		Type:                 action.Type,
+		ExDate:               action.ExDate,
+		SplitTo:              action.SplitTo,
+		SplitFrom:            action.SplitFrom,
+		CashInLieuPrice:      action.CashInLieuPrice,
+		NewSymbol:            action.NewSymbol,
+		AppliedAt:            action.AppliedAt,
+		TransactionsAdjusted: action.TransactionsAdjusted,
+		AlertsAdjusted:       action.AlertsAdjusted,
+		HistoryAdjusted:      action.HistoryAdjusted,
+		CashInLieuSales:      action.CashInLieuSales,
+	}
+}
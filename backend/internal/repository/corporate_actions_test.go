@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// ledgerLines formats trades as "BUY 15@100.00" for comparison
+func ledgerLines(ledger []*pb.Transaction) []string {
+	out := make([]string, len(ledger))
+	for i, txn := range ledger {
+		out[i] = fmt.Sprintf("%s %g@%.2f", txn.Type, txn.Quantity, txn.Price)
+	}
+	return out
+}
+
+func TestApplySplit(t *testing.T) {
+	ctx := context.Background()
+	stocks := NewMemoryStockRepository()
+	alerts := NewMemoryAlertRepository()
+	history := NewMemoryPriceHistoryRepository()
+	actions := NewMemoryCorporateActionRepository(stocks, alerts, NewMemoryCashRepository(), history)
+
+	for _, txn := range []*pb.Transaction{
+		trade("AAPL", pb.TransactionType_BUY, 10, 150, 100),
+		trade("AAPL", pb.TransactionType_BUY, 5, 160, 200),
+		// Bought after the ex-date, already in post-split shares
+		trade("AAPL", pb.TransactionType_BUY, 1, 80, 400),
+		{Symbol: "AAPL", Type: pb.TransactionType_BUY, Quantity: 2, Price: 140, Timestamp: 100, PortfolioId: "p2"},
+		trade("MSFT", pb.TransactionType_BUY, 3, 300, 100),
+	} {
+		if _, _, err := stocks.RecordTransaction(ctx, "alice", txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := alerts.CreateAlert(ctx, "alice", "p1", "AAPL", 300, AlertCondition_ABOVE); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.SaveTicks(ctx, []*PriceTick{
+		{Symbol: "AAPL", Sequence: 1, Price: 150, Volume: 10, Timestamp: 250},
+		{Symbol: "AAPL", Sequence: 2, Price: 80, Volume: 10, Timestamp: 350},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.SaveBars(ctx, "AAPL", "1m", []*pb.HistoricalPrice{{Timestamp: 240, Open: 150, High: 153, Low: 147, Close: 150, Volume: 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 for 2 with cash in lieu of the half share it leaves in p1
+	action, err := actions.CreateCorporateAction(ctx, &pb.CorporateAction{
+		Symbol: "AAPL", Type: pb.CorporateActionType_SPLIT, ExDate: 300, SplitTo: 3, SplitFrom: 2, CashInLieuPrice: 75,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := actions.ApplyCorporateAction(ctx, action.Id, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.AppliedAt != 500 || applied.TransactionsAdjusted != 3 || applied.CashInLieuSales != 1 ||
+		applied.AlertsAdjusted != 1 || applied.HistoryAdjusted != 2 {
+		t.Errorf("applied %+v, want 3 trades, 1 sale, 1 alert and 2 history rows adjusted", applied)
+	}
+
+	// Pre-split trades are in post-split shares at the same total cost
+	ledger, err := stocks.GetTransactions(ctx, "alice", "p1", "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"BUY 15@100.00", "BUY 7.5@106.67", "SELL 0.5@75.00", "BUY 1@80.00"}
+	if got := ledgerLines(ledger); !slices.Equal(got, want) {
+		t.Errorf("p1 ledger %v, want %v", got, want)
+	}
+	if ledger[2].Timestamp != 300 {
+		t.Errorf("cash in lieu sold at %d, want the ex-date", ledger[2].Timestamp)
+	}
+
+	positions, err := stocks.GetPortfolio(ctx, "alice", "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stock := range positions {
+		switch stock.Symbol {
+		case "AAPL":
+			// FIFO takes the half share from the first lot: 14.5@100, 7.5 costing 800 and 1@80
+			if stock.Quantity != 23 || math.Abs(stock.PurchasePrice-2330.0/23) > 1e-9 {
+				t.Errorf("AAPL %g at %.4f, want 23 at %.4f", stock.Quantity, stock.PurchasePrice, 2330.0/23)
+			}
+		case "MSFT":
+			if stock.Quantity != 3 || stock.PurchasePrice != 300 {
+				t.Errorf("MSFT %g at %g, want it untouched", stock.Quantity, stock.PurchasePrice)
+			}
+		}
+	}
+
+	// A whole position gets no cash in lieu
+	ledger, err = stocks.GetTransactions(ctx, "alice", "p2", "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ledgerLines(ledger); !slices.Equal(got, []string{"BUY 3@93.33"}) {
+		t.Errorf("p2 ledger %v, want [BUY 3@93.33]", got)
+	}
+
+	active, err := alerts.GetActiveAlerts(ctx, "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].TargetPrice != 200 {
+		t.Errorf("alerts %+v, want the target rescaled to 200", active)
+	}
+
+	ticks, err := history.GetTicks(ctx, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticks) != 2 || ticks[0].Price != 100 || ticks[0].Volume != 15 || ticks[1].Price != 80 || ticks[1].Volume != 10 {
+		t.Errorf("ticks %+v %+v, want only the one before the ex-date restated", ticks[0], ticks[1])
+	}
+	bars, err := history.GetBars(ctx, "AAPL", "1m", 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bar := bars[0]; bar.Open != 100 || bar.High != 102 || bar.Low != 98 || bar.Close != 100 || bar.Volume != 15 {
+		t.Errorf("bar %+v, want prices divided and volume multiplied by 1.5", bar)
+	}
+
+	if _, err := actions.ApplyCorporateAction(ctx, action.Id, 600); !errors.Is(err, ErrCorporateActionApplied) {
+		t.Errorf("second apply: got %v, want ErrCorporateActionApplied", err)
+	}
+}
+
+func TestApplySplitLeavesLedgerOnFailure(t *testing.T) {
+	ctx := context.Background()
+	stocks := NewMemoryStockRepository()
+	actions := NewMemoryCorporateActionRepository(stocks, NewMemoryAlertRepository(), NewMemoryCashRepository(), NewMemoryPriceHistoryRepository())
+
+	for _, txn := range []*pb.Transaction{
+		trade("AAPL", pb.TransactionType_BUY, 3, 100, 100),
+		// Recorded before the split was, so it counts the half share that goes to cash
+		trade("AAPL", pb.TransactionType_SELL, 1.5, 210, 400),
+	} {
+		if _, _, err := stocks.RecordTransaction(ctx, "alice", txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	action, err := actions.CreateCorporateAction(ctx, &pb.CorporateAction{
+		Symbol: "AAPL", Type: pb.CorporateActionType_SPLIT, ExDate: 300, SplitTo: 1, SplitFrom: 2, CashInLieuPrice: 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actions.ApplyCorporateAction(ctx, action.Id, 500); !errors.Is(err, ErrInsufficientShares) {
+		t.Fatalf("got %v, want ErrInsufficientShares", err)
+	}
+
+	ledger, err := stocks.GetTransactions(ctx, "alice", "p1", "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ledgerLines(ledger), []string{"BUY 3@100.00", "SELL 1.5@210.00"}; !slices.Equal(got, want) {
+		t.Errorf("ledger %v, want it unchanged %v", got, want)
+	}
+	if due, _ := actions.DueCorporateActions(ctx, 500); len(due) != 1 {
+		t.Errorf("%d due actions, want the failed split still scheduled", len(due))
+	}
+}
+
+func TestCashInLieu(t *testing.T) {
+	split := &pb.CorporateAction{Symbol: "AAPL", Type: pb.CorporateActionType_SPLIT, ExDate: 300, SplitTo: 1, SplitFrom: 3, CashInLieuPrice: 90}
+	ledger := []*pb.Transaction{
+		trade("AAPL", pb.TransactionType_BUY, 10, 30, 100),
+		{Symbol: "AAPL", Type: pb.TransactionType_BUY, Quantity: 3, Price: 30, Timestamp: 100, PortfolioId: "p2"},
+	}
+	for _, txn := range ledger {
+		splitTransaction(txn, splitRatio(split))
+	}
+	// After the ex-date, so not part of the fraction
+	ledger = append(ledger, trade("AAPL", pb.TransactionType_BUY, 0.5, 90, 400))
+
+	sells, err := cashInLieu(ledger, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// p1's 10 shares become 3 1/3; p2's 3 become one whole share within tolerance
+	if len(sells) != 1 || sells[0].PortfolioId != "p1" || math.Abs(sells[0].Quantity-1.0/3) > 1e-9 ||
+		sells[0].Price != 90 || sells[0].Timestamp != 300 {
+		t.Errorf("sells %+v, want a third of a share at 90 from p1", sells)
+	}
+
+	noCash := &pb.CorporateAction{Symbol: "AAPL", Type: pb.CorporateActionType_SPLIT, ExDate: 300, SplitTo: 1, SplitFrom: 3}
+	if sells, err := cashInLieu(ledger, noCash); err != nil || len(sells) != 0 {
+		t.Errorf("got %v, %v without a cash in lieu price, want nothing", sells, err)
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/costbasis"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTransactionNotFound is returned when a transaction does not exist or belongs to another user
+	ErrTransactionNotFound = errors.New("transaction not found or unauthorized")
+	// ErrInsufficientShares is returned when a sell, or removing a buy, would leave a
+	// position holding fewer than zero shares at some point in the ledger
+	ErrInsufficientShares = costbasis.ErrInsufficientShares
+	// ErrLotUnavailable is returned when a specific-lot sell names a lot that is not
+	// open at the time of the sale or holds too few shares
+	ErrLotUnavailable = costbasis.ErrLotUnavailable
+	// ErrCurrencyMismatch is returned when a trade is priced in a different currency
+	// from the user's other trades in the symbol
+	ErrCurrencyMismatch = errors.New("trades in a symbol must all be in one currency")
+)
+
+// PositionsFromLedger matches the sells in transactions, oldest first, to the lots
+// they close and returns one position per symbol still held, valued at the cost of
+// its open lots in the symbol's currency
+func PositionsFromLedger(ledger []*pb.Transaction) ([]*pb.Stock, error) {
+	book, err := costbasis.Match(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	currencies := make(map[string]string)
+	for _, txn := range ledger {
+		if txn.Name != "" {
+			names[txn.Symbol] = txn.Name
+		}
+		if currency, ok := currencies[txn.Symbol]; ok && currency != txn.Currency {
+			return nil, ErrCurrencyMismatch
+		}
+		currencies[txn.Symbol] = txn.Currency
+	}
+
+	stocks := make([]*pb.Stock, 0, len(book.Lots))
+	for symbol, lots := range book.Lots {
+		stock := &pb.Stock{Id: symbol, Symbol: symbol, Name: symbol, PurchaseDate: lots[0].PurchaseDate, Currency: currencies[symbol]}
+		if name, ok := names[symbol]; ok {
+			stock.Name = name
+		}
+
+		cost := 0.0
+		for _, lot := range lots {
+			stock.Quantity += lot.Quantity
+			cost += lot.Quantity * lot.CostPerShare
+			stock.PurchaseDate = min(stock.PurchaseDate, lot.PurchaseDate)
+		}
+		stock.PurchasePrice = cost / stock.Quantity
+		stocks = append(stocks, stock)
+	}
+	// Most recently opened first, like the stocks rows they replace
+	sort.Slice(stocks, func(i, j int) bool {
+		if stocks[i].PurchaseDate != stocks[j].PurchaseDate {
+			return stocks[i].PurchaseDate > stocks[j].PurchaseDate
+		}
+		return stocks[i].Symbol < stocks[j].Symbol
+	})
+
+	return stocks, nil
+}
+
+// checkCurrency returns ErrCurrencyMismatch if any trade in ledger is in another currency.
+// Positions are valued per symbol across portfolios, so ledger holds the symbol's trades
+// in all of the user's portfolios, not only the one being traded in.
+func checkCurrency(ledger []*pb.Transaction, currency string) error {
+	for _, txn := range ledger {
+		if txn.Currency != currency {
+			return ErrCurrencyMismatch
+		}
+	}
+	return nil
+}
+
+// closingSells returns a SELL of the open shares in each portfolio's position in symbol,
+// dated timestamp or after the last trade in ledger, whichever is later. A zero price
+// sells at the cost of the open lots, realizing no gain. Trades already in the ledger
+// are kept, so the gains and cash flows they made are still reported.
+func closingSells(ledger []*pb.Transaction, symbol string, price float64, timestamp int64) ([]*pb.Transaction, error) {
+	book, err := costbasis.Match(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	var name, currency string
+	for _, txn := range ledger {
+		timestamp = max(timestamp, txn.Timestamp)
+		if txn.Name != "" {
+			name = txn.Name
+		}
+		currency = txn.Currency
+	}
+
+	var sells []*pb.Transaction
+	byPortfolio := make(map[string]*pb.Transaction)
+	cost := make(map[string]float64)
+	for _, lot := range book.Lots[symbol] {
+		sell, ok := byPortfolio[lot.PortfolioID]
+		if !ok {
+			sell = &pb.Transaction{
+				Id:          uuid.New().String(),
+				Symbol:      symbol,
+				Name:        name,
+				Type:        pb.TransactionType_SELL,
+				Price:       price,
+				Timestamp:   timestamp,
+				LotMethod:   pb.LotMethod_FIFO,
+				Currency:    currency,
+				PortfolioId: lot.PortfolioID,
+			}
+			byPortfolio[lot.PortfolioID] = sell
+			sells = append(sells, sell)
+		}
+		sell.Quantity += lot.Quantity
+		cost[lot.PortfolioID] += lot.Quantity * lot.CostPerShare
+	}
+	for _, sell := range sells {
+		if sell.Price <= 0 {
+			sell.Price = cost[sell.PortfolioId] / sell.Quantity
+		}
+	}
+
+	return sells, nil
+}
+
+// normalizeSymbol returns symbol trimmed and upper-cased, as trades are stored
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// positionFor returns the position in symbol, or nil if none is held
+func positionFor(ledger []*pb.Transaction, symbol string) (*pb.Stock, error) {
+	stocks, err := PositionsFromLedger(ledger)
+	if err != nil {
+		return nil, err
+	}
+	for _, stock := range stocks {
+		if stock.Symbol == symbol {
+			return stock, nil
+		}
+	}
+	return nil, nil
+}
+
+func copyTransaction(txn *pb.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		Id:          txn.Id,
+		Symbol:      txn.Symbol,
+		Name:        txn.Name,
+		Type:        txn.Type,
+		Quantity:    txn.Quantity,
+		Price:       txn.Price,
+		Fees:        txn.Fees,
+		Timestamp:   txn.Timestamp,
+		LotMethod:   txn.LotMethod,
+		LotId:       txn.LotId,
+		Currency:    txn.Currency,
+		PortfolioId: txn.PortfolioId,
+	}
+}
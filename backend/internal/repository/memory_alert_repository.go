@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryAlert struct {
+	userID string
+	alert  Alert
+}
+
+// MemoryAlertRepository is an in-memory AlertStore used in mock mode
+type MemoryAlertRepository struct {
+	mu     sync.RWMutex
+	alerts []*memoryAlert // in insertion (created_at) order
+}
+
+func NewMemoryAlertRepository() *MemoryAlertRepository {
+	return &MemoryAlertRepository{}
+}
+
+func (r *MemoryAlertRepository) CreateAlert(ctx context.Context, userID, portfolioID, symbol string, targetPrice float64, condition AlertCondition) (string, error) {
+	alertID := uuid.New().String()
+
+	r.mu.Lock()
+	r.alerts = append(r.alerts, &memoryAlert{
+		userID: userID,
+		alert: Alert{
+			ID:          alertID,
+			UserID:      userID,
+			PortfolioID: portfolioID,
+			Symbol:      symbol,
+			TargetPrice: targetPrice,
+			Condition:   int32(condition),
+			CreatedAt:   time.Now().Unix(),
+		},
+	})
+	r.mu.Unlock()
+
+	return alertID, nil
+}
+
+func (r *MemoryAlertRepository) GetActiveAlerts(ctx context.Context, symbol string) ([]*Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var alerts []*Alert
+	for _, a := range r.alerts {
+		if a.alert.Symbol == symbol && !a.alert.IsTriggered {
+			alerts = append(alerts, copyAlert(&a.alert))
+		}
+	}
+
+	return alerts, nil
+}
+
+func (r *MemoryAlertRepository) TriggerAlert(ctx context.Context, alertID string, triggeredPrice float64, triggeredAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range r.alerts {
+		if a.alert.ID == alertID {
+			if a.alert.IsTriggered {
+				return ErrAlertAlreadyTriggered
+			}
+			a.alert.IsTriggered = true
+			a.alert.TriggeredPrice = &triggeredPrice
+			a.alert.TriggeredAt = &triggeredAt
+			return nil
+		}
+	}
+
+	return ErrAlertNotFound
+}
+
+func (r *MemoryAlertRepository) GetUserAlerts(ctx context.Context, userID, portfolioID string) ([]*Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var alerts []*Alert
+	for i := len(r.alerts) - 1; i >= 0; i-- {
+		if r.alerts[i].userID == userID && (portfolioID == "" || r.alerts[i].alert.PortfolioID == portfolioID) {
+			alerts = append(alerts, copyAlert(&r.alerts[i].alert))
+		}
+	}
+
+	return alerts, nil
+}
+
+// copyAlert returns a deep copy so callers never share state with the store
+func copyAlert(alert *Alert) *Alert {
+	c := *alert
+	if alert.TriggeredPrice != nil {
+		price := *alert.TriggeredPrice
+		c.TriggeredPrice = &price
+	}
+	if alert.TriggeredAt != nil {
+		at := *alert.TriggeredAt
+		c.TriggeredAt = &at
+	}
+	return &c
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+type memoryCashEntry struct {
+	userID string
+	entry  *pb.CashEntry
+}
+
+// MemoryCashRepository is an in-memory CashStore used in mock mode
+type MemoryCashRepository struct {
+	mu      sync.RWMutex
+	entries []*memoryCashEntry // in insertion (created_at) order
+}
+
+func NewMemoryCashRepository() *MemoryCashRepository {
+	return &MemoryCashRepository{}
+}
+
+func (r *MemoryCashRepository) RecordCashEntry(ctx context.Context, userID string, entry *pb.CashEntry) (*pb.CashEntry, error) {
+	stored := copyCashEntry(entry)
+	stored.Id = uuid.New().String()
+
+	r.mu.Lock()
+	r.entries = append(r.entries, &memoryCashEntry{userID: userID, entry: stored})
+	r.mu.Unlock()
+
+	return copyCashEntry(stored), nil
+}
+
+func (r *MemoryCashRepository) GetCashEntries(ctx context.Context, userID, portfolioID string) ([]*pb.CashEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []*pb.CashEntry
+	for _, e := range r.entries {
+		if e.userID == userID && (portfolioID == "" || e.entry.PortfolioId == portfolioID) {
+			entries = append(entries, copyCashEntry(e.entry))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+
+	return entries, nil
+}
+
+func (r *MemoryCashRepository) DeleteCashEntry(ctx context.Context, userID, entryID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, e := range r.entries {
+		if e.entry.Id == entryID && e.userID == userID {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrCashEntryNotFound
+}
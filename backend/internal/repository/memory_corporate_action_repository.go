@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+// MemoryCorporateActionRepository is an in-memory CorporateActionStore used in mock mode.
+// It applies actions to the in-memory stores it was built with.
+type MemoryCorporateActionRepository struct {
+	mu      sync.Mutex
+	actions []*pb.CorporateAction // in insertion (created_at) order
+
+	stocks  *MemoryStockRepository
+	alerts  *MemoryAlertRepository
+	cash    *MemoryCashRepository
+	history *MemoryPriceHistoryRepository
+}
+
+func NewMemoryCorporateActionRepository(stocks *MemoryStockRepository, alerts *MemoryAlertRepository, cash *MemoryCashRepository, history *MemoryPriceHistoryRepository) *MemoryCorporateActionRepository {
+	return &MemoryCorporateActionRepository{
+		stocks:  stocks,
+		alerts:  alerts,
+		cash:    cash,
+		history: history,
+	}
+}
+
+func (r *MemoryCorporateActionRepository) CreateCorporateAction(ctx context.Context, action *pb.CorporateAction) (*pb.CorporateAction, error) {
+	stored := &pb.CorporateAction{
+		Id:              uuid.New().String(),
+		Symbol:          action.Symbol,
+		Type:            action.Type,
+		ExDate:          action.ExDate,
+		SplitTo:         action.SplitTo,
+		SplitFrom:       action.SplitFrom,
+		CashInLieuPrice: action.CashInLieuPrice,
+		NewSymbol:       action.NewSymbol,
+	}
+
+	r.mu.Lock()
+	r.actions = append(r.actions, stored)
+	r.mu.Unlock()
+
+	return copyCorporateAction(stored), nil
+}
+
+func (r *MemoryCorporateActionRepository) GetCorporateActions(ctx context.Context, symbol string) ([]*pb.CorporateAction, error) {
+	return r.filter(func(action *pb.CorporateAction) bool {
+		return symbol == "" || action.Symbol == symbol || action.NewSymbol == symbol
+	}), nil
+}
+
+func (r *MemoryCorporateActionRepository) DueCorporateActions(ctx context.Context, asOf int64) ([]*pb.CorporateAction, error) {
+	return r.filter(func(action *pb.CorporateAction) bool {
+		return action.AppliedAt == 0 && action.ExDate <= asOf
+	}), nil
+}
+
+func (r *MemoryCorporateActionRepository) ApplyCorporateAction(ctx context.Context, actionID string, appliedAt int64) (*pb.CorporateAction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := -1
+	for i, action := range r.actions {
+		if action.Id == actionID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, ErrCorporateActionNotFound
+	}
+	if r.actions[index].AppliedAt != 0 {
+		return nil, ErrCorporateActionApplied
+	}
+
+	action := copyCorporateAction(r.actions[index])
+	switch action.Type {
+	case pb.CorporateActionType_SPLIT:
+		if err := r.applySplit(action); err != nil {
+			return nil, err
+		}
+	case pb.CorporateActionType_SYMBOL_CHANGE:
+		r.applySymbolChange(action)
+	}
+	action.AppliedAt = appliedAt
+	r.actions[index] = copyCorporateAction(action)
+
+	return action, nil
+}
+
+// applySplit mirrors CorporateActionRepository's; the ledger is adjusted first so a
+// failed cash-in-lieu sale leaves every store untouched
+func (r *MemoryCorporateActionRepository) applySplit(action *pb.CorporateAction) error {
+	ratio := splitRatio(action)
+
+	r.stocks.mu.Lock()
+	previous := r.stocks.ledger
+	adjusted := make([]*memoryTransaction, 0, len(previous))
+	holders := make(map[string]bool)
+	var transactions int64
+	for _, t := range previous {
+		if t.txn.Symbol == action.Symbol && t.txn.Timestamp < action.ExDate {
+			txn := copyTransaction(t.txn)
+			splitTransaction(txn, ratio)
+			t = &memoryTransaction{userID: t.userID, txn: txn}
+			holders[t.userID] = true
+			transactions++
+		}
+		adjusted = append(adjusted, t)
+	}
+	r.stocks.ledger = adjusted
+
+	var sales int64
+	for _, userID := range sortedKeys(holders) {
+		sells, err := cashInLieu(r.stocks.userLedger(userID, "", action.Symbol), action)
+		if err == nil && len(sells) > 0 {
+			for _, sell := range sells {
+				sell.Id = uuid.New().String()
+				r.stocks.ledger = append(r.stocks.ledger, &memoryTransaction{userID: userID, txn: sell})
+			}
+			_, err = PositionsFromLedger(r.stocks.userLedger(userID, "", action.Symbol))
+			sales += int64(len(sells))
+		}
+		if err != nil {
+			r.stocks.ledger = previous
+			r.stocks.mu.Unlock()
+			return err
+		}
+	}
+	r.stocks.mu.Unlock()
+	action.TransactionsAdjusted = transactions
+	action.CashInLieuSales = sales
+
+	r.alerts.mu.Lock()
+	for _, a := range r.alerts.alerts {
+		if a.alert.Symbol == action.Symbol && !a.alert.IsTriggered {
+			a.alert.TargetPrice /= ratio
+			action.AlertsAdjusted++
+		}
+	}
+	r.alerts.mu.Unlock()
+
+	r.history.mu.Lock()
+	for i := range r.history.ticks {
+		tick := &r.history.ticks[i]
+		if tick.Symbol == action.Symbol && tick.Timestamp < action.ExDate {
+			tick.Price /= ratio
+			tick.Volume *= ratio
+			action.HistoryAdjusted++
+		}
+	}
+	for key, bar := range r.history.bars {
+		if key.symbol == action.Symbol && key.timestamp < action.ExDate {
+			splitBar(bar, ratio)
+			action.HistoryAdjusted++
+		}
+	}
+	r.history.mu.Unlock()
+
+	return nil
+}
+
+// applySymbolChange mirrors CorporateActionRepository's
+func (r *MemoryCorporateActionRepository) applySymbolChange(action *pb.CorporateAction) {
+	r.stocks.mu.Lock()
+	for _, t := range r.stocks.ledger {
+		if t.txn.Symbol == action.Symbol {
+			t.txn.Symbol = action.NewSymbol
+			action.TransactionsAdjusted++
+		}
+	}
+	r.stocks.mu.Unlock()
+
+	r.alerts.mu.Lock()
+	for _, a := range r.alerts.alerts {
+		if a.alert.Symbol == action.Symbol {
+			a.alert.Symbol = action.NewSymbol
+			action.AlertsAdjusted++
+		}
+	}
+	r.alerts.mu.Unlock()
+
+	r.cash.mu.Lock()
+	for _, e := range r.cash.entries {
+		if e.entry.Symbol == action.Symbol {
+			e.entry.Symbol = action.NewSymbol
+		}
+	}
+	r.cash.mu.Unlock()
+
+	r.history.mu.Lock()
+	ticks := r.history.ticks[:0]
+	for _, tick := range r.history.ticks {
+		if tick.Symbol == action.Symbol {
+			delete(r.history.seen, tickKey{tick.Symbol, tick.Timestamp, tick.Sequence})
+			key := tickKey{action.NewSymbol, tick.Timestamp, tick.Sequence}
+			if r.history.seen[key] {
+				continue
+			}
+			r.history.seen[key] = true
+			tick.Symbol = action.NewSymbol
+			action.HistoryAdjusted++
+		}
+		ticks = append(ticks, tick)
+	}
+	r.history.ticks = ticks
+	for key, bar := range r.history.bars {
+		if key.symbol != action.Symbol {
+			continue
+		}
+		delete(r.history.bars, key)
+		renamed := barKey{action.NewSymbol, key.interval, key.timestamp}
+		if _, exists := r.history.bars[renamed]; !exists {
+			r.history.bars[renamed] = bar
+			action.HistoryAdjusted++
+		}
+	}
+	r.history.mu.Unlock()
+}
+
+// filter returns copies of the actions that match, by ex-date
+func (r *MemoryCorporateActionRepository) filter(match func(*pb.CorporateAction) bool) []*pb.CorporateAction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var actions []*pb.CorporateAction
+	for _, action := range r.actions {
+		if match(action) {
+			actions = append(actions, copyCorporateAction(action))
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].ExDate < actions[j].ExDate
+	})
+
+	return actions
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
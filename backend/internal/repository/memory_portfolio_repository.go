@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+type memoryPortfolio struct {
+	userID    string
+	portfolio *pb.Portfolio
+}
+
+// MemoryPortfolioRepository is an in-memory PortfolioStore used in mock mode. It checks
+// and clears the in-memory stores it was built with when a portfolio is deleted.
+type MemoryPortfolioRepository struct {
+	mu         sync.RWMutex
+	portfolios []*memoryPortfolio // in insertion (created_at) order
+
+	stocks *MemoryStockRepository
+	alerts *MemoryAlertRepository
+	cash   *MemoryCashRepository
+}
+
+func NewMemoryPortfolioRepository(stocks *MemoryStockRepository, alerts *MemoryAlertRepository, cash *MemoryCashRepository) *MemoryPortfolioRepository {
+	return &MemoryPortfolioRepository{
+		stocks: stocks,
+		alerts: alerts,
+		cash:   cash,
+	}
+}
+
+func (r *MemoryPortfolioRepository) CreatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error) {
+	stored := copyPortfolio(portfolio)
+	stored.Id = uuid.New().String()
+	stored.IsDefault = false
+	stored.CreatedAt = time.Now().Unix()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(userID, stored.Name, "") {
+		return nil, ErrPortfolioNameTaken
+	}
+	r.portfolios = append(r.portfolios, &memoryPortfolio{userID: userID, portfolio: stored})
+
+	return copyPortfolio(stored), nil
+}
+
+func (r *MemoryPortfolioRepository) GetPortfolios(ctx context.Context, userID string) ([]*pb.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var portfolios []*pb.Portfolio
+	for _, p := range r.portfolios {
+		if p.userID == userID {
+			portfolios = append(portfolios, copyPortfolio(p.portfolio))
+		}
+	}
+	sort.SliceStable(portfolios, func(i, j int) bool {
+		return portfolios[i].IsDefault && !portfolios[j].IsDefault
+	})
+
+	return portfolios, nil
+}
+
+func (r *MemoryPortfolioRepository) GetPortfolio(ctx context.Context, userID, portfolioID string) (*pb.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p := r.find(userID, portfolioID); p != nil {
+		return copyPortfolio(p.portfolio), nil
+	}
+	return nil, ErrPortfolioNotFound
+}
+
+func (r *MemoryPortfolioRepository) DefaultPortfolio(ctx context.Context, userID string) (*pb.Portfolio, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p := r.find(userID, defaultPortfolioID(userID)); p != nil {
+		return copyPortfolio(p.portfolio), nil
+	}
+	if r.nameTaken(userID, defaultPortfolioName, "") {
+		return nil, ErrPortfolioNameTaken
+	}
+
+	stored := &pb.Portfolio{
+		Id:        defaultPortfolioID(userID),
+		Name:      defaultPortfolioName,
+		IsDefault: true,
+		CreatedAt: time.Now().Unix(),
+	}
+	r.portfolios = append(r.portfolios, &memoryPortfolio{userID: userID, portfolio: stored})
+
+	return copyPortfolio(stored), nil
+}
+
+func (r *MemoryPortfolioRepository) UpdatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.find(userID, portfolio.Id)
+	if p == nil {
+		return nil, ErrPortfolioNotFound
+	}
+	if r.nameTaken(userID, portfolio.Name, portfolio.Id) {
+		return nil, ErrPortfolioNameTaken
+	}
+	p.portfolio.Name = portfolio.Name
+	p.portfolio.Description = portfolio.Description
+
+	return copyPortfolio(p.portfolio), nil
+}
+
+func (r *MemoryPortfolioRepository) DeletePortfolio(ctx context.Context, userID, portfolioID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.find(userID, portfolioID)
+	if p == nil {
+		return ErrPortfolioNotFound
+	}
+	if p.portfolio.IsDefault {
+		return ErrDefaultPortfolio
+	}
+
+	r.stocks.mu.RLock()
+	used := len(r.stocks.userLedger(userID, portfolioID, "")) > 0
+	r.stocks.mu.RUnlock()
+	if !used {
+		r.cash.mu.RLock()
+		for _, e := range r.cash.entries {
+			if e.userID == userID && e.entry.PortfolioId == portfolioID {
+				used = true
+				break
+			}
+		}
+		r.cash.mu.RUnlock()
+	}
+	if used {
+		return ErrPortfolioNotEmpty
+	}
+
+	r.alerts.mu.Lock()
+	kept := r.alerts.alerts[:0]
+	for _, a := range r.alerts.alerts {
+		if a.userID != userID || a.alert.PortfolioID != portfolioID {
+			kept = append(kept, a)
+		}
+	}
+	clear(r.alerts.alerts[len(kept):])
+	r.alerts.alerts = kept
+	r.alerts.mu.Unlock()
+
+	for i, candidate := range r.portfolios {
+		if candidate == p {
+			r.portfolios = append(r.portfolios[:i], r.portfolios[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// find returns the user's portfolio with the ID, or nil. r.mu must be held.
+func (r *MemoryPortfolioRepository) find(userID, portfolioID string) *memoryPortfolio {
+	for _, p := range r.portfolios {
+		if p.userID == userID && p.portfolio.Id == portfolioID {
+			return p
+		}
+	}
+	return nil
+}
+
+// nameTaken reports whether another of the user's portfolios than exceptID is called name. r.mu must be held.
+func (r *MemoryPortfolioRepository) nameTaken(userID, name, exceptID string) bool {
+	for _, p := range r.portfolios {
+		if p.userID == userID && p.portfolio.Name == name && p.portfolio.Id != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+type tickKey struct {
+	symbol    string
+	timestamp int64
+	sequence  uint64
+}
+
+type barKey struct {
+	symbol    string
+	interval  string
+	timestamp int64
+}
+
+// MemoryPriceHistoryRepository is an in-memory PriceHistoryStore used in mock mode
+type MemoryPriceHistoryRepository struct {
+	mu    sync.RWMutex
+	ticks []PriceTick // in insertion order
+	seen  map[tickKey]bool
+	bars  map[barKey]*pb.HistoricalPrice
+}
+
+func NewMemoryPriceHistoryRepository() *MemoryPriceHistoryRepository {
+	return &MemoryPriceHistoryRepository{
+		seen: make(map[tickKey]bool),
+		bars: make(map[barKey]*pb.HistoricalPrice),
+	}
+}
+
+func (r *MemoryPriceHistoryRepository) SaveTicks(ctx context.Context, ticks []*PriceTick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tick := range ticks {
+		key := tickKey{tick.Symbol, tick.Timestamp, tick.Sequence}
+		if r.seen[key] {
+			continue
+		}
+		r.seen[key] = true
+		r.ticks = append(r.ticks, *tick)
+	}
+
+	return nil
+}
+
+func (r *MemoryPriceHistoryRepository) GetTicks(ctx context.Context, start, end int64) ([]*PriceTick, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ticks []*PriceTick
+	for i := range r.ticks {
+		if r.ticks[i].Timestamp >= start && r.ticks[i].Timestamp < end {
+			tick := r.ticks[i]
+			ticks = append(ticks, &tick)
+		}
+	}
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].Timestamp < ticks[j].Timestamp
+	})
+
+	return ticks, nil
+}
+
+func (r *MemoryPriceHistoryRepository) SaveBars(ctx context.Context, symbol, interval string, bars []*pb.HistoricalPrice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, bar := range bars {
+		r.bars[barKey{symbol, interval, bar.Timestamp}] = copyBar(bar)
+	}
+
+	return nil
+}
+
+func (r *MemoryPriceHistoryRepository) GetBars(ctx context.Context, symbol, interval string, start, end int64) ([]*pb.HistoricalPrice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var bars []*pb.HistoricalPrice
+	for key, bar := range r.bars {
+		if key.symbol == symbol && key.interval == interval && key.timestamp >= start && key.timestamp < end {
+			bars = append(bars, copyBar(bar))
+		}
+	}
+	sortBars(bars)
+
+	return bars, nil
+}
+
+func (r *MemoryPriceHistoryRepository) LatestBar(ctx context.Context, symbol, interval string) (*pb.HistoricalPrice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *pb.HistoricalPrice
+	for key, bar := range r.bars {
+		if key.symbol == symbol && key.interval == interval && (latest == nil || bar.Timestamp > latest.Timestamp) {
+			latest = bar
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	return copyBar(latest), nil
+}
+
+func (r *MemoryPriceHistoryRepository) GetAllBars(ctx context.Context, interval string, start, end int64) (map[string][]*pb.HistoricalPrice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bars := make(map[string][]*pb.HistoricalPrice)
+	for key, bar := range r.bars {
+		if key.interval == interval && key.timestamp >= start && key.timestamp < end {
+			bars[key.symbol] = append(bars[key.symbol], copyBar(bar))
+		}
+	}
+	for _, symbolBars := range bars {
+		sortBars(symbolBars)
+	}
+
+	return bars, nil
+}
+
+func (r *MemoryPriceHistoryRepository) DeleteTicksBefore(ctx context.Context, before int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.ticks[:0]
+	for _, tick := range r.ticks {
+		if tick.Timestamp >= before {
+			kept = append(kept, tick)
+		} else {
+			delete(r.seen, tickKey{tick.Symbol, tick.Timestamp, tick.Sequence})
+		}
+	}
+	deleted := int64(len(r.ticks) - len(kept))
+	r.ticks = kept
+
+	return deleted, nil
+}
+
+func (r *MemoryPriceHistoryRepository) DeleteBarsBefore(ctx context.Context, interval string, before int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key := range r.bars {
+		if key.interval == interval && key.timestamp < before {
+			delete(r.bars, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// copyBar returns a copy so callers never share state with the store
+func copyBar(bar *pb.HistoricalPrice) *pb.HistoricalPrice {
+	return &pb.HistoricalPrice{
+		Timestamp: bar.Timestamp,
+		Open:      bar.Open,
+		High:      bar.High,
+		Low:       bar.Low,
+		Close:     bar.Close,
+		Volume:    bar.Volume,
+	}
+}
+
+func sortBars(bars []*pb.HistoricalPrice) {
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].Timestamp < bars[j].Timestamp
+	})
+}
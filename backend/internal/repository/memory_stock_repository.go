@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+)
+
+type memoryTransaction struct {
+	userID string
+	txn    *pb.Transaction
+}
+
+// MemoryStockRepository is an in-memory StockStore used in mock mode
+type MemoryStockRepository struct {
+	mu     sync.RWMutex
+	ledger []*memoryTransaction // in insertion (created_at) order
+}
+
+func NewMemoryStockRepository() *MemoryStockRepository {
+	return &MemoryStockRepository{}
+}
+
+func (r *MemoryStockRepository) AddStock(ctx context.Context, userID, portfolioID, symbol, name string, quantity, purchasePrice float64, purchaseDate int64) (*pb.Stock, error) {
+	_, position, err := r.RecordTransaction(ctx, userID, &pb.Transaction{
+		Symbol:      symbol,
+		Name:        name,
+		Type:        pb.TransactionType_BUY,
+		Quantity:    quantity,
+		Price:       purchasePrice,
+		Timestamp:   purchaseDate,
+		PortfolioId: portfolioID,
+	})
+	return position, err
+}
+
+func (r *MemoryStockRepository) GetPortfolio(ctx context.Context, userID, portfolioID string) ([]*pb.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return PositionsFromLedger(r.userLedger(userID, portfolioID, ""))
+}
+
+func (r *MemoryStockRepository) RemoveStock(ctx context.Context, userID, portfolioID, symbol string, price float64) error {
+	symbol = normalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sells, err := closingSells(r.userLedger(userID, portfolioID, symbol), symbol, price, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if len(sells) == 0 {
+		return ErrStockNotFound
+	}
+
+	for _, sell := range sells {
+		r.ledger = append(r.ledger, &memoryTransaction{userID: userID, txn: sell})
+	}
+	return nil
+}
+
+func (r *MemoryStockRepository) GetSymbolsByUserID(ctx context.Context, userID string) ([]string, error) {
+	stocks, err := r.GetPortfolio(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return symbolsOf(stocks), nil
+}
+
+func (r *MemoryStockRepository) RecordTransaction(ctx context.Context, userID string, txn *pb.Transaction) (*pb.Transaction, *pb.Stock, error) {
+	stored := copyTransaction(txn)
+	stored.Id = uuid.New().String()
+	if stored.Currency == "" {
+		stored.Currency = fx.ListingCurrency(stored.Symbol)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkCurrency(r.userLedger(userID, "", stored.Symbol), stored.Currency); err != nil {
+		return nil, nil, err
+	}
+	r.ledger = append(r.ledger, &memoryTransaction{userID: userID, txn: stored})
+	position, err := positionFor(r.userLedger(userID, stored.PortfolioId, stored.Symbol), stored.Symbol)
+	if err != nil {
+		r.ledger = r.ledger[:len(r.ledger)-1]
+		return nil, nil, err
+	}
+
+	return copyTransaction(stored), position, nil
+}
+
+func (r *MemoryStockRepository) GetTransactions(ctx context.Context, userID, portfolioID, symbol string) ([]*pb.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.userLedger(userID, portfolioID, symbol), nil
+}
+
+func (r *MemoryStockRepository) DeleteTransaction(ctx context.Context, userID, transactionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, t := range r.ledger {
+		if t.txn.Id != transactionID || t.userID != userID {
+			continue
+		}
+
+		remaining := append(append([]*memoryTransaction(nil), r.ledger[:i]...), r.ledger[i+1:]...)
+		previous := r.ledger
+		r.ledger = remaining
+		if _, err := PositionsFromLedger(r.userLedger(userID, t.txn.PortfolioId, t.txn.Symbol)); err != nil {
+			r.ledger = previous
+			return err
+		}
+		return nil
+	}
+
+	return ErrTransactionNotFound
+}
+
+// userLedger returns copies of a user's transactions in portfolioID and symbol, either
+// of which may be empty for all of them, oldest first. Trades with the same timestamp
+// keep insertion order.
+func (r *MemoryStockRepository) userLedger(userID, portfolioID, symbol string) []*pb.Transaction {
+	var ledger []*pb.Transaction
+	for _, t := range r.ledger {
+		if t.userID == userID && (portfolioID == "" || t.txn.PortfolioId == portfolioID) && (symbol == "" || t.txn.Symbol == symbol) {
+			ledger = append(ledger, copyTransaction(t.txn))
+		}
+	}
+	sort.SliceStable(ledger, func(i, j int) bool {
+		return ledger[i].Timestamp < ledger[j].Timestamp
+	})
+
+	return ledger
+}
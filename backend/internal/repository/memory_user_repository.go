@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+)
+
+// MemoryUserRepository is an in-memory UserStore used in mock mode. Any user ID is accepted.
+type MemoryUserRepository struct {
+	mu             sync.RWMutex
+	baseCurrencies map[string]string
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{baseCurrencies: make(map[string]string)}
+}
+
+func (r *MemoryUserRepository) GetBaseCurrency(ctx context.Context, userID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if currency, ok := r.baseCurrencies[userID]; ok {
+		return currency, nil
+	}
+	return fx.USD, nil
+}
+
+func (r *MemoryUserRepository) SetBaseCurrency(ctx context.Context, userID, currency string) error {
+	r.mu.Lock()
+	r.baseCurrencies[userID] = currency
+	r.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrPortfolioNotFound is returned when a portfolio does not exist or belongs to another user
+	ErrPortfolioNotFound = errors.New("portfolio not found or unauthorized")
+	// ErrPortfolioNameTaken is returned when the user already has a portfolio with the name
+	ErrPortfolioNameTaken = errors.New("portfolio name already in use")
+	// ErrDefaultPortfolio is returned when deleting the portfolio that takes unassigned entries
+	ErrDefaultPortfolio = errors.New("the default portfolio cannot be deleted")
+	// ErrPortfolioNotEmpty is returned when deleting a portfolio that still has transactions or cash entries
+	ErrPortfolioNotEmpty = errors.New("portfolio still has transactions or cash entries")
+)
+
+// defaultPortfolioName is what a user's default portfolio is called until they rename it
+const defaultPortfolioName = "Main"
+
+// defaultPortfolioID is the ID of userID's default portfolio, as migrations/008_portfolios.sql assigns it
+func defaultPortfolioID(userID string) string {
+	return "default-" + userID
+}
+
+type PortfolioRepository struct {
+	db *sql.DB
+}
+
+func NewPortfolioRepository(db *sql.DB) *PortfolioRepository {
+	return &PortfolioRepository{db: db}
+}
+
+const selectPortfolios = `
+	SELECT id, name, COALESCE(description, ''), is_default, EXTRACT(EPOCH FROM created_at)::BIGINT
+	FROM portfolios
+`
+
+func (r *PortfolioRepository) CreatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error) {
+	stored := copyPortfolio(portfolio)
+	stored.Id = uuid.New().String()
+	stored.IsDefault = false
+
+	query := `
+		INSERT INTO portfolios (id, user_id, name, description)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING EXTRACT(EPOCH FROM created_at)::BIGINT
+	`
+	err := r.db.QueryRowContext(ctx, query, stored.Id, userID, stored.Name, stored.Description).Scan(&stored.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrPortfolioNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create portfolio: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (r *PortfolioRepository) GetPortfolios(ctx context.Context, userID string) ([]*pb.Portfolio, error) {
+	rows, err := r.db.QueryContext(ctx, selectPortfolios+`WHERE user_id = $1 ORDER BY is_default DESC, created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	var portfolios []*pb.Portfolio
+	for rows.Next() {
+		portfolio, err := scanPortfolio(rows)
+		if err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios, rows.Err()
+}
+
+func (r *PortfolioRepository) GetPortfolio(ctx context.Context, userID, portfolioID string) (*pb.Portfolio, error) {
+	row := r.db.QueryRowContext(ctx, selectPortfolios+`WHERE id = $1 AND user_id = $2`, portfolioID, userID)
+	portfolio, err := scanPortfolio(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return portfolio, err
+}
+
+func (r *PortfolioRepository) DefaultPortfolio(ctx context.Context, userID string) (*pb.Portfolio, error) {
+	query := `
+		INSERT INTO portfolios (id, user_id, name, is_default)
+		VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, defaultPortfolioID(userID), userID, defaultPortfolioName); err != nil {
+		return nil, fmt.Errorf("failed to create default portfolio: %w", err)
+	}
+
+	row := r.db.QueryRowContext(ctx, selectPortfolios+`WHERE user_id = $1 AND is_default`, userID)
+	portfolio, err := scanPortfolio(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The insert lost to a portfolio the user had already named "Main"
+		return nil, fmt.Errorf("failed to create default portfolio: %w", ErrPortfolioNameTaken)
+	}
+
+	return portfolio, err
+}
+
+func (r *PortfolioRepository) UpdatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error) {
+	query := `
+		UPDATE portfolios SET name = $3, description = NULLIF($4, '')
+		WHERE id = $1 AND user_id = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, portfolio.Id, userID, portfolio.Name, portfolio.Description)
+	if isUniqueViolation(err) {
+		return nil, ErrPortfolioNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update portfolio: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return r.GetPortfolio(ctx, userID, portfolio.Id)
+}
+
+func (r *PortfolioRepository) DeletePortfolio(ctx context.Context, userID, portfolioID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Holding the row keeps trades and cash entries from being added until the delete commits
+	var isDefault bool
+	err = tx.QueryRowContext(ctx, `SELECT is_default FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE`, portfolioID, userID).
+		Scan(&isDefault)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPortfolioNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get portfolio: %w", err)
+	}
+	if isDefault {
+		return ErrDefaultPortfolio
+	}
+
+	var used bool
+	query := `
+		SELECT EXISTS(SELECT 1 FROM transactions WHERE portfolio_id = $1)
+		    OR EXISTS(SELECT 1 FROM cash_entries WHERE portfolio_id = $1)
+	`
+	if err := tx.QueryRowContext(ctx, query, portfolioID).Scan(&used); err != nil {
+		return fmt.Errorf("failed to check portfolio: %w", err)
+	}
+	if used {
+		return ErrPortfolioNotEmpty
+	}
+
+	// Its alerts go with it through ON DELETE CASCADE
+	if _, err := tx.ExecContext(ctx, `DELETE FROM portfolios WHERE id = $1`, portfolioID); err != nil {
+		return fmt.Errorf("failed to delete portfolio: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func scanPortfolio(row rowScanner) (*pb.Portfolio, error) {
+	var portfolio pb.Portfolio
+	err := row.Scan(
+		&portfolio.Id,
+		&portfolio.Name,
+		&portfolio.Description,
+		&portfolio.IsDefault,
+		&portfolio.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan portfolio: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func copyPortfolio(portfolio *pb.Portfolio) *pb.Portfolio {
+	return &pb.Portfolio{
+		Id:          portfolio.Id,
+		Name:        portfolio.Name,
+		Description: portfolio.Description,
+		IsDefault:   portfolio.IsDefault,
+		CreatedAt:   portfolio.CreatedAt,
+	}
+}
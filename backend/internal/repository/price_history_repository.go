@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// PriceTick is one recorded price update. Volume is the number of shares traded
+// since the symbol's previous tick, not the cumulative day volume.
+type PriceTick struct {
+	Symbol    string
+	Sequence  uint64
+	Price     float64
+	Volume    float64
+	Timestamp int64
+}
+
+type PriceHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPriceHistoryRepository(db *sql.DB) *PriceHistoryRepository {
+	return &PriceHistoryRepository{db: db}
+}
+
+func (r *PriceHistoryRepository) SaveTicks(ctx context.Context, ticks []*PriceTick) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO price_ticks (symbol, sequence, price, volume, timestamp)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (symbol, timestamp, sequence) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare tick insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, tick := range ticks {
+		if _, err := stmt.ExecContext(ctx, tick.Symbol, int64(tick.Sequence), tick.Price, tick.Volume, tick.Timestamp); err != nil {
+			return fmt.Errorf("failed to save tick: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save ticks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PriceHistoryRepository) GetTicks(ctx context.Context, start, end int64) ([]*PriceTick, error) {
+	query := `
+		SELECT symbol, sequence, price, volume, timestamp
+		FROM price_ticks
+		WHERE timestamp >= $1 AND timestamp < $2
+		ORDER BY timestamp, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticks: %w", err)
+	}
+	defer rows.Close()
+
+	var ticks []*PriceTick
+	for rows.Next() {
+		var tick PriceTick
+		var sequence int64
+		if err := rows.Scan(&tick.Symbol, &sequence, &tick.Price, &tick.Volume, &tick.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan tick: %w", err)
+		}
+		tick.Sequence = uint64(sequence)
+		ticks = append(ticks, &tick)
+	}
+
+	return ticks, rows.Err()
+}
+
+func (r *PriceHistoryRepository) SaveBars(ctx context.Context, symbol, interval string, bars []*pb.HistoricalPrice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO price_bars (symbol, timeframe, timestamp, open, high, low, close, volume)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (symbol, timeframe, timestamp) DO UPDATE
+		SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+		    close = EXCLUDED.close, volume = EXCLUDED.volume
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare bar upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, bar := range bars {
+		_, err := stmt.ExecContext(ctx, symbol, interval, bar.Timestamp, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume)
+		if err != nil {
+			return fmt.Errorf("failed to save bar: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save bars: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PriceHistoryRepository) GetBars(ctx context.Context, symbol, interval string, start, end int64) ([]*pb.HistoricalPrice, error) {
+	query := `
+		SELECT timestamp, open, high, low, close, volume
+		FROM price_bars
+		WHERE symbol = $1 AND timeframe = $2 AND timestamp >= $3 AND timestamp < $4
+		ORDER BY timestamp
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, symbol, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars: %w", err)
+	}
+	defer rows.Close()
+
+	var bars []*pb.HistoricalPrice
+	for rows.Next() {
+		var bar pb.HistoricalPrice
+		if err := rows.Scan(&bar.Timestamp, &bar.Open, &bar.High, &bar.Low, &bar.Close, &bar.Volume); err != nil {
+			return nil, fmt.Errorf("failed to scan bar: %w", err)
+		}
+		bars = append(bars, &bar)
+	}
+
+	return bars, rows.Err()
+}
+
+func (r *PriceHistoryRepository) LatestBar(ctx context.Context, symbol, interval string) (*pb.HistoricalPrice, error) {
+	query := `
+		SELECT timestamp, open, high, low, close, volume
+		FROM price_bars
+		WHERE symbol = $1 AND timeframe = $2
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`
+
+	var bar pb.HistoricalPrice
+	err := r.db.QueryRowContext(ctx, query, symbol, interval).
+		Scan(&bar.Timestamp, &bar.Open, &bar.High, &bar.Low, &bar.Close, &bar.Volume)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest bar: %w", err)
+	}
+
+	return &bar, nil
+}
+
+func (r *PriceHistoryRepository) GetAllBars(ctx context.Context, interval string, start, end int64) (map[string][]*pb.HistoricalPrice, error) {
+	query := `
+		SELECT symbol, timestamp, open, high, low, close, volume
+		FROM price_bars
+		WHERE timeframe = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY symbol, timestamp
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, interval, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars: %w", err)
+	}
+	defer rows.Close()
+
+	bars := make(map[string][]*pb.HistoricalPrice)
+	for rows.Next() {
+		var symbol string
+		var bar pb.HistoricalPrice
+		if err := rows.Scan(&symbol, &bar.Timestamp, &bar.Open, &bar.High, &bar.Low, &bar.Close, &bar.Volume); err != nil {
+			return nil, fmt.Errorf("failed to scan bar: %w", err)
+		}
+		bars[symbol] = append(bars[symbol], &bar)
+	}
+
+	return bars, rows.Err()
+}
+
+func (r *PriceHistoryRepository) DeleteTicksBefore(ctx context.Context, before int64) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM price_ticks WHERE timestamp < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete ticks: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *PriceHistoryRepository) DeleteBarsBefore(ctx context.Context, interval string, before int64) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM price_bars WHERE timeframe = $1 AND timestamp < $2`, interval, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete bars: %w", err)
+	}
+
+	return result.RowsAffected()
+}
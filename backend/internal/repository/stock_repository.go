@@ -3,12 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
 	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
 	"github.com/google/uuid"
 )
 
+// ErrStockNotFound is returned when a stock does not exist or belongs to another user
+var ErrStockNotFound = errors.New("stock not found or unauthorized")
+
+// StockRepository keeps each user's transaction ledger and derives positions from it
 type StockRepository struct {
 	db *sql.DB
 }
@@ -17,97 +25,249 @@ func NewStockRepository(db *sql.DB) *StockRepository {
 	return &StockRepository{db: db}
 }
 
-func (r *StockRepository) AddStock(ctx context.Context, userID, symbol, name string, quantity, purchasePrice float64, purchaseDate int64) (*pb.Stock, error) {
-	stockID := uuid.New().String()
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
 
-	query := `
-		INSERT INTO stocks (id, user_id, symbol, name, quantity, purchase_price, purchase_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, symbol, name, quantity, purchase_price, purchase_date
-	`
+func (r *StockRepository) AddStock(ctx context.Context, userID, portfolioID, symbol, name string, quantity, purchasePrice float64, purchaseDate int64) (*pb.Stock, error) {
+	_, position, err := r.RecordTransaction(ctx, userID, &pb.Transaction{
+		Symbol:      symbol,
+		Name:        name,
+		Type:        pb.TransactionType_BUY,
+		Quantity:    quantity,
+		Price:       purchasePrice,
+		Timestamp:   purchaseDate,
+		PortfolioId: portfolioID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add stock: %w", err)
+	}
 
-	var stock pb.Stock
-	err := r.db.QueryRowContext(ctx, query, stockID, userID, symbol, name, quantity, purchasePrice, purchaseDate).
-		Scan(&stock.Id, &stock.Symbol, &stock.Name, &stock.Quantity, &stock.PurchasePrice, &stock.PurchaseDate)
+	return position, nil
+}
 
+func (r *StockRepository) GetPortfolio(ctx context.Context, userID, portfolioID string) ([]*pb.Stock, error) {
+	ledger, err := loadLedger(ctx, r.db, userID, portfolioID, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to add stock: %w", err)
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
 
-	return &stock, nil
+	return PositionsFromLedger(ledger)
 }
 
-func (r *StockRepository) GetPortfolio(ctx context.Context, userID string) ([]*pb.Stock, error) {
-	query := `
-		SELECT id, symbol, name, quantity, purchase_price, purchase_date
-		FROM stocks
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
+func (r *StockRepository) RemoveStock(ctx context.Context, userID, portfolioID, symbol string, price float64) error {
+	symbol = normalizeSymbol(symbol)
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var stocks []*pb.Stock
-	for rows.Next() {
-		var stock pb.Stock
-		err := rows.Scan(
-			&stock.Id,
-			&stock.Symbol,
-			&stock.Name,
-			&stock.Quantity,
-			&stock.PurchasePrice,
-			&stock.PurchaseDate,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan stock: %w", err)
+	if err := lockLedger(ctx, tx, userID, symbol); err != nil {
+		return err
+	}
+
+	ledger, err := loadLedger(ctx, tx, userID, portfolioID, symbol)
+	if err != nil {
+		return err
+	}
+	sells, err := closingSells(ledger, symbol, price, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if len(sells) == 0 {
+		return ErrStockNotFound
+	}
+
+	for _, sell := range sells {
+		if err := insertTransaction(ctx, tx, userID, sell); err != nil {
+			return err
 		}
-		stocks = append(stocks, &stock)
 	}
 
-	return stocks, nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *StockRepository) GetSymbolsByUserID(ctx context.Context, userID string) ([]string, error) {
+	stocks, err := r.GetPortfolio(ctx, userID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	}
+
+	return symbolsOf(stocks), nil
+}
+
+func (r *StockRepository) RecordTransaction(ctx context.Context, userID string, txn *pb.Transaction) (*pb.Transaction, *pb.Stock, error) {
+	stored := copyTransaction(txn)
+	stored.Id = uuid.New().String()
+	if stored.Currency == "" {
+		stored.Currency = fx.ListingCurrency(stored.Symbol)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLedger(ctx, tx, userID, stored.Symbol); err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := loadLedger(ctx, tx, userID, "", stored.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkCurrency(existing, stored.Currency); err != nil {
+		return nil, nil, err
+	}
+
+	if err := insertTransaction(ctx, tx, userID, stored); err != nil {
+		return nil, nil, err
+	}
+
+	// A sell may not take the position below zero or draw on a lot already sold,
+	// now or at any later trade
+	ledger, err := loadLedger(ctx, tx, userID, stored.PortfolioId, stored.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	position, err := positionFor(ledger, stored.Symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stored, position, nil
 }
 
-func (r *StockRepository) RemoveStock(ctx context.Context, userID, stockID string) error {
-	query := `DELETE FROM stocks WHERE id = $1 AND user_id = $2`
+func (r *StockRepository) GetTransactions(ctx context.Context, userID, portfolioID, symbol string) ([]*pb.Transaction, error) {
+	return loadLedger(ctx, r.db, userID, portfolioID, symbol)
+}
 
-	result, err := r.db.ExecContext(ctx, query, stockID, userID)
+func (r *StockRepository) DeleteTransaction(ctx context.Context, userID, transactionID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to remove stock: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var symbol, portfolioID string
+	err = tx.QueryRowContext(ctx, `SELECT symbol, portfolio_id FROM transactions WHERE id = $1 AND user_id = $2`, transactionID, userID).
+		Scan(&symbol, &portfolioID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTransactionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if err := lockLedger(ctx, tx, userID, symbol); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transactions WHERE id = $1`, transactionID); err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
+	// Removing a buy must not leave a later sell without shares
+	ledger, err := loadLedger(ctx, tx, userID, portfolioID, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+		return err
+	}
+	if _, err := PositionsFromLedger(ledger); err != nil {
+		return err
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("stock not found or unauthorized")
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (r *StockRepository) GetSymbolsByUserID(ctx context.Context, userID string) ([]string, error) {
-	query := `SELECT DISTINCT symbol FROM stocks WHERE user_id = $1`
+// insertTransaction appends txn, which already has its ID and portfolio, to the user's ledger
+func insertTransaction(ctx context.Context, tx *sql.Tx, userID string, txn *pb.Transaction) error {
+	query := `
+		INSERT INTO transactions (id, user_id, symbol, name, type, quantity, price, fees, timestamp, lot_method, lot_id, currency, portfolio_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13)
+	`
+	_, err := tx.ExecContext(ctx, query, txn.Id, userID, txn.Symbol, txn.Name, txn.Type.String(),
+		txn.Quantity, txn.Price, txn.Fees, txn.Timestamp, txn.LotMethod.String(), txn.LotId, txn.Currency, txn.PortfolioId)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return nil
+}
+
+// lockLedger serializes writes to one user's ledger in a symbol until tx ends, so
+// two concurrent sells can't both pass the share check
+func lockLedger(ctx context.Context, tx *sql.Tx, userID, symbol string) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, userID+"/"+symbol); err != nil {
+		return fmt.Errorf("failed to lock ledger: %w", err)
+	}
+	return nil
+}
+
+// loadLedger returns a user's transactions in portfolioID and symbol, either of which may
+// be empty for all of them, oldest first
+func loadLedger(ctx context.Context, q queryer, userID, portfolioID, symbol string) ([]*pb.Transaction, error) {
+	query := `
+		SELECT id, symbol, COALESCE(name, ''), type, quantity, price, fees, timestamp, lot_method, COALESCE(lot_id, ''), currency, portfolio_id
+		FROM transactions
+		WHERE user_id = $1 AND ($2::text = '' OR portfolio_id = $2::text) AND ($3::text = '' OR symbol = $3::text)
+		ORDER BY timestamp, created_at, id
+	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := q.QueryContext(ctx, query, userID, portfolioID, symbol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get symbols: %w", err)
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var symbols []string
+	var ledger []*pb.Transaction
 	for rows.Next() {
-		var symbol string
-		if err := rows.Scan(&symbol); err != nil {
-			return nil, fmt.Errorf("failed to scan symbol: %w", err)
+		var txn pb.Transaction
+		var txnType, lotMethod string
+		err := rows.Scan(
+			&txn.Id,
+			&txn.Symbol,
+			&txn.Name,
+			&txnType,
+			&txn.Quantity,
+			&txn.Price,
+			&txn.Fees,
+			&txn.Timestamp,
+			&lotMethod,
+			&txn.LotId,
+			&txn.Currency,
+			&txn.PortfolioId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
-		symbols = append(symbols, symbol)
+		txn.Type = pb.TransactionType(pb.TransactionType_value[txnType])
+		txn.LotMethod = pb.LotMethod(pb.LotMethod_value[lotMethod])
+		ledger = append(ledger, &txn)
 	}
 
-	return symbols, nil
+	return ledger, rows.Err()
+}
+
+// symbolsOf returns the symbols of positions, sorted
+func symbolsOf(stocks []*pb.Stock) []string {
+	symbols := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		symbols = append(symbols, stock.Symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
 }
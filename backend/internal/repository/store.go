@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// StockStore persists each user's buy and sell ledger and derives positions from it.
+// Every trade belongs to one of the user's portfolios, and a sell only closes lots in
+// its own. Where a portfolioID is optional, empty means every portfolio.
+// StockRepository (Postgres) and MemoryStockRepository implement it.
+type StockStore interface {
+	// AddStock records a BUY in the portfolio and returns the symbol's position there after it
+	AddStock(ctx context.Context, userID, portfolioID, symbol, name string, quantity, purchasePrice float64, purchaseDate int64) (*pb.Stock, error)
+	// GetPortfolio returns the user's open positions in portfolioID, one per symbol and
+	// keyed by it, most recently opened first. Across every portfolio, a symbol held in
+	// several is one position.
+	GetPortfolio(ctx context.Context, userID, portfolioID string) ([]*pb.Stock, error)
+	// RemoveStock closes the user's position in symbol in portfolioID by selling its open
+	// shares at price, or at their cost if price is zero. Earlier trades are kept. It
+	// returns ErrStockNotFound if the user holds no shares.
+	RemoveStock(ctx context.Context, userID, portfolioID, symbol string, price float64) error
+	// GetSymbolsByUserID returns the symbols of the user's open positions in every portfolio
+	GetSymbolsByUserID(ctx context.Context, userID string) ([]string, error)
+
+	// RecordTransaction appends txn to its portfolio's ledger under a new ID and returns it
+	// with the symbol's position there afterwards, nil once sold out. It returns
+	// ErrInsufficientShares if a sell would take the position below zero, then or at any
+	// later trade, and ErrLotUnavailable if a specific-lot sell can't be matched to its lot.
+	RecordTransaction(ctx context.Context, userID string, txn *pb.Transaction) (*pb.Transaction, *pb.Stock, error)
+	// GetTransactions returns the user's transactions in portfolioID and symbol, or in every
+	// symbol if it is empty, oldest first
+	GetTransactions(ctx context.Context, userID, portfolioID, symbol string) ([]*pb.Transaction, error)
+	// DeleteTransaction returns ErrTransactionNotFound if the transaction is missing or owned by
+	// another user, and ErrInsufficientShares or ErrLotUnavailable if a later sell depends
+	// on the shares it bought
+	DeleteTransaction(ctx context.Context, userID, transactionID string) error
+}
+
+// AlertStore persists price alerts.
+// AlertRepository (Postgres) and MemoryAlertRepository implement it.
+type AlertStore interface {
+	CreateAlert(ctx context.Context, userID, portfolioID, symbol string, targetPrice float64, condition AlertCondition) (string, error)
+	// GetActiveAlerts returns the untriggered alerts for a symbol
+	GetActiveAlerts(ctx context.Context, symbol string) ([]*Alert, error)
+	// TriggerAlert marks an untriggered alert as fired. It returns ErrAlertAlreadyTriggered
+	// if the alert was already triggered, so only one caller ever wins, and ErrAlertNotFound
+	// if the alert does not exist.
+	TriggerAlert(ctx context.Context, alertID string, triggeredPrice float64, triggeredAt int64) error
+	// GetUserAlerts returns a user's alerts in portfolioID, or in every portfolio if it is empty, newest first
+	GetUserAlerts(ctx context.Context, userID, portfolioID string) ([]*Alert, error)
+}
+
+// UserStore persists per-user settings.
+// UserRepository (Postgres) and MemoryUserRepository implement it.
+type UserStore interface {
+	// GetBaseCurrency returns the currency the user's totals are reported in, USD unless changed
+	GetBaseCurrency(ctx context.Context, userID string) (string, error)
+	// SetBaseCurrency returns ErrUserNotFound if the user does not exist
+	SetBaseCurrency(ctx context.Context, userID, currency string) error
+}
+
+// PortfolioStore persists the named portfolios a user's trades, cash entries and alerts
+// are kept in. PortfolioRepository (Postgres) and MemoryPortfolioRepository implement it.
+type PortfolioStore interface {
+	// CreatePortfolio stores portfolio under a new ID. It returns ErrPortfolioNameTaken if
+	// the user already has a portfolio with the name.
+	CreatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error)
+	// GetPortfolios returns the user's portfolios, the default first and then oldest first
+	GetPortfolios(ctx context.Context, userID string) ([]*pb.Portfolio, error)
+	// GetPortfolio returns ErrPortfolioNotFound if the portfolio is missing or owned by another user
+	GetPortfolio(ctx context.Context, userID, portfolioID string) (*pb.Portfolio, error)
+	// DefaultPortfolio returns the portfolio that takes whatever is recorded without naming
+	// one, creating it if the user has none yet
+	DefaultPortfolio(ctx context.Context, userID string) (*pb.Portfolio, error)
+	// UpdatePortfolio changes the portfolio's name and description. It returns
+	// ErrPortfolioNotFound and ErrPortfolioNameTaken like GetPortfolio and CreatePortfolio.
+	UpdatePortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error)
+	// DeletePortfolio deletes the portfolio and its alerts. It returns ErrPortfolioNotFound,
+	// ErrDefaultPortfolio for the default portfolio and ErrPortfolioNotEmpty while it still
+	// has transactions or cash entries.
+	DeletePortfolio(ctx context.Context, userID, portfolioID string) error
+}
+
+// CashStore persists cash movements other than trades.
+// CashRepository (Postgres) and MemoryCashRepository implement it.
+type CashStore interface {
+	// RecordCashEntry stores entry under a new ID and returns it
+	RecordCashEntry(ctx context.Context, userID string, entry *pb.CashEntry) (*pb.CashEntry, error)
+	// GetCashEntries returns the user's cash entries in portfolioID, or in every portfolio
+	// if it is empty, oldest first
+	GetCashEntries(ctx context.Context, userID, portfolioID string) ([]*pb.CashEntry, error)
+	// DeleteCashEntry returns ErrCashEntryNotFound if the entry is missing or owned by another user
+	DeleteCashEntry(ctx context.Context, userID, entryID string) error
+}
+
+// CorporateActionStore persists splits and symbol changes and applies them to every user's
+// ledger, alerts and cash entries and to the recorded price history.
+// CorporateActionRepository (Postgres) and MemoryCorporateActionRepository implement it.
+type CorporateActionStore interface {
+	// CreateCorporateAction stores action under a new ID, unapplied
+	CreateCorporateAction(ctx context.Context, action *pb.CorporateAction) (*pb.CorporateAction, error)
+	// GetCorporateActions returns the actions on symbol, as the old or new symbol of a
+	// symbol change, or every action if it is empty, by ex-date
+	GetCorporateActions(ctx context.Context, symbol string) ([]*pb.CorporateAction, error)
+	// DueCorporateActions returns the unapplied actions with an ex-date at or before asOf, by ex-date
+	DueCorporateActions(ctx context.Context, asOf int64) ([]*pb.CorporateAction, error)
+	// ApplyCorporateAction adjusts everything recorded in the action's symbol in one step and
+	// returns the action with what it changed. A split restates trades and price history
+	// before the ex-date in post-split shares, rescales untriggered alerts and records a
+	// cash-in-lieu SELL of any fractional share left in each portfolio; it returns ErrInsufficientShares
+	// and changes nothing if a later sell depended on that fraction. It returns
+	// ErrCorporateActionApplied if the action was already applied.
+	ApplyCorporateAction(ctx context.Context, actionID string, appliedAt int64) (*pb.CorporateAction, error)
+}
+
+// PriceHistoryStore persists recorded ticks and the OHLCV bars built from them.
+// PriceHistoryRepository (Postgres) and MemoryPriceHistoryRepository implement it.
+type PriceHistoryStore interface {
+	// SaveTicks records ticks, ignoring any already recorded by another instance
+	SaveTicks(ctx context.Context, ticks []*PriceTick) error
+	// GetTicks returns the ticks of every symbol with start <= timestamp < end, oldest first
+	GetTicks(ctx context.Context, start, end int64) ([]*PriceTick, error)
+	// SaveBars stores bars for a symbol and interval, replacing existing bars with the same timestamp
+	SaveBars(ctx context.Context, symbol, interval string, bars []*pb.HistoricalPrice) error
+	// GetBars returns a symbol's bars with start <= timestamp < end, oldest first
+	GetBars(ctx context.Context, symbol, interval string, start, end int64) ([]*pb.HistoricalPrice, error)
+	// LatestBar returns a symbol's most recent bar, or nil if it has none
+	LatestBar(ctx context.Context, symbol, interval string) (*pb.HistoricalPrice, error)
+	// GetAllBars returns every symbol's bars with start <= timestamp < end, oldest first
+	GetAllBars(ctx context.Context, interval string, start, end int64) (map[string][]*pb.HistoricalPrice, error)
+	// DeleteTicksBefore and DeleteBarsBefore enforce retention and return how many rows were removed
+	DeleteTicksBefore(ctx context.Context, before int64) (int64, error)
+	DeleteBarsBefore(ctx context.Context, interval string, before int64) (int64, error)
+}
+
+var (
+	_ StockStore = (*StockRepository)(nil)
+	_ StockStore = (*MemoryStockRepository)(nil)
+	_ AlertStore = (*AlertRepository)(nil)
+	_ AlertStore = (*MemoryAlertRepository)(nil)
+	_ CashStore  = (*CashRepository)(nil)
+	_ CashStore  = (*MemoryCashRepository)(nil)
+	_ UserStore  = (*UserRepository)(nil)
+	_ UserStore  = (*MemoryUserRepository)(nil)
+
+	_ PortfolioStore = (*PortfolioRepository)(nil)
+	_ PortfolioStore = (*MemoryPortfolioRepository)(nil)
+
+	_ CorporateActionStore = (*CorporateActionRepository)(nil)
+	_ CorporateActionStore = (*MemoryCorporateActionRepository)(nil)
+
+	_ PriceHistoryStore = (*PriceHistoryRepository)(nil)
+	_ PriceHistoryStore = (*MemoryPriceHistoryRepository)(nil)
+)
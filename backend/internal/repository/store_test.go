@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// stores is one backend's implementation of every store the contract tests cover
+type stores struct {
+	stocks     StockStore
+	alerts     AlertStore
+	cash       CashStore
+	portfolios PortfolioStore
+}
+
+// backends builds a fresh, empty set of stores for each backend the contract is checked
+// against. The Postgres stores keep the same contract and can be added here given a database.
+var backends = map[string]func(t *testing.T) stores{
+	"memory": func(t *testing.T) stores {
+		stocks := NewMemoryStockRepository()
+		alerts := NewMemoryAlertRepository()
+		cash := NewMemoryCashRepository()
+		return stores{
+			stocks:     stocks,
+			alerts:     alerts,
+			cash:       cash,
+			portfolios: NewMemoryPortfolioRepository(stocks, alerts, cash),
+		}
+	},
+}
+
+// forEachBackend runs test against every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, s stores)) {
+	for name, newStores := range backends {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStores(t))
+		})
+	}
+}
+
+func trade(symbol string, txnType pb.TransactionType, quantity, price float64, timestamp int64) *pb.Transaction {
+	return &pb.Transaction{Symbol: symbol, Type: txnType, Quantity: quantity, Price: price, Timestamp: timestamp, PortfolioId: "p1"}
+}
+
+func symbols(stocks []*pb.Stock) []string {
+	var out []string
+	for _, stock := range stocks {
+		out = append(out, stock.Symbol)
+	}
+	return out
+}
+
+func TestStockStoreContract(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("positions", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, s stores) {
+			for _, txn := range []*pb.Transaction{
+				trade("MSFT", pb.TransactionType_BUY, 5, 300, 100),
+				trade("AAPL", pb.TransactionType_BUY, 10, 150, 300),
+				trade("AAPL", pb.TransactionType_BUY, 10, 170, 400),
+				trade("GOOGL", pb.TransactionType_BUY, 2, 140, 200),
+				trade("GOOGL", pb.TransactionType_SELL, 2, 150, 500),
+			} {
+				if _, _, err := s.stocks.RecordTransaction(ctx, "alice", txn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stocks, err := s.stocks.GetPortfolio(ctx, "alice", "p1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Most recently opened first; GOOGL is sold out
+			if got := symbols(stocks); !slices.Equal(got, []string{"AAPL", "MSFT"}) {
+				t.Errorf("positions %v, want [AAPL MSFT]", got)
+			}
+			if stocks[0].Quantity != 20 || stocks[0].PurchasePrice != 160 || stocks[0].PurchaseDate != 300 {
+				t.Errorf("AAPL position %+v, want 20 shares at 160 from 300", stocks[0])
+			}
+
+			if stocks, _ := s.stocks.GetPortfolio(ctx, "bob", ""); len(stocks) != 0 {
+				t.Errorf("bob sees %v", symbols(stocks))
+			}
+		})
+	})
+
+	t.Run("ledger order", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, s stores) {
+			var ids []string
+			for _, ts := range []int64{300, 100, 300, 200} {
+				stored, _, err := s.stocks.RecordTransaction(ctx, "alice", trade("AAPL", pb.TransactionType_BUY, 1, 100, ts))
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, stored.Id)
+			}
+
+			ledger, err := s.stocks.GetTransactions(ctx, "alice", "", "AAPL")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Oldest first, and trades at the same time in the order they were recorded
+			var got []string
+			for _, txn := range ledger {
+				got = append(got, txn.Id)
+			}
+			if want := []string{ids[1], ids[3], ids[0], ids[2]}; !slices.Equal(got, want) {
+				t.Errorf("ledger %v, want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("ledger conflicts", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, s stores) {
+			buy, _, err := s.stocks.RecordTransaction(ctx, "alice", trade("AAPL", pb.TransactionType_BUY, 10, 100, 100))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := s.stocks.RecordTransaction(ctx, "alice", trade("AAPL", pb.TransactionType_SELL, 11, 100, 200)); !errors.Is(err, ErrInsufficientShares) {
+				t.Errorf("overselling: got %v, want ErrInsufficientShares", err)
+			}
+			if _, _, err := s.stocks.RecordTransaction(ctx, "bob", trade("AAPL", pb.TransactionType_SELL, 1, 100, 200)); !errors.Is(err, ErrInsufficientShares) {
+				t.Errorf("selling another user's shares: got %v, want ErrInsufficientShares", err)
+			}
+
+			other := trade("AAPL", pb.TransactionType_BUY, 1, 100, 200)
+			other.PortfolioId, other.Currency = "p2", "EUR"
+			if _, _, err := s.stocks.RecordTransaction(ctx, "alice", other); !errors.Is(err, ErrCurrencyMismatch) {
+				t.Errorf("second currency in another portfolio: got %v, want ErrCurrencyMismatch", err)
+			}
+
+			if _, _, err := s.stocks.RecordTransaction(ctx, "alice", trade("AAPL", pb.TransactionType_SELL, 4, 120, 300)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.stocks.DeleteTransaction(ctx, "alice", buy.Id); !errors.Is(err, ErrInsufficientShares) {
+				t.Errorf("deleting a buy a sell depends on: got %v, want ErrInsufficientShares", err)
+			}
+			if err := s.stocks.DeleteTransaction(ctx, "bob", buy.Id); !errors.Is(err, ErrTransactionNotFound) {
+				t.Errorf("deleting another user's trade: got %v, want ErrTransactionNotFound", err)
+			}
+			if err := s.stocks.DeleteTransaction(ctx, "alice", "missing"); !errors.Is(err, ErrTransactionNotFound) {
+				t.Errorf("deleting a missing trade: got %v, want ErrTransactionNotFound", err)
+			}
+
+			if ledger, _ := s.stocks.GetTransactions(ctx, "alice", "", ""); len(ledger) != 2 {
+				t.Errorf("ledger has %d trades after the refused changes, want 2", len(ledger))
+			}
+		})
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		forEachBackend(t, func(t *testing.T, s stores) {
+			for _, txn := range []*pb.Transaction{
+				trade("AAPL", pb.TransactionType_BUY, 10, 100, 100),
+				trade("AAPL", pb.TransactionType_SELL, 4, 130, 200),
+			} {
+				if _, _, err := s.stocks.RecordTransaction(ctx, "alice", txn); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := s.stocks.RemoveStock(ctx, "bob", "p1", "AAPL", 150); !errors.Is(err, ErrStockNotFound) {
+				t.Errorf("removing another user's position: got %v, want ErrStockNotFound", err)
+			}
+			if err := s.stocks.RemoveStock(ctx, "alice", "p2", "AAPL", 150); !errors.Is(err, ErrStockNotFound) {
+				t.Errorf("removing from a portfolio without it: got %v, want ErrStockNotFound", err)
+			}
+			if err := s.stocks.RemoveStock(ctx, "alice", "p1", " aapl", 150); err != nil {
+				t.Fatalf("removing by a lower-case symbol: %v", err)
+			}
+
+			if stocks, _ := s.stocks.GetPortfolio(ctx, "alice", ""); len(stocks) != 0 {
+				t.Errorf("still holding %v", symbols(stocks))
+			}
+			// The position is sold, not erased
+			ledger, err := s.stocks.GetTransactions(ctx, "alice", "p1", "AAPL")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ledger) != 3 {
+				t.Fatalf("ledger has %d trades, want the 2 recorded and a closing sell", len(ledger))
+			}
+			if last := ledger[2]; last.Type != pb.TransactionType_SELL || last.Quantity != 6 || last.Price != 150 {
+				t.Errorf("closing trade %+v, want a sell of 6 at 150", last)
+			}
+
+			if err := s.stocks.RemoveStock(ctx, "alice", "p1", "AAPL", 150); !errors.Is(err, ErrStockNotFound) {
+				t.Errorf("removing twice: got %v, want ErrStockNotFound", err)
+			}
+		})
+	})
+}
+
+func TestAlertStoreContract(t *testing.T) {
+	ctx := context.Background()
+
+	forEachBackend(t, func(t *testing.T, s stores) {
+		first, err := s.alerts.CreateAlert(ctx, "alice", "p1", "AAPL", 200, AlertCondition_ABOVE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := s.alerts.CreateAlert(ctx, "alice", "p2", "AAPL", 100, AlertCondition_BELOW)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.alerts.CreateAlert(ctx, "bob", "p3", "MSFT", 400, AlertCondition_ABOVE); err != nil {
+			t.Fatal(err)
+		}
+
+		alerts, err := s.alerts.GetUserAlerts(ctx, "alice", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 2 || alerts[0].ID != second || alerts[1].ID != first {
+			t.Errorf("alice's alerts %+v, want the newest first", alerts)
+		}
+		if alerts, _ := s.alerts.GetUserAlerts(ctx, "alice", "p1"); len(alerts) != 1 || alerts[0].ID != first {
+			t.Errorf("alice's alerts in p1 %+v, want only the first", alerts)
+		}
+
+		if err := s.alerts.TriggerAlert(ctx, first, 201, 1000); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.alerts.TriggerAlert(ctx, first, 202, 1001); !errors.Is(err, ErrAlertAlreadyTriggered) {
+			t.Errorf("triggering twice: got %v, want ErrAlertAlreadyTriggered", err)
+		}
+		if err := s.alerts.TriggerAlert(ctx, "missing", 1, 1); !errors.Is(err, ErrAlertNotFound) {
+			t.Errorf("triggering a missing alert: got %v, want ErrAlertNotFound", err)
+		}
+
+		active, err := s.alerts.GetActiveAlerts(ctx, "AAPL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 1 || active[0].ID != second {
+			t.Errorf("active AAPL alerts %+v, want only the untriggered one", active)
+		}
+
+		alerts, _ = s.alerts.GetUserAlerts(ctx, "alice", "p1")
+		if a := alerts[0]; !a.IsTriggered || a.TriggeredPrice == nil || *a.TriggeredPrice != 201 || a.TriggeredAt == nil || *a.TriggeredAt != 1000 {
+			t.Errorf("triggered alert %+v, want it fired at 201 at 1000", a)
+		}
+	})
+}
+
+func TestCashStoreContract(t *testing.T) {
+	ctx := context.Background()
+
+	forEachBackend(t, func(t *testing.T, s stores) {
+		var ids []string
+		for _, ts := range []int64{300, 100, 200} {
+			entry, err := s.cash.RecordCashEntry(ctx, "alice", &pb.CashEntry{Type: pb.CashEntryType_DEPOSIT, Amount: 100, Timestamp: ts, Currency: "USD", PortfolioId: "p1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, entry.Id)
+		}
+
+		entries, err := s.cash.GetCashEntries(ctx, "alice", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Id)
+		}
+		if want := []string{ids[1], ids[2], ids[0]}; !slices.Equal(got, want) {
+			t.Errorf("entries %v, want oldest first %v", got, want)
+		}
+		if entries, _ := s.cash.GetCashEntries(ctx, "alice", "p2"); len(entries) != 0 {
+			t.Errorf("got %d entries in p2, want none", len(entries))
+		}
+
+		if err := s.cash.DeleteCashEntry(ctx, "bob", ids[0]); !errors.Is(err, ErrCashEntryNotFound) {
+			t.Errorf("deleting another user's entry: got %v, want ErrCashEntryNotFound", err)
+		}
+		if err := s.cash.DeleteCashEntry(ctx, "alice", ids[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.cash.DeleteCashEntry(ctx, "alice", ids[0]); !errors.Is(err, ErrCashEntryNotFound) {
+			t.Errorf("deleting twice: got %v, want ErrCashEntryNotFound", err)
+		}
+	})
+}
+
+func TestPortfolioStoreContract(t *testing.T) {
+	ctx := context.Background()
+
+	forEachBackend(t, func(t *testing.T, s stores) {
+		trading, err := s.portfolios.CreatePortfolio(ctx, "alice", &pb.Portfolio{Name: "Trading"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kids, err := s.portfolios.CreatePortfolio(ctx, "alice", &pb.Portfolio{Name: "Kids"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		def, err := s.portfolios.DefaultPortfolio(ctx, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := s.portfolios.DefaultPortfolio(ctx, "alice"); again.Id != def.Id {
+			t.Errorf("second default portfolio %s, want %s again", again.Id, def.Id)
+		}
+
+		portfolios, err := s.portfolios.GetPortfolios(ctx, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range portfolios {
+			got = append(got, p.Id)
+		}
+		if want := []string{def.Id, trading.Id, kids.Id}; !slices.Equal(got, want) {
+			t.Errorf("portfolios %v, want the default and then oldest first %v", got, want)
+		}
+
+		if _, err := s.portfolios.CreatePortfolio(ctx, "alice", &pb.Portfolio{Name: "Trading"}); !errors.Is(err, ErrPortfolioNameTaken) {
+			t.Errorf("reusing a name: got %v, want ErrPortfolioNameTaken", err)
+		}
+		if _, err := s.portfolios.CreatePortfolio(ctx, "bob", &pb.Portfolio{Name: "Trading"}); err != nil {
+			t.Errorf("another user reusing a name: %v", err)
+		}
+		if _, err := s.portfolios.UpdatePortfolio(ctx, "alice", &pb.Portfolio{Id: kids.Id, Name: "Trading"}); !errors.Is(err, ErrPortfolioNameTaken) {
+			t.Errorf("renaming to a name in use: got %v, want ErrPortfolioNameTaken", err)
+		}
+
+		if _, err := s.portfolios.GetPortfolio(ctx, "bob", trading.Id); !errors.Is(err, ErrPortfolioNotFound) {
+			t.Errorf("reading another user's portfolio: got %v, want ErrPortfolioNotFound", err)
+		}
+		if _, err := s.portfolios.UpdatePortfolio(ctx, "bob", &pb.Portfolio{Id: trading.Id, Name: "Mine"}); !errors.Is(err, ErrPortfolioNotFound) {
+			t.Errorf("renaming another user's portfolio: got %v, want ErrPortfolioNotFound", err)
+		}
+		if err := s.portfolios.DeletePortfolio(ctx, "bob", trading.Id); !errors.Is(err, ErrPortfolioNotFound) {
+			t.Errorf("deleting another user's portfolio: got %v, want ErrPortfolioNotFound", err)
+		}
+
+		if err := s.portfolios.DeletePortfolio(ctx, "alice", def.Id); !errors.Is(err, ErrDefaultPortfolio) {
+			t.Errorf("deleting the default portfolio: got %v, want ErrDefaultPortfolio", err)
+		}
+		buy := trade("AAPL", pb.TransactionType_BUY, 1, 100, 100)
+		buy.PortfolioId = trading.Id
+		if _, _, err := s.stocks.RecordTransaction(ctx, "alice", buy); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.portfolios.DeletePortfolio(ctx, "alice", trading.Id); !errors.Is(err, ErrPortfolioNotEmpty) {
+			t.Errorf("deleting a portfolio with trades: got %v, want ErrPortfolioNotEmpty", err)
+		}
+
+		if _, err := s.alerts.CreateAlert(ctx, "alice", kids.Id, "AAPL", 200, AlertCondition_ABOVE); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.portfolios.DeletePortfolio(ctx, "alice", kids.Id); err != nil {
+			t.Fatal(err)
+		}
+		if alerts, _ := s.alerts.GetUserAlerts(ctx, "alice", kids.Id); len(alerts) != 0 {
+			t.Errorf("deleted portfolio left %d alerts", len(alerts))
+		}
+		if _, err := s.portfolios.GetPortfolio(ctx, "alice", kids.Id); !errors.Is(err, ErrPortfolioNotFound) {
+			t.Errorf("reading a deleted portfolio: got %v, want ErrPortfolioNotFound", err)
+		}
+	})
+}
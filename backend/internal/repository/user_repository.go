@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+)
+
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) GetBaseCurrency(ctx context.Context, userID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx, `SELECT base_currency FROM users WHERE id = $1`, userID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fx.USD, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get base currency: %w", err)
+	}
+
+	return currency, nil
+}
+
+func (r *UserRepository) SetBaseCurrency(ctx context.Context, userID, currency string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET base_currency = $1 WHERE id = $2`, currency, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set base currency: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
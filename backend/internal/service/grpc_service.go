@@ -0,0 +1,915 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/alert"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/history"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/indicators"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// gRPC Handlers for the PortfolioService defined in proto/portfolio.proto
+
+func (s *PortfolioService) AddStock(ctx context.Context, req *pb.AddStockRequest) (*pb.AddStockResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	symbol, err := validateStockInput(req.Symbol, req.Quantity, req.PurchasePrice)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	purchaseDate := req.PurchaseDate
+	if purchaseDate == 0 {
+		purchaseDate = time.Now().Unix()
+	}
+
+	portfolio, err := s.portfolioFor(ctx, req.UserId, req.PortfolioId)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	stock, err := s.stockRepo.AddStock(ctx, req.UserId, portfolio.Id, symbol, symbol, req.Quantity, req.PurchasePrice, purchaseDate)
+	if isLedgerConflict(err) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to add stock: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if s.priceManager != nil {
+		s.priceManager.AddSymbol(symbol, req.PurchasePrice)
+	}
+
+	return &pb.AddStockResponse{
+		Success: true,
+		Message: "Stock added successfully",
+		Stock:   stock,
+	}, nil
+}
+
+func (s *PortfolioService) GetPortfolio(ctx context.Context, req *pb.GetPortfolioRequest) (*pb.GetPortfolioResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	summary, _, err := s.portfolioView(ctx, req.UserId, req.PortfolioId)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return summary, nil
+}
+
+func (s *PortfolioService) SetPriceAlert(ctx context.Context, req *pb.SetPriceAlertRequest) (*pb.SetPriceAlertResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	symbol, err := validateAlertInput(req.Symbol, req.TargetPrice)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	condition := repository.AlertCondition_ABOVE
+	if req.Condition == pb.AlertCondition_BELOW {
+		condition = repository.AlertCondition_BELOW
+	}
+
+	portfolio, err := s.portfolioFor(ctx, req.UserId, req.PortfolioId)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	alertID, err := s.alertRepo.CreateAlert(ctx, req.UserId, portfolio.Id, symbol, req.TargetPrice, condition)
+	if err != nil {
+		log.Printf("Failed to create alert: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.reloadAlerts(ctx, symbol)
+
+	return &pb.SetPriceAlertResponse{
+		Success: true,
+		Message: "Alert set successfully",
+		AlertId: alertID,
+	}, nil
+}
+
+func (s *PortfolioService) GetAlerts(ctx context.Context, req *pb.GetAlertsRequest) (*pb.GetAlertsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if err := s.checkPortfolio(ctx, req.UserId, req.PortfolioId); err != nil {
+		return nil, portfolioStatus(err)
+	}
+
+	alerts, err := s.alertRepo.GetUserAlerts(ctx, req.UserId, req.PortfolioId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &pb.GetAlertsResponse{}
+	for _, alert := range alerts {
+		response.Alerts = append(response.Alerts, alertToProto(alert))
+	}
+
+	return response, nil
+}
+
+func (s *PortfolioService) GetChartData(ctx context.Context, req *pb.GetChartDataRequest) (*pb.GetChartDataResponse, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	if symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+
+	selection := strings.Join(req.Indicators, ",")
+	if selection == "" {
+		selection = defaultChartIndicators
+	}
+	specs, err := indicators.ParseSpecs(selection)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	bars, err := s.priceHistory(ctx, symbol, req.Timeframe, req.StartTime, req.EndTime, req.Simulated)
+	if err != nil {
+		return nil, historyStatus(err)
+	}
+	series := indicators.FromBars(bars)
+
+	response := &pb.GetChartDataResponse{
+		Symbol:     symbol,
+		Indicators: make(map[string]*pb.TechnicalIndicatorData),
+	}
+	for _, bar := range bars {
+		response.Candlesticks = append(response.Candlesticks, barToCandlestick(bar))
+	}
+
+	for key, points := range indicators.Compute(series, specs) {
+		response.Indicators[key] = pointsToProto(key, points)
+	}
+
+	return response, nil
+}
+
+func (s *PortfolioService) StreamPrices(req *pb.StreamPricesRequest, stream pb.PortfolioService_StreamPricesServer) error {
+	if s.priceManager == nil {
+		return status.Error(codes.Unavailable, "price manager not configured")
+	}
+	if len(req.Symbols) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one symbol is required")
+	}
+
+	symbols := make([]string, 0, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		symbols = append(symbols, strings.ToUpper(symbol))
+	}
+	resume := make(map[string]uint64, len(req.ResumeFrom))
+	for symbol, seq := range req.ResumeFrom {
+		resume[strings.ToUpper(symbol)] = seq
+	}
+
+	// Start with what the client missed, or the latest price so it doesn't wait for
+	// the next tick, then carry on with live updates
+	ctx := stream.Context()
+	sub := s.priceManager.Subscribe(streamOptions(true, resume), symbols...)
+	defer s.priceManager.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-sub.C():
+			if !ok {
+				return subscriptionError(sub)
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *PortfolioService) LivePortfolio(stream pb.PortfolioService_LivePortfolioServer) error {
+	if s.priceManager == nil {
+		return status.Error(codes.Unavailable, "price manager not configured")
+	}
+
+	ctx := stream.Context()
+	actions := make(chan *pb.PortfolioAction)
+	recvErr := make(chan error, 1)
+
+	go func() {
+		for {
+			action, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case actions <- action:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// One subscription carries every symbol the client asks for
+	prices := s.priceManager.Subscribe(streamOptions(false, nil))
+	defer s.priceManager.Unsubscribe(prices)
+
+	// The first action binds the stream to its user; later actions act for that user
+	// and may not name another. Alerts are delivered for the same user.
+	var userID string
+	var alertEvents chan *alert.Event
+	defer func() {
+		if alertEvents != nil {
+			s.alertEngine.Unsubscribe(userID, alertEvents)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+
+		case update, ok := <-prices.C():
+			if !ok {
+				return subscriptionError(prices)
+			}
+			err := stream.Send(&pb.PortfolioUpdate{
+				Type:        pb.PortfolioUpdate_PRICE_CHANGE,
+				PriceUpdate: update,
+				Timestamp:   time.Now().Unix(),
+			})
+			if err != nil {
+				return err
+			}
+
+		case event := <-alertEvents:
+			err := stream.Send(&pb.PortfolioUpdate{
+				Type:      pb.PortfolioUpdate_ALERT_TRIGGERED,
+				Alert:     event.Alert,
+				Timestamp: time.Now().Unix(),
+			})
+			if err != nil {
+				return err
+			}
+
+		case action := <-actions:
+			if userID == "" {
+				if action.UserId == "" {
+					return status.Error(codes.InvalidArgument, "the first action must carry a user_id")
+				}
+				userID = action.UserId
+				if s.alertEngine != nil {
+					alertEvents = s.alertEngine.Subscribe(userID)
+				}
+			}
+			if err := checkActionUser(userID, action); err != nil {
+				return err
+			}
+			symbol := strings.ToUpper(action.Symbol)
+
+			switch action.Action {
+			case pb.PortfolioAction_SUBSCRIBE:
+				// A symbol of "*" subscribes to every tracked symbol
+				if symbol != "" {
+					prices.Add(symbol)
+				}
+
+			case pb.PortfolioAction_UNSUBSCRIBE:
+				if symbol != "" {
+					prices.Remove(symbol)
+				}
+
+			case pb.PortfolioAction_ADD_STOCK:
+				if action.AddDetails == nil {
+					log.Printf("LivePortfolio: ADD_STOCK without add_details from %s", userID)
+					continue
+				}
+				addReq := action.AddDetails
+				addReq.UserId = userID
+				if _, err := s.AddStock(ctx, addReq); err != nil {
+					log.Printf("LivePortfolio: failed to add stock: %v", err)
+					continue
+				}
+				if err := s.sendPortfolioSummary(stream, userID); err != nil {
+					return err
+				}
+
+			case pb.PortfolioAction_REMOVE_STOCK:
+				if err := s.removeSymbol(ctx, userID, action.PortfolioId, symbol); err != nil {
+					log.Printf("LivePortfolio: failed to remove %s: %v", symbol, err)
+					continue
+				}
+				if err := s.sendPortfolioSummary(stream, userID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *PortfolioService) RemoveStock(ctx context.Context, req *pb.RemoveStockRequest) (*pb.RemoveStockResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	symbol, err := validateSymbol(req.Symbol)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = s.removePosition(ctx, req.UserId, req.PortfolioId, req.AllPortfolios, symbol)
+	if errors.Is(err, errAmbiguousPortfolio) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) || errors.Is(err, repository.ErrStockNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RemoveStockResponse{
+		Success: true,
+		Message: "Stock removed successfully",
+	}, nil
+}
+
+func (s *PortfolioService) RecordTransaction(ctx context.Context, req *pb.RecordTransactionRequest) (*pb.RecordTransactionResponse, error) {
+	if req.UserId == "" || req.Transaction == nil {
+		return nil, status.Error(codes.InvalidArgument, "user_id and transaction are required")
+	}
+	txn := req.Transaction
+	if err := validateTransaction(txn); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stored, position, err := s.recordTransaction(ctx, req.UserId, txn)
+	if isLedgerConflict(err) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if errors.Is(err, fx.ErrUnsupportedCurrency) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to record transaction: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if position != nil {
+		s.valuePosition(ctx, req.UserId, stored.PortfolioId, position)
+	}
+
+	return &pb.RecordTransactionResponse{
+		Success:     true,
+		Message:     "Transaction recorded successfully",
+		Transaction: stored,
+		Position:    position,
+	}, nil
+}
+
+func (s *PortfolioService) GetTransactions(ctx context.Context, req *pb.GetTransactionsRequest) (*pb.GetTransactionsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if err := s.checkPortfolio(ctx, req.UserId, req.PortfolioId); err != nil {
+		return nil, portfolioStatus(err)
+	}
+
+	txns, err := s.stockRepo.GetTransactions(ctx, req.UserId, req.PortfolioId, strings.ToUpper(req.Symbol))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.GetTransactionsResponse{Transactions: txns}, nil
+}
+
+func (s *PortfolioService) DeleteTransaction(ctx context.Context, req *pb.DeleteTransactionRequest) (*pb.DeleteTransactionResponse, error) {
+	if req.UserId == "" || req.TransactionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and transaction_id are required")
+	}
+
+	err := s.stockRepo.DeleteTransaction(ctx, req.UserId, req.TransactionId)
+	switch {
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case isLedgerConflict(err):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.DeleteTransactionResponse{
+		Success: true,
+		Message: "Transaction deleted successfully",
+	}, nil
+}
+
+func (s *PortfolioService) GetRealizedGains(ctx context.Context, req *pb.GetRealizedGainsRequest) (*pb.GetRealizedGainsResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := s.checkPortfolio(ctx, req.UserId, req.PortfolioId); err != nil {
+		return nil, portfolioStatus(err)
+	}
+
+	report, err := s.realizedGains(ctx, req.UserId, req.PortfolioId, int(req.Year))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return report, nil
+}
+
+func (s *PortfolioService) RecordCashEntry(ctx context.Context, req *pb.RecordCashEntryRequest) (*pb.RecordCashEntryResponse, error) {
+	if req.UserId == "" || req.Entry == nil {
+		return nil, status.Error(codes.InvalidArgument, "user_id and entry are required")
+	}
+	entry := req.Entry
+	if err := validateCashEntry(entry); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stored, err := s.recordCashEntry(ctx, req.UserId, entry)
+	if errors.Is(err, fx.ErrUnsupportedCurrency) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to record cash entry: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	cash, err := s.cashAccount(ctx, req.UserId, stored.PortfolioId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.RecordCashEntryResponse{
+		Success: true,
+		Message: "Cash entry recorded successfully",
+		Entry:   stored,
+		Cash:    cash.balance,
+	}, nil
+}
+
+func (s *PortfolioService) GetCashEntries(ctx context.Context, req *pb.GetCashEntriesRequest) (*pb.GetCashEntriesResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if err := s.checkPortfolio(ctx, req.UserId, req.PortfolioId); err != nil {
+		return nil, portfolioStatus(err)
+	}
+
+	entries, err := s.cashRepo.GetCashEntries(ctx, req.UserId, req.PortfolioId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	cash, err := s.cashAccount(ctx, req.UserId, req.PortfolioId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.GetCashEntriesResponse{
+		Entries:          entries,
+		Cash:             cash.balance,
+		TotalIncome:      cash.income,
+		NetContributions: cash.netContributions,
+	}, nil
+}
+
+func (s *PortfolioService) DeleteCashEntry(ctx context.Context, req *pb.DeleteCashEntryRequest) (*pb.DeleteCashEntryResponse, error) {
+	if req.UserId == "" || req.EntryId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and entry_id are required")
+	}
+
+	err := s.cashRepo.DeleteCashEntry(ctx, req.UserId, req.EntryId)
+	if errors.Is(err, repository.ErrCashEntryNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.DeleteCashEntryResponse{
+		Success: true,
+		Message: "Cash entry deleted successfully",
+	}, nil
+}
+
+func (s *PortfolioService) CreatePortfolio(ctx context.Context, req *pb.CreatePortfolioRequest) (*pb.CreatePortfolioResponse, error) {
+	if req.UserId == "" || req.Portfolio == nil {
+		return nil, status.Error(codes.InvalidArgument, "user_id and portfolio are required")
+	}
+	portfolio := req.Portfolio
+	if err := validatePortfolio(portfolio); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stored, err := s.createPortfolio(ctx, req.UserId, portfolio)
+	if errors.Is(err, repository.ErrPortfolioNameTaken) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to create portfolio: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.CreatePortfolioResponse{
+		Success:   true,
+		Message:   "Portfolio created successfully",
+		Portfolio: stored,
+	}, nil
+}
+
+func (s *PortfolioService) GetPortfolios(ctx context.Context, req *pb.GetPortfoliosRequest) (*pb.GetPortfoliosResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	portfolios, err := s.portfolios(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.GetPortfoliosResponse{Portfolios: portfolios}, nil
+}
+
+func (s *PortfolioService) UpdatePortfolio(ctx context.Context, req *pb.UpdatePortfolioRequest) (*pb.UpdatePortfolioResponse, error) {
+	if req.UserId == "" || req.Portfolio == nil || req.Portfolio.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and a portfolio with an id are required")
+	}
+	portfolio := req.Portfolio
+	if err := validatePortfolio(portfolio); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stored, err := s.portfolioRepo.UpdatePortfolio(ctx, req.UserId, portfolio)
+	switch {
+	case errors.Is(err, repository.ErrPortfolioNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrPortfolioNameTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.UpdatePortfolioResponse{
+		Success:   true,
+		Message:   "Portfolio updated successfully",
+		Portfolio: stored,
+	}, nil
+}
+
+func (s *PortfolioService) DeletePortfolio(ctx context.Context, req *pb.DeletePortfolioRequest) (*pb.DeletePortfolioResponse, error) {
+	if req.UserId == "" || req.PortfolioId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and portfolio_id are required")
+	}
+
+	err := s.deletePortfolio(ctx, req.UserId, req.PortfolioId)
+	switch {
+	case errors.Is(err, repository.ErrPortfolioNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrDefaultPortfolio) || errors.Is(err, repository.ErrPortfolioNotEmpty):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.DeletePortfolioResponse{
+		Success: true,
+		Message: "Portfolio deleted successfully",
+	}, nil
+}
+
+func (s *PortfolioService) GetUserSettings(ctx context.Context, req *pb.GetUserSettingsRequest) (*pb.UserSettings, error) {
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	baseCurrency, err := s.userRepo.GetBaseCurrency(ctx, req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.UserSettings{UserId: req.UserId, BaseCurrency: baseCurrency}, nil
+}
+
+func (s *PortfolioService) UpdateUserSettings(ctx context.Context, req *pb.UpdateUserSettingsRequest) (*pb.UserSettings, error) {
+	if req.Settings == nil || req.Settings.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "settings with a user_id are required")
+	}
+
+	baseCurrency, err := s.setBaseCurrency(ctx, req.Settings.UserId, req.Settings.BaseCurrency)
+	switch {
+	case errors.Is(err, fx.ErrUnsupportedCurrency) || errors.Is(err, errInvalidCurrency):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.UserSettings{UserId: req.Settings.UserId, BaseCurrency: baseCurrency}, nil
+}
+
+func (s *PortfolioService) CreateCorporateAction(ctx context.Context, req *pb.CreateCorporateActionRequest) (*pb.CreateCorporateActionResponse, error) {
+	if req.Action == nil {
+		return nil, status.Error(codes.InvalidArgument, "action is required")
+	}
+	action := req.Action
+	if err := validateCorporateAction(action); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stored, err := s.corporate.Schedule(ctx, action)
+	if stored != nil && isLedgerConflict(err) {
+		return nil, status.Errorf(codes.FailedPrecondition, "corporate action %s saved but not applied: %v", stored.Id, err)
+	}
+	if err != nil {
+		log.Printf("Failed to create corporate action: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	message := "Corporate action scheduled"
+	if stored.AppliedAt != 0 {
+		message = "Corporate action applied"
+	}
+	return &pb.CreateCorporateActionResponse{
+		Success: true,
+		Message: message,
+		Action:  stored,
+	}, nil
+}
+
+func (s *PortfolioService) GetCorporateActions(ctx context.Context, req *pb.GetCorporateActionsRequest) (*pb.GetCorporateActionsResponse, error) {
+	symbol := req.Symbol
+	if symbol != "" {
+		var err error
+		if symbol, err = validateSymbol(symbol); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	actions, err := s.corporate.Actions(ctx, symbol)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.GetCorporateActionsResponse{Actions: actions}, nil
+}
+
+func (s *PortfolioService) GetHistoricalData(ctx context.Context, req *pb.HistoricalDataRequest) (*pb.HistoricalDataResponse, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	if symbol == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol is required")
+	}
+
+	bars, err := s.priceHistory(ctx, symbol, req.Interval, req.StartDate, req.EndDate, req.Simulated)
+	if err != nil {
+		return nil, historyStatus(err)
+	}
+
+	return &pb.HistoricalDataResponse{Symbol: symbol, Prices: bars}, nil
+}
+
+func (s *PortfolioService) StreamChart(req *pb.StreamChartRequest, stream pb.PortfolioService_StreamChartServer) error {
+	if s.priceManager == nil {
+		return status.Error(codes.Unavailable, "price manager not configured")
+	}
+	symbol := strings.ToUpper(req.Symbol)
+	if symbol == "" {
+		return status.Error(codes.InvalidArgument, "symbol is required")
+	}
+	timeframe := req.Timeframe
+	if timeframe == "" {
+		timeframe = string(history.Day)
+	}
+	interval, err := history.ParseInterval(timeframe)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	selection := strings.Join(req.Indicators, ",")
+	if selection == "" {
+		selection = defaultChartIndicators
+	}
+	specs, err := indicators.ParseSpecs(selection)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Subscribe before reading history so no tick falls between the two; ticks
+	// already counted in the stored bars are ignored or only touch the forming bar
+	ctx := stream.Context()
+	sub := s.priceManager.Subscribe(streamOptions(true, nil), symbol)
+	defer s.priceManager.Unsubscribe(sub)
+
+	bars, err := s.priceHistory(ctx, symbol, timeframe, 0, 0, false)
+	if err != nil && !errors.Is(err, errNoHistory) {
+		return historyStatus(err)
+	}
+	series := indicators.FromBars(bars)
+
+	live := make([]*indicators.Incremental, len(specs))
+	for i, spec := range specs {
+		if live[i], err = spec.Incremental(series); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	var lastBar *pb.HistoricalPrice
+	if len(bars) > 0 {
+		lastBar = bars[len(bars)-1]
+	}
+	candles := history.NewLiveBar(interval, lastBar)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-sub.C():
+			if !ok {
+				return subscriptionError(sub)
+			}
+			bar := candles.Add(update)
+			if bar == nil {
+				continue
+			}
+
+			candle := indicators.FromBar(bar)
+			chartUpdate := &pb.ChartUpdate{
+				Symbol:     symbol,
+				Candle:     barToCandlestick(bar),
+				Indicators: make(map[string]float64),
+				Sequence:   update.Sequence,
+			}
+			for _, inc := range live {
+				for key, value := range inc.Update(candle) {
+					chartUpdate.Indicators[key] = value
+				}
+			}
+
+			if err := stream.Send(chartUpdate); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Helper functions for the gRPC handlers
+
+// checkActionUser rejects a LivePortfolio action that names a user other than the one
+// the stream is bound to, directly or in its add_details
+func checkActionUser(userID string, action *pb.PortfolioAction) error {
+	named := []string{action.UserId}
+	if action.AddDetails != nil {
+		named = append(named, action.AddDetails.UserId)
+	}
+	for _, other := range named {
+		if other != "" && other != userID {
+			return status.Errorf(codes.PermissionDenied, "stream is bound to user %s", userID)
+		}
+	}
+	return nil
+}
+
+// sendPortfolioSummary pushes a PORTFOLIO_SUMMARY update on a LivePortfolio stream
+func (s *PortfolioService) sendPortfolioSummary(stream pb.PortfolioService_LivePortfolioServer, userID string) error {
+	summary, _, err := s.portfolioSummary(stream.Context(), userID, "")
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return stream.Send(&pb.PortfolioUpdate{
+		Type:             pb.PortfolioUpdate_PORTFOLIO_SUMMARY,
+		PortfolioSummary: summary,
+		Timestamp:        time.Now().Unix(),
+	})
+}
+
+// removeSymbol removes a user's position in symbol from portfolioID, or from their
+// default portfolio if it is empty
+func (s *PortfolioService) removeSymbol(ctx context.Context, userID, portfolioID, symbol string) error {
+	err := s.removePosition(ctx, userID, portfolioID, false, symbol)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, repository.ErrStockNotFound) {
+		return status.Errorf(codes.NotFound, "no holdings of %s", symbol)
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
+
+// portfolioStatus maps an error from checking a portfolio filter to a gRPC status
+func portfolioStatus(err error) error {
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// streamOptions configures the price subscription behind a client stream. A slow
+// client only needs the latest price for each symbol, so queued updates are conflated.
+func streamOptions(snapshot bool, resume map[string]uint64) stream.SubscribeOptions {
+	return stream.SubscribeOptions{Policy: stream.Conflate, Snapshot: snapshot, Resume: resume}
+}
+
+// historyStatus maps a priceHistory error to a gRPC status
+func historyStatus(err error) error {
+	switch {
+	case errors.Is(err, errInvalidHistoryRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errUnknownSymbol), errors.Is(err, errNoHistory):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// subscriptionError explains why PriceManager closed a client's subscription
+func subscriptionError(sub *stream.Subscription) error {
+	if err := sub.Err(); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Unavailable, "price subscription closed")
+}
+
+func alertToProto(alert *repository.Alert) *pb.Alert {
+	pbAlert := &pb.Alert{
+		Id:          alert.ID,
+		Symbol:      alert.Symbol,
+		TargetPrice: alert.TargetPrice,
+		Condition:   pb.AlertCondition(alert.Condition),
+		CreatedAt:   alert.CreatedAt,
+		IsTriggered: alert.IsTriggered,
+		PortfolioId: alert.PortfolioID,
+	}
+	if alert.TriggeredPrice != nil {
+		pbAlert.TriggeredPrice = *alert.TriggeredPrice
+	}
+	if alert.TriggeredAt != nil {
+		pbAlert.TriggeredAt = *alert.TriggeredAt
+	}
+
+	return pbAlert
+}
+
+func barToCandlestick(bar *pb.HistoricalPrice) *pb.CandlestickData {
+	return &pb.CandlestickData{
+		Timestamp: bar.Timestamp,
+		Open:      bar.Open,
+		High:      bar.High,
+		Low:       bar.Low,
+		Close:     bar.Close,
+		Volume:    bar.Volume,
+	}
+}
+
+func pointsToProto(name string, points []indicators.Point) *pb.TechnicalIndicatorData {
+	data := &pb.TechnicalIndicatorData{Name: name}
+	for _, point := range points {
+		data.Points = append(data.Points, &pb.IndicatorPoint{
+			Timestamp: point.Time,
+			Value:     point.Value,
+		})
+	}
+
+	return data
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// liveStream feeds actions to LivePortfolio, then ends, and collects what it sends
+type liveStream struct {
+	grpc.ServerStream
+	actions []*pb.PortfolioAction
+	sent    []*pb.PortfolioUpdate
+}
+
+func (l *liveStream) Context() context.Context {
+	return context.Background()
+}
+
+func (l *liveStream) Recv() (*pb.PortfolioAction, error) {
+	if len(l.actions) == 0 {
+		return nil, io.EOF
+	}
+	action := l.actions[0]
+	l.actions = l.actions[1:]
+	return action, nil
+}
+
+func (l *liveStream) Send(update *pb.PortfolioUpdate) error {
+	l.sent = append(l.sent, update)
+	return nil
+}
+
+func TestListingRequiresAUser(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+
+	tests := map[string]func() error{
+		"GetAlerts": func() error {
+			_, err := s.GetAlerts(ctx, &pb.GetAlertsRequest{})
+			return err
+		},
+		"GetTransactions": func() error {
+			_, err := s.GetTransactions(ctx, &pb.GetTransactionsRequest{Symbol: "AAPL"})
+			return err
+		},
+		"GetCashEntries": func() error {
+			_, err := s.GetCashEntries(ctx, &pb.GetCashEntriesRequest{})
+			return err
+		},
+	}
+
+	for name, call := range tests {
+		if code := status.Code(call()); code != codes.InvalidArgument {
+			t.Errorf("%s without a user_id: got %s, want InvalidArgument", name, code)
+		}
+	}
+}
+
+func TestLivePortfolioBindsOneUser(t *testing.T) {
+	add := func(userID string) *pb.PortfolioAction {
+		return &pb.PortfolioAction{
+			Action:     pb.PortfolioAction_ADD_STOCK,
+			AddDetails: &pb.AddStockRequest{UserId: userID, Symbol: "AAPL", Quantity: 1, PurchasePrice: 100},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		actions []*pb.PortfolioAction
+		want    codes.Code
+		// alice's and bob's positions afterwards
+		alice, bob int
+	}{
+		{
+			name:    "later actions act for the bound user",
+			actions: []*pb.PortfolioAction{{Action: pb.PortfolioAction_SUBSCRIBE, Symbol: "AAPL", UserId: "alice"}, add("")},
+			want:    codes.OK,
+			alice:   1,
+		},
+		{
+			name:    "no user to bind",
+			actions: []*pb.PortfolioAction{add("alice")},
+			want:    codes.InvalidArgument,
+		},
+		{
+			name:    "add for another user",
+			actions: []*pb.PortfolioAction{{Action: pb.PortfolioAction_SUBSCRIBE, UserId: "alice"}, add("bob")},
+			want:    codes.PermissionDenied,
+		},
+		{
+			name: "remove for another user",
+			actions: []*pb.PortfolioAction{
+				{Action: pb.PortfolioAction_SUBSCRIBE, UserId: "bob"},
+				add(""),
+				{Action: pb.PortfolioAction_REMOVE_STOCK, Symbol: "AAPL", UserId: "alice"},
+			},
+			want: codes.PermissionDenied,
+			bob:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			s.priceManager = stream.NewPriceManager(nil, stream.NewRandomWalkSource(nil, time.Hour))
+			live := &liveStream{actions: tt.actions}
+
+			if code := status.Code(s.LivePortfolio(live)); code != tt.want {
+				t.Errorf("got %s, want %s", code, tt.want)
+			}
+			if tt.alice+tt.bob > 0 && (len(live.sent) == 0 || live.sent[len(live.sent)-1].Type != pb.PortfolioUpdate_PORTFOLIO_SUMMARY) {
+				t.Errorf("sent %v, want a summary after the add", live.sent)
+			}
+			for user, want := range map[string]int{"alice": tt.alice, "bob": tt.bob} {
+				stocks, err := s.stockRepo.GetPortfolio(context.Background(), user, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(stocks) != want {
+					t.Errorf("%s holds %d positions, want %d", user, len(stocks), want)
+				}
+			}
+		})
+	}
+}
@@ -3,15 +3,27 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
-	"math/rand"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/alert"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/corporate"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/costbasis"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/history"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/indicators"
 	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
 	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/stream"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
 )
 
 // Simple structs for HTTP API
@@ -24,12 +36,64 @@ type Stock struct {
 	CurrentPrice    float64
 	GainLoss        float64
 	GainLossPercent float64
+	// PriceAvailable is false when no quote has been seen for the symbol yet;
+	// the position is then valued at its purchase price with zero gain.
+	PriceAvailable bool
+	// Prices and GainLoss are in Currency, the symbol's listing currency. The
+	// fields below are only set in GetPortfolioResponse and are in its BaseCurrency.
+	Currency       string
+	FxRate         float64
+	MarketValue    float64
+	PriceEffect    float64
+	CurrencyEffect float64
 }
 
 type GetPortfolioResponse struct {
-	Stocks        []*Stock
-	TotalValue    float64
-	TotalGainLoss float64
+	Stocks               []*Stock
+	TotalValue           float64
+	TotalGainLoss        float64
+	TotalGainLossPercent float64
+	UnpricedSymbols      []string
+	Cash                 float64
+	InvestedValue        float64
+	TotalIncome          float64
+	NetContributions     float64
+	BaseCurrency         string
+	PriceEffect          float64
+	CurrencyEffect       float64
+	// PortfolioID is empty for the consolidated view, which lists each portfolio's totals
+	PortfolioID string
+	Portfolios  []*PortfolioSummary
+}
+
+// Portfolio is a named set of holdings, cash and alerts; IsDefault marks the one that
+// takes whatever is recorded without naming a portfolio
+type Portfolio struct {
+	ID          string
+	Name        string
+	Description string
+	IsDefault   bool
+	CreatedAt   int64
+}
+
+// PortfolioSummary is one portfolio's totals within the consolidated view
+type PortfolioSummary struct {
+	Portfolio            *Portfolio
+	TotalValue           float64
+	InvestedValue        float64
+	Cash                 float64
+	TotalGainLoss        float64
+	TotalGainLossPercent float64
+}
+
+type PortfolioResponse struct {
+	Success   bool
+	Message   string
+	Portfolio *Portfolio
+}
+
+type GetPortfoliosResponse struct {
+	Portfolios []*Portfolio
 }
 
 type AddStockResponse struct {
@@ -38,6 +102,134 @@ type AddStockResponse struct {
 	Stock   *Stock
 }
 
+// Transaction is one ledger entry; Type is "BUY" or "SELL"
+type Transaction struct {
+	ID        string
+	Symbol    string
+	Name      string
+	Type      string
+	Quantity  float64
+	Price     float64
+	Fees      float64
+	Timestamp int64
+	// LotMethod and LotID say which lots a SELL closes
+	LotMethod   string
+	LotID       string
+	Currency    string
+	PortfolioID string
+}
+
+type RecordTransactionResponse struct {
+	Success     bool
+	Message     string
+	Transaction *Transaction
+	// Position is the symbol's position after the transaction, nil once sold out
+	Position *Stock
+}
+
+type GetTransactionsResponse struct {
+	Transactions []*Transaction
+}
+
+// RealizedGain is the result of selling shares out of one lot
+type RealizedGain struct {
+	SellID       string
+	LotID        string
+	Symbol       string
+	Quantity     float64
+	PurchaseDate int64
+	SaleDate     int64
+	CostBasis    float64
+	Proceeds     float64
+	Gain         float64
+	LongTerm     bool
+	Currency     string
+	// PriceEffect and CurrencyEffect split Gain; amounts are in the report's base currency
+	PriceEffect    float64
+	CurrencyEffect float64
+	PortfolioID    string
+}
+
+type GetRealizedGainsResponse struct {
+	Year               int
+	Gains              []*RealizedGain
+	ShortTermGain      float64
+	LongTermGain       float64
+	TotalRealizedGain  float64
+	TotalProceeds      float64
+	TotalCostBasis     float64
+	UnrealizedGainLoss float64
+	BaseCurrency       string
+	PriceEffect        float64
+	CurrencyEffect     float64
+}
+
+// CashEntry is a cash movement other than a trade; Type is "DEPOSIT", "WITHDRAWAL",
+// "DIVIDEND", "FEE" or "INTEREST" and Amount is always positive
+type CashEntry struct {
+	ID          string
+	Type        string
+	Amount      float64
+	Symbol      string
+	Description string
+	Timestamp   int64
+	Currency    string
+	PortfolioID string
+}
+
+type RecordCashEntryResponse struct {
+	Success bool
+	Message string
+	Entry   *CashEntry
+	Cash    float64
+}
+
+type GetCashEntriesResponse struct {
+	Entries          []*CashEntry
+	Cash             float64
+	TotalIncome      float64
+	NetContributions float64
+}
+
+// CorporateAction is a split or symbol change; Type is "SPLIT" or "SYMBOL_CHANGE".
+// AppliedAt is 0 until the ex-date passes and the counts say what it changed.
+type CorporateAction struct {
+	ID                   string
+	Symbol               string
+	Type                 string
+	ExDate               int64
+	SplitTo              float64
+	SplitFrom            float64
+	CashInLieuPrice      float64
+	NewSymbol            string
+	AppliedAt            int64
+	TransactionsAdjusted int64
+	AlertsAdjusted       int64
+	HistoryAdjusted      int64
+	CashInLieuSales      int64
+}
+
+type CreateCorporateActionResponse struct {
+	Success bool
+	Message string
+	Action  *CorporateAction
+}
+
+type GetCorporateActionsResponse struct {
+	Actions []*CorporateAction
+}
+
+// UserSettings are a user's preferences; BaseCurrency is what portfolio totals are reported in
+type UserSettings struct {
+	UserID       string
+	BaseCurrency string
+}
+
+type RemoveStockResponse struct {
+	Success bool
+	Message string
+}
+
 type SetPriceAlertResponse struct {
 	Success bool
 	Message string
@@ -49,157 +241,93 @@ type GetAlertsResponse struct {
 }
 
 type PortfolioService struct {
-	stockRepo    *repository.StockRepository
-	alertRepo    *repository.AlertRepository
-	priceManager *stream.PriceManager
+	pb.UnimplementedPortfolioServiceServer
+
+	stockRepo     repository.StockStore
+	alertRepo     repository.AlertStore
+	cashRepo      repository.CashStore
+	portfolioRepo repository.PortfolioStore
+	userRepo      repository.UserStore
+	historyRepo   repository.PriceHistoryStore
+	priceManager  *stream.PriceManager
+	fxSource      fx.RateSource
+	alertEngine   *alert.Engine
+	corporate     *corporate.Processor
 }
 
 func NewPortfolioService(
-	stockRepo *repository.StockRepository,
-	alertRepo *repository.AlertRepository,
+	stockRepo repository.StockStore,
+	alertRepo repository.AlertStore,
+	cashRepo repository.CashStore,
+	portfolioRepo repository.PortfolioStore,
+	userRepo repository.UserStore,
+	historyRepo repository.PriceHistoryStore,
 	priceManager *stream.PriceManager,
+	fxSource fx.RateSource,
+	alertEngine *alert.Engine,
+	corporateActions *corporate.Processor,
 ) *PortfolioService {
 	return &PortfolioService{
-		stockRepo:    stockRepo,
-		alertRepo:    alertRepo,
-		priceManager: priceManager,
+		stockRepo:     stockRepo,
+		alertRepo:     alertRepo,
+		cashRepo:      cashRepo,
+		portfolioRepo: portfolioRepo,
+		userRepo:      userRepo,
+		historyRepo:   historyRepo,
+		priceManager:  priceManager,
+		fxSource:      fxSource,
+		alertEngine:   alertEngine,
+		corporate:     corporateActions,
 	}
 }
 
 // HTTP Handlers for REST API
 
 func (s *PortfolioService) GetPortfolioHTTP(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
+	query := r.URL.Query()
+	userID := query.Get("user_id")
 	if userID == "" {
 		userID = "demo-user-1" // Default for demo
 	}
 
-	// Mock implementation for now
+	summary, unpriced, err := s.portfolioView(r.Context(), userID, query.Get("portfolio_id"))
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio for %s: %v", userID, err)
+		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+		return
+	}
+
 	response := &GetPortfolioResponse{
-		Stocks: []*Stock{
-			{
-				ID:              "stock-1",
-				Symbol:          "AAPL",
-				Name:            "Apple Inc.",
-				Quantity:        10,
-				PurchasePrice:   150.00,
-				CurrentPrice:    175.50,
-				GainLoss:        255.00,
-				GainLossPercent: 17.00,
-			},
-			{
-				ID:              "stock-2",
-				Symbol:          "GOOGL",
-				Name:            "Alphabet Inc.",
-				Quantity:        5,
-				PurchasePrice:   2800.00,
-				CurrentPrice:    2950.00,
-				GainLoss:        750.00,
-				GainLossPercent: 5.36,
-			},
-			{
-				ID:              "stock-3",
-				Symbol:          "MSFT",
-				Name:            "Microsoft Corporation",
-				Quantity:        8,
-				PurchasePrice:   300.00,
-				CurrentPrice:    415.26,
-				GainLoss:        921.08,
-				GainLossPercent: 38.39,
-			},
-			{
-				ID:              "stock-4",
-				Symbol:          "TSLA",
-				Name:            "Tesla Inc.",
-				Quantity:        15,
-				PurchasePrice:   200.00,
-				CurrentPrice:    248.42,
-				GainLoss:        726.30,
-				GainLossPercent: 24.21,
-			},
-			{
-				ID:              "stock-5",
-				Symbol:          "JPM",
-				Name:            "JPMorgan Chase & Co.",
-				Quantity:        12,
-				PurchasePrice:   180.00,
-				CurrentPrice:    198.35,
-				GainLoss:        220.20,
-				GainLossPercent: 10.18,
-			},
-			{
-				ID:              "stock-6",
-				Symbol:          "JNJ",
-				Name:            "Johnson & Johnson",
-				Quantity:        20,
-				PurchasePrice:   140.00,
-				CurrentPrice:    155.67,
-				GainLoss:        313.40,
-				GainLossPercent: 11.19,
-			},
-			{
-				ID:              "stock-7",
-				Symbol:          "AMZN",
-				Name:            "Amazon.com Inc.",
-				Quantity:        25,
-				PurchasePrice:   3200.00,
-				CurrentPrice:    3442.13,
-				GainLoss:        6053.25,
-				GainLossPercent: 7.54,
-			},
-			{
-				ID:              "stock-8",
-				Symbol:          "NVDA",
-				Name:            "NVIDIA Corporation",
-				Quantity:        30,
-				PurchasePrice:   450.00,
-				CurrentPrice:    875.28,
-				GainLoss:        12758.40,
-				GainLossPercent: 94.48,
-			},
-			{
-				ID:              "stock-9",
-				Symbol:          "META",
-				Name:            "Meta Platforms Inc.",
-				Quantity:        18,
-				PurchasePrice:   330.00,
-				CurrentPrice:    484.16,
-				GainLoss:        2786.88,
-				GainLossPercent: 46.87,
-			},
-			{
-				ID:              "stock-10",
-				Symbol:          "NFLX",
-				Name:            "Netflix Inc.",
-				Quantity:        22,
-				PurchasePrice:   380.00,
-				CurrentPrice:    612.09,
-				GainLoss:        5129.98,
-				GainLossPercent: 61.11,
-			},
-			{
-				ID:              "stock-11",
-				Symbol:          "V",
-				Name:            "Visa Inc.",
-				Quantity:        40,
-				PurchasePrice:   220.00,
-				CurrentPrice:    265.47,
-				GainLoss:        1818.80,
-				GainLossPercent: 20.68,
-			},
-			{
-				ID:              "stock-12",
-				Symbol:          "WMT",
-				Name:            "Walmart Inc.",
-				Quantity:        35,
-				PurchasePrice:   140.00,
-				CurrentPrice:    153.78,
-				GainLoss:        482.30,
-				GainLossPercent: 9.99,
-			},
-		},
-		TotalValue:    125847.15,
-		TotalGainLoss: 27714.59,
+		Stocks:               make([]*Stock, 0, len(summary.Stocks)),
+		TotalValue:           summary.TotalValue,
+		TotalGainLoss:        summary.TotalGainLoss,
+		TotalGainLossPercent: summary.TotalGainLossPercentage,
+		UnpricedSymbols:      unpriced,
+		Cash:                 summary.Cash,
+		InvestedValue:        summary.InvestedValue,
+		TotalIncome:          summary.TotalIncome,
+		NetContributions:     summary.NetContributions,
+		BaseCurrency:         summary.BaseCurrency,
+		PriceEffect:          summary.PriceEffect,
+		CurrencyEffect:       summary.CurrencyEffect,
+		PortfolioID:          summary.PortfolioId,
+	}
+	for _, stock := range summary.Stocks {
+		response.Stocks = append(response.Stocks, stockFromProto(stock, !slices.Contains(unpriced, stock.Symbol)))
+	}
+	for _, portfolio := range summary.Portfolios {
+		response.Portfolios = append(response.Portfolios, &PortfolioSummary{
+			Portfolio:            portfolioFromProto(portfolio.Portfolio),
+			TotalValue:           portfolio.TotalValue,
+			InvestedValue:        portfolio.InvestedValue,
+			Cash:                 portfolio.Cash,
+			TotalGainLoss:        portfolio.TotalGainLoss,
+			TotalGainLossPercent: portfolio.TotalGainLossPercentage,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -210,334 +338,1798 @@ func (s *PortfolioService) AddStockHTTP(w http.ResponseWriter, r *http.Request)
 	var req struct {
 		UserID        string  `json:"user_id"`
 		Symbol        string  `json:"symbol"`
+		Name          string  `json:"name"`
 		Quantity      float64 `json:"quantity"`
 		PurchasePrice float64 `json:"purchase_price"`
+		PurchaseDate  int64   `json:"purchase_date"`
+		PortfolioID   string  `json:"portfolio_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
+
+	symbol, err := validateStockInput(req.Symbol, req.Quantity, req.PurchasePrice)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = symbol
+	}
+	purchaseDate := req.PurchaseDate
+	if purchaseDate == 0 {
+		purchaseDate = time.Now().Unix()
+	}
+
+	portfolio, err := s.portfolioFor(r.Context(), req.UserID, req.PortfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio for %s: %v", req.UserID, err)
+		http.Error(w, "Failed to add stock", http.StatusInternalServerError)
+		return
+	}
+
+	stock, err := s.stockRepo.AddStock(r.Context(), req.UserID, portfolio.Id, symbol, name, req.Quantity, req.PurchasePrice, purchaseDate)
+	if isLedgerConflict(err) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to add stock: %v", err)
+		http.Error(w, "Failed to add stock", http.StatusInternalServerError)
+		return
+	}
+
+	if s.priceManager != nil {
+		s.priceManager.AddSymbol(symbol, req.PurchasePrice)
+	}
 
 	response := &AddStockResponse{
 		Success: true,
 		Message: "Stock added successfully",
-		Stock: &Stock{
-			ID:              "new-stock-id",
-			Symbol:          req.Symbol,
-			Name:            req.Symbol + " Corp.",
-			Quantity:        req.Quantity,
-			PurchasePrice:   req.PurchasePrice,
-			CurrentPrice:    req.PurchasePrice * 1.05,
-			GainLoss:        req.PurchasePrice * req.Quantity * 0.05,
-			GainLossPercent: 5.00,
-		},
+		Stock:   stockFromProto(stock, s.valuePosition(r.Context(), req.UserID, portfolio.Id, stock)),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s *PortfolioService) GetAlertsHTTP(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
+func (s *PortfolioService) RemoveStockHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
 	if userID == "" {
 		userID = "demo-user-1" // Default for demo
 	}
+	symbol, err := validateSymbol(mux.Vars(r)["symbol"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response := &GetAlertsResponse{}
-
-	// Try to get real alerts from database, fallback to mock data
-	if s.alertRepo != nil {
-		alerts, err := s.alertRepo.GetUserAlerts(context.Background(), userID)
-		if err == nil {
-			response.Alerts = alerts
-		} else {
-			// Fallback to mock data
-			response.Alerts = []*repository.Alert{
-				{
-					ID:          "1",
-					Symbol:      "AAPL",
-					TargetPrice: 180.00,
-					Condition:   0,
-					IsTriggered: false,
-					CreatedAt:   1640995200,
-				},
-				{
-					ID:          "2",
-					Symbol:      "GOOGL",
-					TargetPrice: 2900.00,
-					Condition:   1,
-					IsTriggered: true,
-					CreatedAt:   1641081600,
-				},
-			}
-		}
-	} else {
-		// Mock data when no database connection
-		response.Alerts = []*repository.Alert{
-			{
-				ID:          "1",
-				Symbol:      "AAPL",
-				TargetPrice: 180.00,
-				Condition:   0,
-				IsTriggered: false,
-				CreatedAt:   1640995200,
-			},
-			{
-				ID:          "2",
-				Symbol:      "GOOGL",
-				TargetPrice: 2900.00,
-				Condition:   1,
-				IsTriggered: true,
-				CreatedAt:   1641081600,
-			},
+	allPortfolios := false
+	if value := query.Get("all_portfolios"); value != "" {
+		if allPortfolios, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "all_portfolios must be true or false", http.StatusBadRequest)
+			return
 		}
 	}
 
+	err = s.removePosition(r.Context(), userID, query.Get("portfolio_id"), allPortfolios, symbol)
+	if errors.Is(err, errAmbiguousPortfolio) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrStockNotFound) {
+		http.Error(w, "Stock not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to remove stock %s: %v", symbol, err)
+		http.Error(w, "Failed to remove stock", http.StatusInternalServerError)
+		return
+	}
+
+	response := &RemoveStockResponse{
+		Success: true,
+		Message: "Stock removed successfully",
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s *PortfolioService) SetAlertHTTP(w http.ResponseWriter, r *http.Request) {
+func (s *PortfolioService) RecordTransactionHTTP(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID      string  `json:"user_id"`
 		Symbol      string  `json:"symbol"`
-		TargetPrice float64 `json:"target_price"`
-		Condition   int     `json:"condition"`
+		Name        string  `json:"name"`
+		Type        string  `json:"type"`
+		Quantity    float64 `json:"quantity"`
+		Price       float64 `json:"price"`
+		Fees        float64 `json:"fees"`
+		Timestamp   int64   `json:"timestamp"`
+		LotMethod   string  `json:"lot_method"`
+		LotID       string  `json:"lot_id"`
+		Currency    string  `json:"currency"`
+		PortfolioID string  `json:"portfolio_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-
-	response := &SetPriceAlertResponse{
-		Success: false,
-		Message: "Failed to set alert",
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
 	}
 
-	// Try to create alert in database if repository is available
-	if s.alertRepo != nil {
-		condition := repository.AlertCondition_ABOVE
-		if req.Condition == 1 {
-			condition = repository.AlertCondition_BELOW
+	txnType, ok := pb.TransactionType_value[strings.ToUpper(req.Type)]
+	if !ok {
+		http.Error(w, "type must be BUY or SELL", http.StatusBadRequest)
+		return
+	}
+	lotMethod := pb.LotMethod_FIFO
+	if req.LotMethod != "" {
+		value, ok := pb.LotMethod_value[strings.ToUpper(req.LotMethod)]
+		if !ok {
+			http.Error(w, "lot_method must be FIFO, LIFO, HIFO or SPECIFIC_LOT", http.StatusBadRequest)
+			return
 		}
+		lotMethod = pb.LotMethod(value)
+	}
+	txn := &pb.Transaction{
+		Symbol:      req.Symbol,
+		Name:        strings.TrimSpace(req.Name),
+		Type:        pb.TransactionType(txnType),
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		Fees:        req.Fees,
+		Timestamp:   req.Timestamp,
+		LotMethod:   lotMethod,
+		LotId:       strings.TrimSpace(req.LotID),
+		Currency:    req.Currency,
+		PortfolioId: req.PortfolioID,
+	}
+	if err := validateTransaction(txn); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		alertID, err := s.alertRepo.CreateAlert(context.Background(), req.UserID, req.Symbol, req.TargetPrice, condition)
-		if err != nil {
-			log.Printf("Failed to create alert: %v", err)
-			response.Message = "Database error: " + err.Error()
-		} else {
-			response.Success = true
-			response.Message = "Alert set successfully"
-			response.AlertId = alertID
-		}
-	} else {
-		// Fallback to mock response when no database
-		response.Success = true
-		response.Message = "Alert set successfully (mock mode)"
-		response.AlertId = "mock-alert-" + fmt.Sprintf("%d", time.Now().Unix())
+	stored, position, err := s.recordTransaction(r.Context(), req.UserID, txn)
+	if errors.Is(err, fx.ErrUnsupportedCurrency) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if isLedgerConflict(err) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record transaction: %v", err)
+		http.Error(w, "Failed to record transaction", http.StatusInternalServerError)
+		return
+	}
+
+	response := &RecordTransactionResponse{
+		Success:     true,
+		Message:     "Transaction recorded successfully",
+		Transaction: transactionFromProto(stored),
+	}
+	if position != nil {
+		response.Position = stockFromProto(position, s.valuePosition(r.Context(), req.UserID, stored.PortfolioId, position))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s *PortfolioService) GetChartDataHTTP(w http.ResponseWriter, r *http.Request) {
-	symbol := r.URL.Query().Get("symbol")
-	if symbol == "" {
-		symbol = "AAPL" // Default symbol
+func (s *PortfolioService) GetTransactionsHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
 	}
 
-	// Generate mock chart data with technical indicators
-	data := generateMockChartData(symbol, 0, 0)
-	sma := generateSMAIndicator(data, 20)
-	rsi := generateRSIIndicator(data, 14)
-	macd := generateMACDIndicator(data)
+	portfolioID := query.Get("portfolio_id")
+	err := s.checkPortfolio(r.Context(), userID, portfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio %s: %v", portfolioID, err)
+		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+		return
+	}
 
-	response := map[string]interface{}{
-		"symbol":       symbol,
-		"candlesticks": data,
-		"indicators": map[string]interface{}{
-			"sma":  sma["points"],
-			"rsi":  rsi["points"],
-			"macd": macd["points"],
-		},
+	txns, err := s.stockRepo.GetTransactions(r.Context(), userID, portfolioID, strings.ToUpper(query.Get("symbol")))
+	if err != nil {
+		log.Printf("Failed to load transactions for %s: %v", userID, err)
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return
+	}
+
+	response := &GetTransactionsResponse{Transactions: make([]*Transaction, 0, len(txns))}
+	for _, txn := range txns {
+		response.Transactions = append(response.Transactions, transactionFromProto(txn))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// Helper functions for chart data generation
-
-func generateMockChartData(symbol string, startTime, endTime int64) []map[string]interface{} {
-	var candlesticks []map[string]interface{}
-
-	// Generate data for the last 100 days if no time range specified
-	if startTime == 0 {
-		startTime = time.Now().AddDate(0, 0, -100).Unix()
+func (s *PortfolioService) DeleteTransactionHTTP(w http.ResponseWriter, r *http.Request) {
+	transactionID := mux.Vars(r)["id"]
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
 	}
-	if endTime == 0 {
-		endTime = time.Now().Unix()
+
+	err := s.stockRepo.DeleteTransaction(r.Context(), userID, transactionID)
+	switch {
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	case isLedgerConflict(err):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to delete transaction %s: %v", transactionID, err)
+		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+		return
 	}
 
-	basePrice := 150.0
-	currentPrice := basePrice
+	response := &RemoveStockResponse{
+		Success: true,
+		Message: "Transaction deleted successfully",
+	}
 
-	// Generate daily candlesticks
-	for timestamp := startTime; timestamp <= endTime; timestamp += 86400 {
-		change := (rand.Float64() - 0.5) * 20
-		open := currentPrice
-		close := currentPrice + change
-		high := math.Max(open, close) + rand.Float64()*10
-		low := math.Min(open, close) - rand.Float64()*10
-		volume := rand.Float64()*1000000 + 500000
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-		candlestick := map[string]interface{}{
-			"time":   timestamp,
-			"open":   math.Round(open*100) / 100,
-			"high":   math.Round(high*100) / 100,
-			"low":    math.Round(low*100) / 100,
-			"close":  math.Round(close*100) / 100,
-			"volume": math.Round(volume),
+func (s *PortfolioService) GetRealizedGainsHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
+	year := 0
+	if value := query.Get("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "year must be a number", http.StatusBadRequest)
+			return
 		}
+	}
+
+	portfolioID := query.Get("portfolio_id")
+	err := s.checkPortfolio(r.Context(), userID, portfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio %s: %v", portfolioID, err)
+		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := s.realizedGains(r.Context(), userID, portfolioID, year)
+	if err != nil {
+		log.Printf("Failed to load realized gains for %s: %v", userID, err)
+		http.Error(w, "Failed to load realized gains", http.StatusInternalServerError)
+		return
+	}
 
-		candlesticks = append(candlesticks, candlestick)
-		currentPrice = close
+	response := &GetRealizedGainsResponse{
+		Year:               int(report.Year),
+		Gains:              make([]*RealizedGain, 0, len(report.Gains)),
+		ShortTermGain:      report.ShortTermGain,
+		LongTermGain:       report.LongTermGain,
+		TotalRealizedGain:  report.TotalRealizedGain,
+		TotalProceeds:      report.TotalProceeds,
+		TotalCostBasis:     report.TotalCostBasis,
+		UnrealizedGainLoss: report.UnrealizedGainLoss,
+		BaseCurrency:       report.BaseCurrency,
+		PriceEffect:        report.PriceEffect,
+		CurrencyEffect:     report.CurrencyEffect,
+	}
+	for _, gain := range report.Gains {
+		response.Gains = append(response.Gains, &RealizedGain{
+			SellID:         gain.SellId,
+			LotID:          gain.LotId,
+			Symbol:         gain.Symbol,
+			Quantity:       gain.Quantity,
+			PurchaseDate:   gain.PurchaseDate,
+			SaleDate:       gain.SaleDate,
+			CostBasis:      gain.CostBasis,
+			Proceeds:       gain.Proceeds,
+			Gain:           gain.Gain,
+			LongTerm:       gain.LongTerm,
+			Currency:       gain.Currency,
+			PriceEffect:    gain.PriceEffect,
+			CurrencyEffect: gain.CurrencyEffect,
+			PortfolioID:    gain.PortfolioId,
+		})
 	}
 
-	return candlesticks
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-func generateSMAIndicator(candlesticks []map[string]interface{}, period int) map[string]interface{} {
-	var points []map[string]interface{}
+func (s *PortfolioService) RecordCashEntryHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID      string  `json:"user_id"`
+		Type        string  `json:"type"`
+		Amount      float64 `json:"amount"`
+		Symbol      string  `json:"symbol"`
+		Description string  `json:"description"`
+		Timestamp   int64   `json:"timestamp"`
+		Currency    string  `json:"currency"`
+		PortfolioID string  `json:"portfolio_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
 
-	for i := period - 1; i < len(candlesticks); i++ {
-		sum := 0.0
-		for j := i - period + 1; j <= i; j++ {
-			sum += candlesticks[j]["close"].(float64)
-		}
-		avg := sum / float64(period)
+	entryType, ok := pb.CashEntryType_value[strings.ToUpper(req.Type)]
+	if !ok {
+		http.Error(w, "type must be DEPOSIT, WITHDRAWAL, DIVIDEND, FEE or INTEREST", http.StatusBadRequest)
+		return
+	}
+	entry := &pb.CashEntry{
+		Type:        pb.CashEntryType(entryType),
+		Amount:      req.Amount,
+		Symbol:      req.Symbol,
+		Description: req.Description,
+		Timestamp:   req.Timestamp,
+		Currency:    req.Currency,
+		PortfolioId: req.PortfolioID,
+	}
+	if err := validateCashEntry(entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		point := map[string]interface{}{
-			"time":  candlesticks[i]["time"],
-			"value": math.Round(avg*100) / 100,
-		}
-		points = append(points, point)
+	stored, err := s.recordCashEntry(r.Context(), req.UserID, entry)
+	if errors.Is(err, fx.ErrUnsupportedCurrency) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record cash entry: %v", err)
+		http.Error(w, "Failed to record cash entry", http.StatusInternalServerError)
+		return
+	}
+	cash, err := s.cashAccount(r.Context(), req.UserID, stored.PortfolioId)
+	if err != nil {
+		log.Printf("Failed to load cash for %s: %v", req.UserID, err)
+		http.Error(w, "Failed to load cash", http.StatusInternalServerError)
+		return
 	}
 
-	return map[string]interface{}{
-		"name":   "SMA",
-		"points": points,
+	response := &RecordCashEntryResponse{
+		Success: true,
+		Message: "Cash entry recorded successfully",
+		Entry:   cashEntryFromProto(stored),
+		Cash:    cash.balance,
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
 }
 
-func generateRSIIndicator(candlesticks []map[string]interface{}, period int) map[string]interface{} {
-	var points []map[string]interface{}
+func (s *PortfolioService) GetCashEntriesHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
 
-	if len(candlesticks) < period+1 {
-		return map[string]interface{}{
-			"name":   "RSI",
-			"points": points,
-		}
+	portfolioID := query.Get("portfolio_id")
+	err := s.checkPortfolio(r.Context(), userID, portfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio %s: %v", portfolioID, err)
+		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+		return
 	}
 
-	// Calculate price changes
-	var gains, losses []float64
-	for i := 1; i < len(candlesticks); i++ {
-		change := candlesticks[i]["close"].(float64) - candlesticks[i-1]["close"].(float64)
-		if change > 0 {
-			gains = append(gains, change)
-			losses = append(losses, 0)
-		} else {
-			gains = append(gains, 0)
-			losses = append(losses, -change)
-		}
+	entries, err := s.cashRepo.GetCashEntries(r.Context(), userID, portfolioID)
+	if err != nil {
+		log.Printf("Failed to load cash entries for %s: %v", userID, err)
+		http.Error(w, "Failed to load cash entries", http.StatusInternalServerError)
+		return
+	}
+	cash, err := s.cashAccount(r.Context(), userID, portfolioID)
+	if err != nil {
+		log.Printf("Failed to load cash for %s: %v", userID, err)
+		http.Error(w, "Failed to load cash", http.StatusInternalServerError)
+		return
 	}
 
-	// Calculate initial averages
-	avgGain := 0.0
-	avgLoss := 0.0
-	for i := 0; i < period; i++ {
-		avgGain += gains[i]
-		avgLoss += losses[i]
+	response := &GetCashEntriesResponse{
+		Entries:          make([]*CashEntry, 0, len(entries)),
+		Cash:             cash.balance,
+		TotalIncome:      cash.income,
+		NetContributions: cash.netContributions,
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, cashEntryFromProto(entry))
 	}
-	avgGain /= float64(period)
-	avgLoss /= float64(period)
 
-	// Calculate RSI values
-	for i := period; i < len(candlesticks); i++ {
-		if i > period {
-			avgGain = (avgGain*float64(period-1) + gains[i-1]) / float64(period)
-			avgLoss = (avgLoss*float64(period-1) + losses[i-1]) / float64(period)
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-		rs := avgGain / avgLoss
-		rsi := 100 - (100 / (1 + rs))
+func (s *PortfolioService) DeleteCashEntryHTTP(w http.ResponseWriter, r *http.Request) {
+	entryID := mux.Vars(r)["id"]
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
 
-		point := map[string]interface{}{
-			"time":  candlesticks[i]["time"],
-			"value": math.Round(rsi*100) / 100,
-		}
-		points = append(points, point)
+	err := s.cashRepo.DeleteCashEntry(r.Context(), userID, entryID)
+	if errors.Is(err, repository.ErrCashEntryNotFound) {
+		http.Error(w, "Cash entry not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete cash entry %s: %v", entryID, err)
+		http.Error(w, "Failed to delete cash entry", http.StatusInternalServerError)
+		return
 	}
 
-	return map[string]interface{}{
-		"name":   "RSI",
-		"points": points,
+	response := &RemoveStockResponse{
+		Success: true,
+		Message: "Cash entry deleted successfully",
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-func generateMACDIndicator(candlesticks []map[string]interface{}) map[string]interface{} {
-	var points []map[string]interface{}
+func (s *PortfolioService) CreateCorporateActionHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Symbol          string  `json:"symbol"`
+		Type            string  `json:"type"`
+		ExDate          int64   `json:"ex_date"`
+		SplitTo         float64 `json:"split_to"`
+		SplitFrom       float64 `json:"split_from"`
+		CashInLieuPrice float64 `json:"cash_in_lieu_price"`
+		NewSymbol       string  `json:"new_symbol"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
 
-	if len(candlesticks) < 26 {
-		return map[string]interface{}{
-			"name":   "MACD",
-			"points": points,
-		}
+	actionType, ok := pb.CorporateActionType_value[strings.ToUpper(req.Type)]
+	if !ok {
+		http.Error(w, "type must be SPLIT or SYMBOL_CHANGE", http.StatusBadRequest)
+		return
+	}
+	action := &pb.CorporateAction{
+		Symbol:          req.Symbol,
+		Type:            pb.CorporateActionType(actionType),
+		ExDate:          req.ExDate,
+		SplitTo:         req.SplitTo,
+		SplitFrom:       req.SplitFrom,
+		CashInLieuPrice: req.CashInLieuPrice,
+		NewSymbol:       req.NewSymbol,
+	}
+	if err := validateCorporateAction(action); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.corporate.Schedule(r.Context(), action)
+	if stored != nil && isLedgerConflict(err) {
+		http.Error(w, fmt.Sprintf("Corporate action %s saved but not applied: %v", stored.Id, err), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create corporate action: %v", err)
+		http.Error(w, "Failed to create corporate action", http.StatusInternalServerError)
+		return
 	}
 
-	// Calculate EMA12 and EMA26
-	ema12 := calculateEMA(candlesticks, 12)
-	ema26 := calculateEMA(candlesticks, 26)
+	message := "Corporate action scheduled"
+	if stored.AppliedAt != 0 {
+		message = "Corporate action applied"
+	}
+	response := &CreateCorporateActionResponse{
+		Success: true,
+		Message: message,
+		Action:  corporateActionFromProto(stored),
+	}
 
-	// Calculate MACD line
-	startIdx := 25 // Max of the two periods - 1
-	for i := startIdx; i < len(candlesticks); i++ {
-		if i < len(ema12) && i < len(ema26) {
-			macd := ema12[i] - ema26[i]
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
 
-			point := map[string]interface{}{
-				"time":  candlesticks[i]["time"],
-				"value": math.Round(macd*100) / 100,
-			}
-			points = append(points, point)
+func (s *PortfolioService) GetCorporateActionsHTTP(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol != "" {
+		var err error
+		if symbol, err = validateSymbol(symbol); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 	}
 
-	return map[string]interface{}{
-		"name":   "MACD",
-		"points": points,
+	actions, err := s.corporate.Actions(r.Context(), symbol)
+	if err != nil {
+		log.Printf("Failed to load corporate actions: %v", err)
+		http.Error(w, "Failed to load corporate actions", http.StatusInternalServerError)
+		return
+	}
+
+	response := &GetCorporateActionsResponse{
+		Actions: make([]*CorporateAction, 0, len(actions)),
 	}
+	for _, action := range actions {
+		response.Actions = append(response.Actions, corporateActionFromProto(action))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-func calculateEMA(candlesticks []map[string]interface{}, period int) []float64 {
-	var ema []float64
-	multiplier := 2.0 / (float64(period) + 1.0)
+func (s *PortfolioService) GetPortfoliosHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
 
-	// Calculate initial SMA
-	sum := 0.0
-	for i := 0; i < period; i++ {
-		sum += candlesticks[i]["close"].(float64)
+	portfolios, err := s.portfolios(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load portfolios for %s: %v", userID, err)
+		http.Error(w, "Failed to load portfolios", http.StatusInternalServerError)
+		return
 	}
-	currentEMA := sum / float64(period)
-	ema = append(ema, currentEMA)
 
-	// Calculate subsequent EMA values
-	for i := period; i < len(candlesticks); i++ {
-		currentEMA = (candlesticks[i]["close"].(float64) * multiplier) + (currentEMA * (1 - multiplier))
-		ema = append(ema, currentEMA)
+	response := &GetPortfoliosResponse{Portfolios: make([]*Portfolio, 0, len(portfolios))}
+	for _, portfolio := range portfolios {
+		response.Portfolios = append(response.Portfolios, portfolioFromProto(portfolio))
 	}
 
-	return ema
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) CreatePortfolioHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID      string `json:"user_id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
+
+	portfolio := &pb.Portfolio{Name: req.Name, Description: req.Description}
+	if err := validatePortfolio(portfolio); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.createPortfolio(r.Context(), req.UserID, portfolio)
+	if errors.Is(err, repository.ErrPortfolioNameTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create portfolio: %v", err)
+		http.Error(w, "Failed to create portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	response := &PortfolioResponse{
+		Success:   true,
+		Message:   "Portfolio created successfully",
+		Portfolio: portfolioFromProto(stored),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) UpdatePortfolioHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID      string `json:"user_id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
+
+	portfolio := &pb.Portfolio{Id: mux.Vars(r)["id"], Name: req.Name, Description: req.Description}
+	if err := validatePortfolio(portfolio); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := s.portfolioRepo.UpdatePortfolio(r.Context(), req.UserID, portfolio)
+	switch {
+	case errors.Is(err, repository.ErrPortfolioNotFound):
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrPortfolioNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to update portfolio %s: %v", portfolio.Id, err)
+		http.Error(w, "Failed to update portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	response := &PortfolioResponse{
+		Success:   true,
+		Message:   "Portfolio updated successfully",
+		Portfolio: portfolioFromProto(stored),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) DeletePortfolioHTTP(w http.ResponseWriter, r *http.Request) {
+	portfolioID := mux.Vars(r)["id"]
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
+
+	err := s.deletePortfolio(r.Context(), userID, portfolioID)
+	switch {
+	case errors.Is(err, repository.ErrPortfolioNotFound):
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrDefaultPortfolio) || errors.Is(err, repository.ErrPortfolioNotEmpty):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to delete portfolio %s: %v", portfolioID, err)
+		http.Error(w, "Failed to delete portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	response := &RemoveStockResponse{
+		Success: true,
+		Message: "Portfolio deleted successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) GetUserSettingsHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
+
+	baseCurrency, err := s.userRepo.GetBaseCurrency(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load settings for %s: %v", userID, err)
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&UserSettings{UserID: userID, BaseCurrency: baseCurrency})
+}
+
+func (s *PortfolioService) UpdateUserSettingsHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID       string `json:"user_id"`
+		BaseCurrency string `json:"base_currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
+
+	baseCurrency, err := s.setBaseCurrency(r.Context(), req.UserID, req.BaseCurrency)
+	switch {
+	case errors.Is(err, fx.ErrUnsupportedCurrency) || errors.Is(err, errInvalidCurrency):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, repository.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Failed to update settings for %s: %v", req.UserID, err)
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&UserSettings{UserID: req.UserID, BaseCurrency: baseCurrency})
+}
+
+func (s *PortfolioService) GetAlertsHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		userID = "demo-user-1" // Default for demo
+	}
+
+	portfolioID := query.Get("portfolio_id")
+	err := s.checkPortfolio(r.Context(), userID, portfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio %s: %v", portfolioID, err)
+		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	alerts, err := s.alertRepo.GetUserAlerts(r.Context(), userID, portfolioID)
+	if err != nil {
+		log.Printf("Failed to load alerts for %s: %v", userID, err)
+		http.Error(w, "Failed to load alerts", http.StatusInternalServerError)
+		return
+	}
+
+	response := &GetAlertsResponse{Alerts: alerts}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) SetAlertHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID      string  `json:"user_id"`
+		Symbol      string  `json:"symbol"`
+		TargetPrice float64 `json:"target_price"`
+		Condition   int     `json:"condition"`
+		PortfolioID string  `json:"portfolio_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = "demo-user-1" // Default for demo
+	}
+
+	symbol, err := validateAlertInput(req.Symbol, req.TargetPrice)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	condition := repository.AlertCondition_ABOVE
+	if req.Condition == 1 {
+		condition = repository.AlertCondition_BELOW
+	}
+
+	portfolio, err := s.portfolioFor(r.Context(), req.UserID, req.PortfolioID)
+	if errors.Is(err, repository.ErrPortfolioNotFound) {
+		http.Error(w, "Portfolio not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load portfolio for %s: %v", req.UserID, err)
+		http.Error(w, "Failed to set alert", http.StatusInternalServerError)
+		return
+	}
+
+	alertID, err := s.alertRepo.CreateAlert(r.Context(), req.UserID, portfolio.Id, symbol, req.TargetPrice, condition)
+	if err != nil {
+		log.Printf("Failed to create alert: %v", err)
+		http.Error(w, "Failed to set alert", http.StatusInternalServerError)
+		return
+	}
+	s.reloadAlerts(r.Context(), symbol)
+
+	response := &SetPriceAlertResponse{
+		Success: true,
+		Message: "Alert set successfully",
+		AlertId: alertID,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *PortfolioService) GetChartDataHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	symbol := strings.ToUpper(query.Get("symbol"))
+	if symbol == "" {
+		symbol = "AAPL" // Default symbol
+	}
+
+	var start, end int64
+	var err error
+	if value := query.Get("start"); value != "" {
+		if start, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "start must be a Unix timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("end"); value != "" {
+		if end, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "end must be a Unix timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	simulated, _ := strconv.ParseBool(query.Get("simulated"))
+
+	// e.g. indicators=bb(20,2),rsi(7)
+	selection := query.Get("indicators")
+	if selection == "" {
+		selection = defaultChartIndicators
+	}
+	specs, err := indicators.ParseSpecs(selection)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bars, err := s.priceHistory(r.Context(), symbol, query.Get("interval"), start, end, simulated)
+	if err != nil {
+		http.Error(w, err.Error(), historyHTTPStatus(err))
+		return
+	}
+
+	series := indicators.FromBars(bars)
+	response := map[string]interface{}{
+		"symbol":       symbol,
+		"candlesticks": series,
+		"indicators":   indicators.Compute(series, specs),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Helper functions for portfolio valuation
+
+// portfolioView is what GetPortfolio returns: the summary of one portfolio or, without a
+// portfolioID, the consolidated summary of all the user's portfolios with each one's totals
+func (s *PortfolioService) portfolioView(ctx context.Context, userID, portfolioID string) (*pb.GetPortfolioResponse, []string, error) {
+	if portfolioID != "" {
+		if err := s.checkPortfolio(ctx, userID, portfolioID); err != nil {
+			return nil, nil, err
+		}
+		return s.portfolioSummary(ctx, userID, portfolioID)
+	}
+
+	// Everything is loaded once and split by portfolio in memory for each one's totals
+	data, err := s.loadPortfolioData(ctx, userID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, unpriced, err := s.summarize(ctx, data, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	portfolios, err := s.portfolioRepo.GetPortfolios(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, portfolio := range portfolios {
+		part, _, err := s.summarize(ctx, data.inPortfolio(portfolio.Id), portfolio.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		summary.Portfolios = append(summary.Portfolios, &pb.PortfolioSummary{
+			Portfolio:               portfolio,
+			TotalValue:              part.TotalValue,
+			InvestedValue:           part.InvestedValue,
+			Cash:                    part.Cash,
+			TotalGainLoss:           part.TotalGainLoss,
+			TotalGainLossPercentage: part.TotalGainLossPercentage,
+		})
+	}
+
+	return summary, unpriced, nil
+}
+
+// portfolioData is what a summary is built from: a user's base currency, trades and
+// cash entries in one portfolio or in all of them
+type portfolioData struct {
+	baseCurrency string
+	ledger       []*pb.Transaction
+	entries      []*pb.CashEntry
+}
+
+// loadPortfolioData loads a user's base currency, trades and cash entries in
+// portfolioID, or in every portfolio if it is empty
+func (s *PortfolioService) loadPortfolioData(ctx context.Context, userID, portfolioID string) (*portfolioData, error) {
+	baseCurrency, err := s.userRepo.GetBaseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.stockRepo.GetTransactions(ctx, userID, portfolioID, "")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.cashRepo.GetCashEntries(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &portfolioData{baseCurrency: baseCurrency, ledger: ledger, entries: entries}, nil
+}
+
+// inPortfolio returns the part of d in portfolioID, in the same order
+func (d *portfolioData) inPortfolio(portfolioID string) *portfolioData {
+	part := &portfolioData{baseCurrency: d.baseCurrency}
+	for _, txn := range d.ledger {
+		if txn.PortfolioId == portfolioID {
+			part.ledger = append(part.ledger, txn)
+		}
+	}
+	for _, entry := range d.entries {
+		if entry.PortfolioId == portfolioID {
+			part.entries = append(part.entries, entry)
+		}
+	}
+	return part
+}
+
+// portfolioSummary loads a user's holdings and cash in portfolioID, or in every
+// portfolio if it is empty, and summarizes them
+func (s *PortfolioService) portfolioSummary(ctx context.Context, userID, portfolioID string) (*pb.GetPortfolioResponse, []string, error) {
+	data, err := s.loadPortfolioData(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.summarize(ctx, data, portfolioID)
+}
+
+// summarize values the holdings in data at the latest known prices, with totals in the
+// user's base currency. Holdings without a price yet are valued at cost and their symbols
+// are returned as unpriced.
+func (s *PortfolioService) summarize(ctx context.Context, data *portfolioData, portfolioID string) (*pb.GetPortfolioResponse, []string, error) {
+	stocks, err := repository.PositionsFromLedger(data.ledger)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Positions only carry their average cost; the lots say when each share was bought
+	book, err := costbasis.Match(data.ledger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &pb.GetPortfolioResponse{Stocks: stocks, BaseCurrency: data.baseCurrency, PortfolioId: portfolioID}
+	var unpriced []string
+	totalCost := 0.0
+	now := time.Now()
+	for _, stock := range stocks {
+		if !s.valueStock(ctx, stock) {
+			unpriced = append(unpriced, stock.Symbol)
+		}
+
+		cost, err := s.convertStock(ctx, stock, book.Lots[stock.Symbol], data.baseCurrency, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalCost += cost
+		response.InvestedValue += stock.MarketValue
+		response.PriceEffect += stock.PriceEffect
+		response.CurrencyEffect += stock.CurrencyEffect
+	}
+	response.TotalGainLoss = response.PriceEffect + response.CurrencyEffect
+	response.TotalGainLossPercentage = percentOf(response.TotalGainLoss, totalCost)
+
+	cash, err := s.cashBalance(ctx, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	response.Cash = cash.balance
+	response.TotalIncome = cash.income
+	response.NetContributions = cash.netContributions
+	response.TotalValue = response.InvestedValue + response.Cash
+
+	return response, unpriced, nil
+}
+
+// valueStock fills in a position's current price and gain at the latest known price.
+// Without a price it is valued at cost and false is returned.
+func (s *PortfolioService) valueStock(ctx context.Context, stock *pb.Stock) bool {
+	price, priced := s.currentPrice(ctx, stock.Symbol)
+	if !priced {
+		price = stock.PurchasePrice
+	}
+
+	cost := stock.PurchasePrice * stock.Quantity
+	stock.CurrentPrice = price
+	stock.GainLoss = price*stock.Quantity - cost
+	stock.GainLossPercentage = percentOf(stock.GainLoss, cost)
+
+	return priced
+}
+
+// valuePosition values a single position in a portfolio the way portfolioSummary does,
+// for responses that return the position a trade changed
+func (s *PortfolioService) valuePosition(ctx context.Context, userID, portfolioID string, stock *pb.Stock) bool {
+	priced := s.valueStock(ctx, stock)
+
+	baseCurrency, err := s.userRepo.GetBaseCurrency(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load base currency for %s: %v", userID, err)
+		return priced
+	}
+	ledger, err := s.stockRepo.GetTransactions(ctx, userID, portfolioID, stock.Symbol)
+	if err != nil {
+		log.Printf("Failed to load %s ledger for %s: %v", stock.Symbol, userID, err)
+		return priced
+	}
+	book, err := costbasis.Match(ledger)
+	if err == nil {
+		_, err = s.convertStock(ctx, stock, book.Lots[stock.Symbol], baseCurrency, time.Now())
+	}
+	if err != nil {
+		log.Printf("Failed to convert %s for %s: %v", stock.Symbol, userID, err)
+	}
+
+	return priced
+}
+
+// convertStock fills in a valued position's base-currency fields and returns what its open
+// lots cost in the base currency. Each lot's cost is converted at the rate on its purchase
+// date, so the price effect is the price move at that rate and the currency effect is what
+// the rate's move since then did to today's market value.
+func (s *PortfolioService) convertStock(ctx context.Context, stock *pb.Stock, lots []*costbasis.Lot, baseCurrency string, now time.Time) (float64, error) {
+	rate, err := s.fxSource.Rate(ctx, stock.Currency, baseCurrency, now)
+	if err != nil {
+		return 0, err
+	}
+	stock.FxRate = rate
+	stock.MarketValue = stock.CurrentPrice * stock.Quantity * rate
+
+	cost := 0.0
+	for _, lot := range lots {
+		purchaseRate, err := s.fxSource.Rate(ctx, stock.Currency, baseCurrency, time.Unix(lot.PurchaseDate, 0))
+		if err != nil {
+			return 0, err
+		}
+		lotCost := lot.Quantity * lot.CostPerShare
+		lotValue := lot.Quantity * stock.CurrentPrice
+		cost += lotCost * purchaseRate
+		stock.PriceEffect += (lotValue - lotCost) * purchaseRate
+		stock.CurrencyEffect += lotValue * (rate - purchaseRate)
+	}
+
+	return cost, nil
+}
+
+// recordTransaction adds a validated transaction to the ledger of its portfolio, the
+// user's default one unless it names one, and makes sure the symbol is priced once it is bought
+func (s *PortfolioService) recordTransaction(ctx context.Context, userID string, txn *pb.Transaction) (*pb.Transaction, *pb.Stock, error) {
+	if txn.Timestamp == 0 {
+		txn.Timestamp = time.Now().Unix()
+	}
+	portfolio, err := s.portfolioFor(ctx, userID, txn.PortfolioId)
+	if err != nil {
+		return nil, nil, err
+	}
+	txn.PortfolioId = portfolio.Id
+	if _, err := s.fxSource.Rate(ctx, txn.Currency, fx.USD, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	stored, position, err := s.stockRepo.RecordTransaction(ctx, userID, txn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.priceManager != nil && txn.Type == pb.TransactionType_BUY {
+		s.priceManager.AddSymbol(txn.Symbol, txn.Price)
+	}
+
+	return stored, position, nil
+}
+
+// recordCashEntry stores a validated cash entry, in the user's default portfolio and base
+// currency unless it names others
+func (s *PortfolioService) recordCashEntry(ctx context.Context, userID string, entry *pb.CashEntry) (*pb.CashEntry, error) {
+	portfolio, err := s.portfolioFor(ctx, userID, entry.PortfolioId)
+	if err != nil {
+		return nil, err
+	}
+	entry.PortfolioId = portfolio.Id
+	if entry.Currency == "" {
+		baseCurrency, err := s.userRepo.GetBaseCurrency(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		entry.Currency = baseCurrency
+	}
+	if _, err := s.fxSource.Rate(ctx, entry.Currency, fx.USD, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.cashRepo.RecordCashEntry(ctx, userID, entry)
+}
+
+// portfolioFor returns the user's portfolio portfolioID, or their default portfolio if it
+// is empty. It returns ErrPortfolioNotFound for a portfolio the user doesn't own.
+func (s *PortfolioService) portfolioFor(ctx context.Context, userID, portfolioID string) (*pb.Portfolio, error) {
+	if portfolioID == "" {
+		return s.portfolioRepo.DefaultPortfolio(ctx, userID)
+	}
+	return s.portfolioRepo.GetPortfolio(ctx, userID, portfolioID)
+}
+
+// checkPortfolio returns ErrPortfolioNotFound unless portfolioID, used as a filter, is
+// empty or one of the user's portfolios
+func (s *PortfolioService) checkPortfolio(ctx context.Context, userID, portfolioID string) error {
+	if portfolioID == "" {
+		return nil
+	}
+	_, err := s.portfolioRepo.GetPortfolio(ctx, userID, portfolioID)
+	return err
+}
+
+// portfolios lists the user's portfolios, making sure their default portfolio exists
+func (s *PortfolioService) portfolios(ctx context.Context, userID string) ([]*pb.Portfolio, error) {
+	if _, err := s.portfolioRepo.DefaultPortfolio(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.portfolioRepo.GetPortfolios(ctx, userID)
+}
+
+// createPortfolio stores a validated portfolio. The default portfolio is created first,
+// so a new portfolio can't take its name before it exists.
+func (s *PortfolioService) createPortfolio(ctx context.Context, userID string, portfolio *pb.Portfolio) (*pb.Portfolio, error) {
+	if _, err := s.portfolioRepo.DefaultPortfolio(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.portfolioRepo.CreatePortfolio(ctx, userID, portfolio)
+}
+
+// deletePortfolio deletes an empty portfolio and stops watching the alerts deleted with it
+func (s *PortfolioService) deletePortfolio(ctx context.Context, userID, portfolioID string) error {
+	alerts, err := s.alertRepo.GetUserAlerts(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+	if err := s.portfolioRepo.DeletePortfolio(ctx, userID, portfolioID); err != nil {
+		return err
+	}
+
+	reloaded := make(map[string]bool)
+	for _, alert := range alerts {
+		if !reloaded[alert.Symbol] {
+			s.reloadAlerts(ctx, alert.Symbol)
+			reloaded[alert.Symbol] = true
+		}
+	}
+	return nil
+}
+
+// setBaseCurrency changes the currency the user's totals are reported in and returns it normalized
+func (s *PortfolioService) setBaseCurrency(ctx context.Context, userID, currency string) (string, error) {
+	currency, err := fx.ParseCurrency(currency)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidCurrency, err)
+	}
+	if _, err := s.fxSource.Rate(ctx, currency, fx.USD, time.Now()); err != nil {
+		return "", err
+	}
+	if err := s.userRepo.SetBaseCurrency(ctx, userID, currency); err != nil {
+		return "", err
+	}
+
+	return currency, nil
+}
+
+// realizedGains matches the user's sells in portfolioID, or in every portfolio if it is
+// empty, to lots and reports the gains on sales made in year (UTC), or in the current
+// year if it is zero, with today's unrealized gain. Amounts are in the user's base currency.
+func (s *PortfolioService) realizedGains(ctx context.Context, userID, portfolioID string, year int) (*pb.GetRealizedGainsResponse, error) {
+	if year == 0 {
+		year = time.Now().UTC().Year()
+	}
+
+	data, err := s.loadPortfolioData(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	book, err := costbasis.Match(data.ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	baseCurrency := data.baseCurrency
+	currencies := make(map[string]string)
+	for _, txn := range data.ledger {
+		currencies[txn.Symbol] = txn.Currency
+	}
+
+	report := &pb.GetRealizedGainsResponse{Year: int32(year), BaseCurrency: baseCurrency}
+	for _, gain := range book.Gains {
+		if time.Unix(gain.SaleDate, 0).UTC().Year() != year {
+			continue
+		}
+
+		// Cost converts at the rate on the purchase date and proceeds at the rate on the sale date
+		currency := currencies[gain.Symbol]
+		purchaseRate, err := s.fxSource.Rate(ctx, currency, baseCurrency, time.Unix(gain.PurchaseDate, 0))
+		if err != nil {
+			return nil, err
+		}
+		saleRate, err := s.fxSource.Rate(ctx, currency, baseCurrency, time.Unix(gain.SaleDate, 0))
+		if err != nil {
+			return nil, err
+		}
+		costBasis := gain.CostBasis * purchaseRate
+		proceeds := gain.Proceeds * saleRate
+		amount := proceeds - costBasis
+
+		realized := &pb.RealizedGain{
+			SellId:         gain.SellID,
+			LotId:          gain.LotID,
+			Symbol:         gain.Symbol,
+			Quantity:       gain.Quantity,
+			PurchaseDate:   gain.PurchaseDate,
+			SaleDate:       gain.SaleDate,
+			CostBasis:      costBasis,
+			Proceeds:       proceeds,
+			Gain:           amount,
+			LongTerm:       gain.LongTerm,
+			Currency:       currency,
+			PriceEffect:    gain.Amount() * purchaseRate,
+			CurrencyEffect: gain.Proceeds * (saleRate - purchaseRate),
+			PortfolioId:    gain.PortfolioID,
+		}
+		report.Gains = append(report.Gains, realized)
+		if gain.LongTerm {
+			report.LongTermGain += amount
+		} else {
+			report.ShortTermGain += amount
+		}
+		report.TotalRealizedGain += amount
+		report.TotalProceeds += proceeds
+		report.TotalCostBasis += costBasis
+		report.PriceEffect += realized.PriceEffect
+		report.CurrencyEffect += realized.CurrencyEffect
+	}
+
+	summary, _, err := s.summarize(ctx, data, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	report.UnrealizedGainLoss = summary.TotalGainLoss
+
+	return report, nil
+}
+
+// cashSummary is a user's cash account, derived from cash entries and trades
+type cashSummary struct {
+	balance          float64
+	income           float64 // dividends and interest
+	netContributions float64 // deposits less withdrawals
+}
+
+// cashAccount loads the user's cash entries and trades in portfolioID, or in every
+// portfolio if it is empty, and adds up their cash account
+func (s *PortfolioService) cashAccount(ctx context.Context, userID, portfolioID string) (cashSummary, error) {
+	data, err := s.loadPortfolioData(ctx, userID, portfolioID)
+	if err != nil {
+		return cashSummary{}, err
+	}
+	return s.cashBalance(ctx, data)
+}
+
+// cashBalance adds up the cash entries in data and the cash its trades moved: buys
+// pay price and fees out of cash, and sells pay price less fees into it. The balance
+// goes negative when buys were funded from outside the account. Every amount is
+// converted to the user's base currency at the rate on its date.
+func (s *PortfolioService) cashBalance(ctx context.Context, data *portfolioData) (cashSummary, error) {
+	var cash cashSummary
+
+	convert := func(amount float64, currency string, at int64) (float64, error) {
+		rate, err := s.fxSource.Rate(ctx, currency, data.baseCurrency, time.Unix(at, 0))
+		return amount * rate, err
+	}
+
+	for _, entry := range data.entries {
+		amount, err := convert(entry.Amount, entry.Currency, entry.Timestamp)
+		if err != nil {
+			return cash, err
+		}
+		switch entry.Type {
+		case pb.CashEntryType_DEPOSIT:
+			cash.balance += amount
+			cash.netContributions += amount
+		case pb.CashEntryType_WITHDRAWAL:
+			cash.balance -= amount
+			cash.netContributions -= amount
+		case pb.CashEntryType_DIVIDEND, pb.CashEntryType_INTEREST:
+			cash.balance += amount
+			cash.income += amount
+		case pb.CashEntryType_FEE:
+			cash.balance -= amount
+		}
+	}
+
+	for _, txn := range data.ledger {
+		var flow float64
+		switch txn.Type {
+		case pb.TransactionType_BUY:
+			flow = -(txn.Quantity*txn.Price + txn.Fees)
+		case pb.TransactionType_SELL:
+			flow = txn.Quantity*txn.Price - txn.Fees
+		}
+		amount, err := convert(flow, txn.Currency, txn.Timestamp)
+		if err != nil {
+			return cash, err
+		}
+		cash.balance += amount
+	}
+
+	return cash, nil
+}
+
+// errInvalidCurrency is returned for a currency code that is not ISO 4217 shaped
+var errInvalidCurrency = errors.New("invalid currency")
+
+// isLedgerConflict reports whether a ledger change was refused because it doesn't
+// fit the shares and lots held at the time
+func isLedgerConflict(err error) bool {
+	return errors.Is(err, repository.ErrInsufficientShares) || errors.Is(err, repository.ErrLotUnavailable) ||
+		errors.Is(err, repository.ErrCurrencyMismatch)
+}
+
+// errAmbiguousPortfolio is returned when a removal names a portfolio and asks for all of them
+var errAmbiguousPortfolio = errors.New("portfolio_id and all_portfolios can't both be set")
+
+// removePosition closes the user's position in symbol by selling it at the latest
+// price, or at cost if none is known yet. Like AddStock it works in the default
+// portfolio unless given another; every portfolio only when asked explicitly.
+func (s *PortfolioService) removePosition(ctx context.Context, userID, portfolioID string, allPortfolios bool, symbol string) error {
+	if allPortfolios && portfolioID != "" {
+		return errAmbiguousPortfolio
+	}
+	if !allPortfolios {
+		portfolio, err := s.portfolioFor(ctx, userID, portfolioID)
+		if err != nil {
+			return err
+		}
+		portfolioID = portfolio.Id
+	}
+
+	price, _ := s.currentPrice(ctx, symbol)
+	return s.stockRepo.RemoveStock(ctx, userID, portfolioID, symbol, price)
+}
+
+// currentPrice returns the latest price for symbol, or false if none is known yet
+func (s *PortfolioService) currentPrice(ctx context.Context, symbol string) (float64, bool) {
+	if s.priceManager == nil {
+		return 0, false
+	}
+
+	price, err := s.priceManager.GetCurrentPrice(ctx, symbol)
+	if err != nil {
+		return 0, false
+	}
+
+	return price.CurrentPrice, true
+}
+
+func stockFromProto(stock *pb.Stock, priced bool) *Stock {
+	return &Stock{
+		ID:              stock.Id,
+		Symbol:          stock.Symbol,
+		Name:            stock.Name,
+		Quantity:        stock.Quantity,
+		PurchasePrice:   stock.PurchasePrice,
+		CurrentPrice:    stock.CurrentPrice,
+		GainLoss:        stock.GainLoss,
+		GainLossPercent: stock.GainLossPercentage,
+		PriceAvailable:  priced,
+		Currency:        stock.Currency,
+		FxRate:          stock.FxRate,
+		MarketValue:     stock.MarketValue,
+		PriceEffect:     stock.PriceEffect,
+		CurrencyEffect:  stock.CurrencyEffect,
+	}
+}
+
+func transactionFromProto(txn *pb.Transaction) *Transaction {
+	return &Transaction{
+		ID:          txn.Id,
+		Symbol:      txn.Symbol,
+		Name:        txn.Name,
+		Type:        txn.Type.String(),
+		Quantity:    txn.Quantity,
+		Price:       txn.Price,
+		Fees:        txn.Fees,
+		Timestamp:   txn.Timestamp,
+		LotMethod:   txn.LotMethod.String(),
+		LotID:       txn.LotId,
+		Currency:    txn.Currency,
+		PortfolioID: txn.PortfolioId,
+	}
+}
+
+func cashEntryFromProto(entry *pb.CashEntry) *CashEntry {
+	return &CashEntry{
+		ID:          entry.Id,
+		Type:        entry.Type.String(),
+		Amount:      entry.Amount,
+		Symbol:      entry.Symbol,
+		Description: entry.Description,
+		Timestamp:   entry.Timestamp,
+		Currency:    entry.Currency,
+		PortfolioID: entry.PortfolioId,
+	}
+}
+
+func portfolioFromProto(portfolio *pb.Portfolio) *Portfolio {
+	return &Portfolio{
+		ID:          portfolio.Id,
+		Name:        portfolio.Name,
+		Description: portfolio.Description,
+		IsDefault:   portfolio.IsDefault,
+		CreatedAt:   portfolio.CreatedAt,
+	}
+}
+
+func corporateActionFromProto(action *pb.CorporateAction) *CorporateAction {
+	return &CorporateAction{
+		ID:                   action.Id,
+		Symbol:               action.Symbol,
+		Type:                 action.Type.String(),
+		ExDate:               action.ExDate,
+		SplitTo:              action.SplitTo,
+		SplitFrom:            action.SplitFrom,
+		CashInLieuPrice:      action.CashInLieuPrice,
+		NewSymbol:            action.NewSymbol,
+		AppliedAt:            action.AppliedAt,
+		TransactionsAdjusted: action.TransactionsAdjusted,
+		AlertsAdjusted:       action.AlertsAdjusted,
+		HistoryAdjusted:      action.HistoryAdjusted,
+		CashInLieuSales:      action.CashInLieuSales,
+	}
+}
+
+// validateCorporateAction checks a new corporate action, normalizes its symbols and
+// defaults its ex-date to now
+func validateCorporateAction(action *pb.CorporateAction) error {
+	symbol, err := validateSymbol(action.Symbol)
+	if err != nil {
+		return err
+	}
+	action.Symbol = symbol
+
+	switch action.Type {
+	case pb.CorporateActionType_SPLIT:
+		for _, v := range []float64{action.SplitTo, action.SplitFrom} {
+			if v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+				return errors.New("split_to and split_from must be positive numbers")
+			}
+		}
+		if action.SplitTo == action.SplitFrom {
+			return errors.New("split_to and split_from must differ")
+		}
+		if action.CashInLieuPrice < 0 || math.IsInf(action.CashInLieuPrice, 0) || math.IsNaN(action.CashInLieuPrice) {
+			return errors.New("cash_in_lieu_price must not be negative")
+		}
+		if action.NewSymbol != "" {
+			return errors.New("new_symbol only applies to SYMBOL_CHANGE")
+		}
+	case pb.CorporateActionType_SYMBOL_CHANGE:
+		newSymbol, err := validateSymbol(action.NewSymbol)
+		if err != nil {
+			return fmt.Errorf("new_symbol: %w", err)
+		}
+		if newSymbol == symbol {
+			return errors.New("new_symbol must differ from symbol")
+		}
+		action.NewSymbol = newSymbol
+		if action.SplitTo != 0 || action.SplitFrom != 0 || action.CashInLieuPrice != 0 {
+			return errors.New("split_to, split_from and cash_in_lieu_price only apply to SPLIT")
+		}
+	default:
+		return errors.New("type must be SPLIT or SYMBOL_CHANGE")
+	}
+
+	if action.ExDate == 0 {
+		action.ExDate = time.Now().Unix()
+	}
+
+	return nil
+}
+
+// validatePortfolio checks a portfolio's name and description and trims them
+func validatePortfolio(portfolio *pb.Portfolio) error {
+	portfolio.Name = strings.TrimSpace(portfolio.Name)
+	if portfolio.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(portfolio.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	portfolio.Description = strings.TrimSpace(portfolio.Description)
+	if len(portfolio.Description) > 255 {
+		return errors.New("description must be at most 255 characters")
+	}
+
+	return nil
+}
+
+// validateCashEntry checks a new cash entry, normalizes its symbol and defaults its timestamp to now
+func validateCashEntry(entry *pb.CashEntry) error {
+	if _, ok := pb.CashEntryType_name[int32(entry.Type)]; !ok {
+		return errors.New("type must be DEPOSIT, WITHDRAWAL, DIVIDEND, FEE or INTEREST")
+	}
+	if entry.Amount <= 0 || math.IsInf(entry.Amount, 0) || math.IsNaN(entry.Amount) {
+		return errors.New("amount must be a positive number")
+	}
+	if entry.Symbol != "" {
+		symbol, err := validateSymbol(entry.Symbol)
+		if err != nil {
+			return err
+		}
+		entry.Symbol = symbol
+	}
+	entry.Description = strings.TrimSpace(entry.Description)
+	if len(entry.Description) > 255 {
+		return errors.New("description must be at most 255 characters")
+	}
+	if entry.Currency != "" {
+		currency, err := fx.ParseCurrency(entry.Currency)
+		if err != nil {
+			return err
+		}
+		entry.Currency = currency
+	}
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().Unix()
+	}
+
+	return nil
+}
+
+// validateTransaction checks a new ledger entry and normalizes its symbol
+func validateTransaction(txn *pb.Transaction) error {
+	symbol, err := validateSymbol(txn.Symbol)
+	if err != nil {
+		return err
+	}
+	if txn.Quantity <= 0 || math.IsInf(txn.Quantity, 0) || math.IsNaN(txn.Quantity) {
+		return errors.New("quantity must be a positive number")
+	}
+	if txn.Price <= 0 || math.IsInf(txn.Price, 0) || math.IsNaN(txn.Price) {
+		return errors.New("price must be a positive number")
+	}
+	if _, ok := pb.TransactionType_name[int32(txn.Type)]; !ok {
+		return errors.New("type must be BUY or SELL")
+	}
+	if _, ok := pb.LotMethod_name[int32(txn.LotMethod)]; !ok {
+		return errors.New("lot_method must be FIFO, LIFO, HIFO or SPECIFIC_LOT")
+	}
+	switch {
+	case txn.Type == pb.TransactionType_BUY && (txn.LotMethod != pb.LotMethod_FIFO || txn.LotId != ""):
+		return errors.New("lot_method and lot_id only apply to sells")
+	case txn.LotMethod == pb.LotMethod_SPECIFIC_LOT && txn.LotId == "":
+		return errors.New("lot_id is required with SPECIFIC_LOT")
+	case txn.LotMethod != pb.LotMethod_SPECIFIC_LOT && txn.LotId != "":
+		return errors.New("lot_id requires lot_method SPECIFIC_LOT")
+	}
+	if txn.Fees < 0 || math.IsInf(txn.Fees, 0) || math.IsNaN(txn.Fees) {
+		return errors.New("fees must not be negative")
+	}
+	if txn.Currency == "" {
+		txn.Currency = fx.ListingCurrency(symbol)
+	} else {
+		currency, err := fx.ParseCurrency(txn.Currency)
+		if err != nil {
+			return err
+		}
+		txn.Currency = currency
+	}
+
+	txn.Symbol = symbol
+	return nil
+}
+
+// validateStockInput checks a new position and returns its normalized symbol
+func validateStockInput(symbol string, quantity, purchasePrice float64) (string, error) {
+	symbol, err := validateSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	if quantity <= 0 || math.IsInf(quantity, 0) || math.IsNaN(quantity) {
+		return "", errors.New("quantity must be a positive number")
+	}
+	if purchasePrice <= 0 || math.IsInf(purchasePrice, 0) || math.IsNaN(purchasePrice) {
+		return "", errors.New("purchase_price must be a positive number")
+	}
+
+	return symbol, nil
+}
+
+// validateAlertInput checks a new price alert and returns its normalized symbol
+func validateAlertInput(symbol string, targetPrice float64) (string, error) {
+	symbol, err := validateSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	if targetPrice <= 0 || math.IsInf(targetPrice, 0) || math.IsNaN(targetPrice) {
+		return "", errors.New("target_price must be a positive number")
+	}
+
+	return symbol, nil
+}
+
+// validateSymbol returns symbol trimmed and upper-cased, or why it is invalid
+func validateSymbol(symbol string) (string, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return "", errors.New("symbol is required")
+	}
+	if len(symbol) > 10 {
+		return "", errors.New("symbol must be at most 10 characters")
+	}
+	for _, c := range symbol {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '.' && c != '-' {
+			return "", fmt.Errorf("invalid character %q in symbol", c)
+		}
+	}
+
+	return symbol, nil
+}
+
+func percentOf(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
+
+// reloadAlerts makes the alert engine pick up a newly created alert
+func (s *PortfolioService) reloadAlerts(ctx context.Context, symbol string) {
+	if s.alertEngine == nil {
+		return
+	}
+	if err := s.alertEngine.Reload(ctx, symbol); err != nil {
+		log.Printf("Failed to reload alerts for %s: %v", symbol, err)
+	}
+}
+
+// Helper functions for price history
+
+const (
+	// defaultHistoryBars is how many bars are returned when no start time is given
+	defaultHistoryBars = 100
+	// maxHistoryBars caps how many bars one request can ask for
+	maxHistoryBars = 5000
+	// defaultChartIndicators is computed when a chart request names no indicators
+	defaultChartIndicators = "sma,rsi,macd"
+)
+
+var (
+	errInvalidHistoryRequest = errors.New("invalid history request")
+	errUnknownSymbol         = errors.New("unknown symbol")
+	errNoHistory             = errors.New("no price history")
+)
+
+// priceHistory returns a symbol's bars of interval (default 1d) for [start, end).
+// A zero end means the latest stored bar and a zero start the 100 bars before end.
+// Simulated history is generated by the market simulator instead of read from storage.
+func (s *PortfolioService) priceHistory(ctx context.Context, symbol, intervalName string, start, end int64, simulated bool) ([]*pb.HistoricalPrice, error) {
+	if intervalName == "" {
+		intervalName = string(history.Day)
+	}
+	interval, err := history.ParseInterval(intervalName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidHistoryRequest, err)
+	}
+
+	latestBar, err := s.historyRepo.LatestBar(ctx, symbol, string(interval))
+	if err != nil {
+		return nil, err
+	}
+	var latestPrice *pb.PriceUpdate
+	if s.priceManager != nil {
+		latestPrice, _ = s.priceManager.GetCurrentPrice(ctx, symbol)
+	}
+	if latestBar == nil && latestPrice == nil {
+		return nil, fmt.Errorf("%w: %s", errUnknownSymbol, symbol)
+	}
+
+	if end == 0 {
+		end = time.Now().Unix()
+		if latestBar != nil && !simulated {
+			end = latestBar.Timestamp + interval.Seconds()
+		}
+	}
+	if start == 0 {
+		start = end - defaultHistoryBars*interval.Seconds()
+	}
+	if start >= end {
+		return nil, fmt.Errorf("%w: start must be before end", errInvalidHistoryRequest)
+	}
+	if (end-start)/interval.Seconds() > maxHistoryBars {
+		return nil, fmt.Errorf("%w: range covers more than %d %s bars", errInvalidHistoryRequest, maxHistoryBars, interval)
+	}
+
+	var bars []*pb.HistoricalPrice
+	if simulated {
+		basePrice := 0.0
+		if latestPrice != nil {
+			basePrice = latestPrice.CurrentPrice
+		} else {
+			basePrice = latestBar.Close
+		}
+		bars = history.Simulate(symbol, basePrice, interval, start, end)
+	} else {
+		bars, err = s.historyRepo.GetBars(ctx, symbol, string(interval), start, end)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("%w for %s between %s and %s", errNoHistory, symbol,
+			time.Unix(start, 0).UTC().Format(time.RFC3339), time.Unix(end, 0).UTC().Format(time.RFC3339))
+	}
+
+	return bars, nil
+}
+
+// historyHTTPStatus maps a priceHistory error to an HTTP status code
+func historyHTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidHistoryRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errUnknownSymbol), errors.Is(err, errNoHistory):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/costbasis"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/fx"
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/repository"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const tolerance = 1e-9
+
+var (
+	january = time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	march   = time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	june    = time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+)
+
+// eurRates quotes EUR in USD at 1.00 from January, 1.20 from March and 1.25 from June on
+type eurRates struct{}
+
+func (eurRates) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if from != "EUR" || to != fx.USD {
+		return 0, fx.ErrUnsupportedCurrency
+	}
+	switch {
+	case at.Before(march):
+		return 1, nil
+	case at.Before(june):
+		return 1.2, nil
+	default:
+		return 1.25, nil
+	}
+}
+
+// newTestService returns a service over empty memory stores with no price feed, so
+// every position is valued at cost
+func newTestService() *PortfolioService {
+	stocks := repository.NewMemoryStockRepository()
+	alerts := repository.NewMemoryAlertRepository()
+	cash := repository.NewMemoryCashRepository()
+	return NewPortfolioService(stocks, alerts, cash, repository.NewMemoryPortfolioRepository(stocks, alerts, cash),
+		repository.NewMemoryUserRepository(), repository.NewMemoryPriceHistoryRepository(), nil, eurRates{}, nil, nil)
+}
+
+func TestConvertStockSplitsPriceAndCurrencyEffects(t *testing.T) {
+	s := newTestService()
+	stock := &pb.Stock{Symbol: "SAP.DE", Currency: "EUR", Quantity: 15, CurrentPrice: 120}
+	lots := []*costbasis.Lot{
+		{Quantity: 10, CostPerShare: 100, PurchaseDate: january.Unix()},
+		{Quantity: 5, CostPerShare: 110, PurchaseDate: march.Unix()},
+	}
+
+	cost, err := s.convertStock(context.Background(), stock, lots, fx.USD, june)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each lot's cost at its purchase rate: 1000 at 1.00 and 550 at 1.20
+	if math.Abs(cost-1660) > tolerance || math.Abs(stock.MarketValue-2250) > tolerance || stock.FxRate != 1.25 {
+		t.Errorf("cost %.2f and value %.2f at %g, want 1660.00 and 2250.00 at 1.25", cost, stock.MarketValue, stock.FxRate)
+	}
+	// The price moves of 200 and 50 at the purchase rates, then the rate's move on
+	// today's values of 1200 and 600
+	if math.Abs(stock.PriceEffect-260) > tolerance || math.Abs(stock.CurrencyEffect-330) > tolerance {
+		t.Errorf("price effect %.2f and currency effect %.2f, want 260.00 and 330.00", stock.PriceEffect, stock.CurrencyEffect)
+	}
+	if total := stock.PriceEffect + stock.CurrencyEffect; math.Abs(total-(stock.MarketValue-cost)) > tolerance {
+		t.Errorf("effects add up to %.2f, want the gain of %.2f", total, stock.MarketValue-cost)
+	}
+}
+
+func TestRealizedGainsSplitsPriceAndCurrencyEffects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+	for _, txn := range []*pb.Transaction{
+		{Symbol: "SAP.DE", Type: pb.TransactionType_BUY, Quantity: 10, Price: 100, Currency: "EUR", Timestamp: january.Unix()},
+		{Symbol: "SAP.DE", Type: pb.TransactionType_SELL, Quantity: 4, Price: 130, Currency: "EUR", Timestamp: june.Unix()},
+	} {
+		if _, _, err := s.recordTransaction(ctx, "alice", txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := s.realizedGains(ctx, "alice", "", 2024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Gains) != 1 {
+		t.Fatalf("got %d gains, want 1", len(report.Gains))
+	}
+
+	// 400 of cost at 1.00 sold for 520 at 1.25: 120 of the gain is the price rise at
+	// the purchase rate and 130 the rate's rise on the proceeds
+	gain := report.Gains[0]
+	if math.Abs(gain.CostBasis-400) > tolerance || math.Abs(gain.Proceeds-650) > tolerance || math.Abs(gain.Gain-250) > tolerance {
+		t.Errorf("cost %.2f, proceeds %.2f, gain %.2f, want 400.00, 650.00 and 250.00", gain.CostBasis, gain.Proceeds, gain.Gain)
+	}
+	if math.Abs(gain.PriceEffect-120) > tolerance || math.Abs(gain.CurrencyEffect-130) > tolerance {
+		t.Errorf("price effect %.2f and currency effect %.2f, want 120.00 and 130.00", gain.PriceEffect, gain.CurrencyEffect)
+	}
+	if math.Abs(report.PriceEffect+report.CurrencyEffect-report.TotalRealizedGain) > tolerance {
+		t.Errorf("effects %.2f and %.2f don't add up to %.2f", report.PriceEffect, report.CurrencyEffect, report.TotalRealizedGain)
+	}
+
+	// The 6 shares left are valued at cost with no feed, so all of their gain is the rate's move
+	if math.Abs(report.UnrealizedGainLoss-150) > tolerance {
+		t.Errorf("unrealized %.2f, want 150.00", report.UnrealizedGainLoss)
+	}
+
+	if report, err := s.realizedGains(ctx, "alice", "", 2023); err != nil || len(report.Gains) != 0 {
+		t.Errorf("2023 report %v, %v, want no gains", report, err)
+	}
+}
+
+// countingStocks is a StockStore that counts how often the ledger is loaded
+type countingStocks struct {
+	repository.StockStore
+	loads int
+}
+
+func (c *countingStocks) GetTransactions(ctx context.Context, userID, portfolioID, symbol string) ([]*pb.Transaction, error) {
+	c.loads++
+	return c.StockStore.GetTransactions(ctx, userID, portfolioID, symbol)
+}
+
+func TestConsolidatedViewLoadsTheLedgerOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService()
+	retirement, err := s.portfolioRepo.CreatePortfolio(ctx, "alice", &pb.Portfolio{Name: "Retirement"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range []*pb.Transaction{
+		{Symbol: "AAPL", Type: pb.TransactionType_BUY, Quantity: 10, Price: 100, Currency: fx.USD, Timestamp: january.Unix()},
+		{Symbol: "SAP.DE", Type: pb.TransactionType_BUY, Quantity: 10, Price: 100, Currency: "EUR", Timestamp: january.Unix(), PortfolioId: retirement.Id},
+		{Symbol: "AAPL", Type: pb.TransactionType_BUY, Quantity: 5, Price: 120, Currency: fx.USD, Timestamp: march.Unix(), PortfolioId: retirement.Id},
+	} {
+		if _, _, err := s.recordTransaction(ctx, "alice", txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.cashRepo.RecordCashEntry(ctx, "alice", &pb.CashEntry{
+		Type: pb.CashEntryType_DEPOSIT, Amount: 3000, Currency: fx.USD, Timestamp: january.Unix(), PortfolioId: retirement.Id,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stocks := &countingStocks{StockStore: s.stockRepo}
+	s.stockRepo = stocks
+	view, _, err := s.portfolioView(ctx, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stocks.loads != 1 {
+		t.Errorf("loaded the ledger %d times, want once", stocks.loads)
+	}
+
+	// Each portfolio's totals are the ones its own summary reports
+	if len(view.Portfolios) != 2 {
+		t.Fatalf("got %d portfolios, want 2", len(view.Portfolios))
+	}
+	for _, part := range view.Portfolios {
+		want, _, err := s.portfolioSummary(ctx, "alice", part.Portfolio.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(part.TotalValue-want.TotalValue) > tolerance || math.Abs(part.Cash-want.Cash) > tolerance ||
+			math.Abs(part.TotalGainLoss-want.TotalGainLoss) > tolerance {
+			t.Errorf("%s: value %.2f, cash %.2f, gain %.2f, want %.2f, %.2f and %.2f", part.Portfolio.Name,
+				part.TotalValue, part.Cash, part.TotalGainLoss, want.TotalValue, want.Cash, want.TotalGainLoss)
+		}
+	}
+	// SAP.DE bought at 1.00 is worth 1.25 today, so the retirement portfolio is up 250
+	if retirement := view.Portfolios[1]; math.Abs(retirement.TotalGainLoss-250) > tolerance || math.Abs(retirement.Cash-1400) > tolerance {
+		t.Errorf("retirement gain %.2f and cash %.2f, want 250.00 and 1400.00", retirement.TotalGainLoss, retirement.Cash)
+	}
+}
+
+// failingAlerts is an AlertStore that can't store alerts
+type failingAlerts struct {
+	repository.AlertStore
+}
+
+func (failingAlerts) CreateAlert(ctx context.Context, userID, portfolioID, symbol string, targetPrice float64, condition repository.AlertCondition) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestSetAlertHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		alerts repository.AlertStore
+		body   string
+		want   int
+	}{
+		{"stored", repository.NewMemoryAlertRepository(), `{"user_id":"alice","symbol":"aapl","target_price":200}`, http.StatusOK},
+		{"invalid", repository.NewMemoryAlertRepository(), `{"user_id":"alice","symbol":"aapl","target_price":-1}`, http.StatusBadRequest},
+		{"unknown portfolio", repository.NewMemoryAlertRepository(), `{"user_id":"alice","symbol":"aapl","target_price":200,"portfolio_id":"missing"}`, http.StatusNotFound},
+		{"store error", failingAlerts{}, `{"user_id":"alice","symbol":"aapl","target_price":200}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			s.alertRepo = tt.alerts
+
+			w := httptest.NewRecorder()
+			s.SetAlertHTTP(w, httptest.NewRequest(http.MethodPost, "/api/alerts", strings.NewReader(tt.body)))
+			if w.Code != tt.want {
+				t.Errorf("got %d %q, want %d", w.Code, w.Body.String(), tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var response SetPriceAlertResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil || !response.Success || response.AlertId == "" {
+				t.Errorf("got %+v, %v, want the new alert's ID", response, err)
+			}
+		})
+	}
+}
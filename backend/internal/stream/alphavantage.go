@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const (
+	// AlphaVantageURL is the production Alpha Vantage endpoint
+	AlphaVantageURL = "https://www.alphavantage.co"
+	// AlphaVantageFreeRequestsPerMinute is the free tier quota
+	AlphaVantageFreeRequestsPerMinute = 5
+)
+
+// AlphaVantageClient fetches quotes from the GLOBAL_QUOTE endpoint
+type AlphaVantageClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewAlphaVantageClient creates a client; baseURL may point at a stub such as quotestub
+func NewAlphaVantageClient(apiKey, baseURL string) *AlphaVantageClient {
+	if baseURL == "" {
+		baseURL = AlphaVantageURL
+	}
+
+	return &AlphaVantageClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *AlphaVantageClient) Name() string {
+	return "Alpha Vantage"
+}
+
+func (c *AlphaVantageClient) Quote(ctx context.Context, symbol string) (*pb.PriceUpdate, error) {
+	params := url.Values{
+		"function": {"GLOBAL_QUOTE"},
+		"symbol":   {symbol},
+		"apikey":   {c.apiKey},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("alpha vantage request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("alpha vantage returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Quote       map[string]string `json:"Global Quote"`
+		Note        string            `json:"Note"`
+		Information string            `json:"Information"`
+		Error       string            `json:"Error Message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode alpha vantage quote: %w", err)
+	}
+
+	// Quota problems come back as 200 with a Note or Information message
+	if body.Note != "" || body.Information != "" {
+		return nil, ErrRateLimited
+	}
+	if body.Error != "" {
+		return nil, fmt.Errorf("alpha vantage error: %s", body.Error)
+	}
+	if len(body.Quote) == 0 {
+		return nil, fmt.Errorf("alpha vantage has no quote for %s", symbol)
+	}
+
+	return parseAlphaVantageQuote(body.Quote)
+}
+
+// parseAlphaVantageQuote maps the numbered GLOBAL_QUOTE fields onto a PriceUpdate
+func parseAlphaVantageQuote(quote map[string]string) (*pb.PriceUpdate, error) {
+	var err error
+	number := func(key string) float64 {
+		if err != nil {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(strings.TrimSuffix(quote[key], "%"), 64)
+		if err != nil {
+			err = fmt.Errorf("invalid %q in alpha vantage quote: %w", key, err)
+		}
+		return v
+	}
+
+	update := &pb.PriceUpdate{
+		Symbol:           quote["01. symbol"],
+		DayOpen:          number("02. open"),
+		DayHigh:          number("03. high"),
+		DayLow:           number("04. low"),
+		CurrentPrice:     number("05. price"),
+		Volume:           number("06. volume"),
+		Change:           number("09. change"),
+		ChangePercentage: number("10. change percent"),
+		Timestamp:        time.Now().Unix(),
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return update, nil
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const (
+	// FinnhubURL is the production Finnhub endpoint
+	FinnhubURL = "https://finnhub.io"
+	// FinnhubFreeRequestsPerMinute is the free tier quota
+	FinnhubFreeRequestsPerMinute = 60
+)
+
+// FinnhubClient fetches quotes from the /api/v1/quote endpoint
+type FinnhubClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewFinnhubClient creates a client; baseURL may point at a stub such as quotestub
+func NewFinnhubClient(apiKey, baseURL string) *FinnhubClient {
+	if baseURL == "" {
+		baseURL = FinnhubURL
+	}
+
+	return &FinnhubClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *FinnhubClient) Name() string {
+	return "Finnhub"
+}
+
+func (c *FinnhubClient) Quote(ctx context.Context, symbol string) (*pb.PriceUpdate, error) {
+	params := url.Values{
+		"symbol": {symbol},
+		"token":  {c.apiKey},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/quote?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("finnhub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("finnhub returned status %d", resp.StatusCode)
+	}
+
+	var quote struct {
+		Current       float64 `json:"c"`
+		Change        float64 `json:"d"`
+		ChangePercent float64 `json:"dp"`
+		High          float64 `json:"h"`
+		Low           float64 `json:"l"`
+		Open          float64 `json:"o"`
+		PreviousClose float64 `json:"pc"`
+		Timestamp     int64   `json:"t"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		return nil, fmt.Errorf("failed to decode finnhub quote: %w", err)
+	}
+
+	// Unknown symbols come back as an all-zero quote
+	if quote.Current == 0 && quote.Timestamp == 0 {
+		return nil, fmt.Errorf("finnhub has no quote for %s", symbol)
+	}
+
+	timestamp := quote.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().Unix()
+	}
+
+	// Finnhub's quote endpoint does not report volume
+	return &pb.PriceUpdate{
+		Symbol:           symbol,
+		CurrentPrice:     quote.Current,
+		Change:           quote.Change,
+		ChangePercentage: quote.ChangePercent,
+		Timestamp:        timestamp,
+		DayHigh:          quote.High,
+		DayLow:           quote.Low,
+		DayOpen:          quote.Open,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package stream
+
+import (
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// historySize is how many recent updates are kept per symbol for resuming subscribers
+const historySize = 256
+
+// symbolHistory numbers a symbol's updates and remembers the most recent ones
+type symbolHistory struct {
+	seq  uint64
+	ring []*pb.PriceUpdate
+	head int // index of the oldest update once the ring is full
+}
+
+// record assigns update its sequence number and stores it. Updates that arrive
+// already numbered (from a RedisProducer) keep their number, and ones at or
+// below the last seen number are duplicates and rejected.
+func (h *symbolHistory) record(update *pb.PriceUpdate) bool {
+	if update.Sequence == 0 {
+		update.Sequence = h.seq + 1
+	} else if update.Sequence <= h.seq {
+		return false
+	}
+	h.seq = update.Sequence
+
+	if len(h.ring) < historySize {
+		h.ring = append(h.ring, update)
+	} else {
+		h.ring[h.head] = update
+		h.head = (h.head + 1) % historySize
+	}
+
+	return true
+}
+
+// since returns the updates after seq in order. ok is false if some of them
+// have already been evicted, in which case the caller needs a fresh snapshot.
+func (h *symbolHistory) since(seq uint64) (updates []*pb.PriceUpdate, ok bool) {
+	if seq == h.seq {
+		return nil, true
+	}
+	if seq > h.seq {
+		// The subscriber saw numbers from before a restart
+		return nil, false
+	}
+
+	for i := range h.ring {
+		update := h.ring[(h.head+i)%len(h.ring)]
+		if update.Sequence > seq {
+			updates = append(updates, update)
+		}
+	}
+
+	return updates, len(updates) > 0 && updates[0].Sequence == seq+1
+}
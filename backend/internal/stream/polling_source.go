@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// ErrRateLimited is returned by a QuoteClient when the vendor rejects a request for exceeding its quota
+var ErrRateLimited = errors.New("quote provider rate limit exceeded")
+
+// QuoteClient fetches a single quote from a market data vendor
+type QuoteClient interface {
+	Name() string
+	Quote(ctx context.Context, symbol string) (*pb.PriceUpdate, error)
+}
+
+const (
+	minRetryDelay  = 30 * time.Second
+	maxRetryDelay  = 10 * time.Minute
+	rateLimitPause = time.Minute
+)
+
+// PollingSource turns a request/response QuoteClient into a PriceSource. It polls
+// one symbol at a time, round-robin, never exceeding requestsPerMinute. Symbols
+// whose quotes fail are retried with exponential backoff while the rest keep updating.
+type PollingSource struct {
+	client   QuoteClient
+	interval time.Duration
+
+	mu      sync.Mutex
+	symbols []string
+	retry   map[string]time.Time     // symbol -> earliest next attempt
+	delay   map[string]time.Duration // symbol -> current backoff
+	next    int
+}
+
+func NewPollingSource(client QuoteClient, requestsPerMinute int, symbols []string) *PollingSource {
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = 1
+	}
+
+	s := &PollingSource{
+		client:   client,
+		interval: time.Minute / time.Duration(requestsPerMinute),
+		retry:    make(map[string]time.Time),
+		delay:    make(map[string]time.Duration),
+	}
+	for _, symbol := range symbols {
+		s.AddSymbol(symbol, 0)
+	}
+
+	return s
+}
+
+// Run polls the vendor until ctx is done
+func (s *PollingSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	log.Printf("📡 %s price source started (one request every %s)", s.client.Name(), s.interval)
+
+	for {
+		if symbol, ok := s.nextSymbol(time.Now()); ok {
+			update, err := s.client.Quote(ctx, symbol)
+			switch {
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case errors.Is(err, ErrRateLimited):
+				log.Printf("⚠️  %s rate limit hit, pausing for %s", s.client.Name(), rateLimitPause)
+				if err := sleepContext(ctx, rateLimitPause); err != nil {
+					return err
+				}
+			case err != nil:
+				delay := s.backoff(symbol)
+				log.Printf("⚠️  %s quote for %s failed, retrying in %s: %v", s.client.Name(), symbol, delay, err)
+			default:
+				s.recovered(symbol)
+				if err := sendAll(ctx, out, []*pb.PriceUpdate{update}); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// AddSymbol adds symbol to the polling rotation; basePrice is ignored because the vendor supplies prices
+func (s *PollingSource) AddSymbol(symbol string, basePrice float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.symbols {
+		if existing == symbol {
+			return
+		}
+	}
+	s.symbols = append(s.symbols, symbol)
+	sort.Strings(s.symbols)
+}
+
+// RemoveSymbol takes symbol out of the polling rotation
+func (s *PollingSource) RemoveSymbol(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.Index(s.symbols, symbol); i >= 0 {
+		s.symbols = slices.Delete(s.symbols, i, i+1)
+	}
+	delete(s.retry, symbol)
+	delete(s.delay, symbol)
+}
+
+// nextSymbol returns the next symbol in the rotation that is not backing off
+func (s *PollingSource) nextSymbol(now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for range s.symbols {
+		symbol := s.symbols[s.next%len(s.symbols)]
+		s.next = (s.next + 1) % len(s.symbols)
+		if now.After(s.retry[symbol]) {
+			return symbol, true
+		}
+	}
+
+	return "", false
+}
+
+func (s *PollingSource) backoff(symbol string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delay := s.delay[symbol] * 2
+	if delay < minRetryDelay {
+		delay = minRetryDelay
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	s.delay[symbol] = delay
+	s.retry[symbol] = time.Now().Add(delay)
+
+	return delay
+}
+
+func (s *PollingSource) recovered(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.delay, symbol)
+	delete(s.retry, symbol)
+}
@@ -0,0 +1,216 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chinnareddy578/realtime-portfolio-tracker/backend/internal/quotestub"
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// stubVendor serves quotestub and counts the requests it gets for each symbol
+type stubVendor struct {
+	*quotestub.Server
+	url string
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newStubVendor(t *testing.T) *stubVendor {
+	v := &stubVendor{Server: quotestub.NewServer(nil), requests: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v.mu.Lock()
+		v.requests[r.URL.Query().Get("symbol")]++
+		v.mu.Unlock()
+		v.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	v.url = server.URL
+	return v
+}
+
+func (v *stubVendor) count(symbol string) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.requests[symbol]
+}
+
+func (v *stubVendor) total() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	n := 0
+	for _, count := range v.requests {
+		n += count
+	}
+	return n
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-3
+}
+
+func TestAlphaVantageQuote(t *testing.T) {
+	vendor := newStubVendor(t)
+	client := NewAlphaVantageClient("test-key", vendor.url)
+	want := quotestub.DefaultQuotes["AAPL"]
+
+	update, err := client.Quote(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Symbol != "AAPL" || !approxEqual(update.CurrentPrice, want.Price) ||
+		!approxEqual(update.DayOpen, want.Open) || !approxEqual(update.DayHigh, want.High) ||
+		!approxEqual(update.DayLow, want.Low) || update.Volume != want.Volume {
+		t.Errorf("got %+v, want %+v", update, want)
+	}
+	change := want.Price - want.PreviousClose
+	if !approxEqual(update.Change, change) || !approxEqual(update.ChangePercentage, change/want.PreviousClose*100) {
+		t.Errorf("change %.4f (%.4f%%), want %.4f (%.4f%%)", update.Change, update.ChangePercentage, change, change/want.PreviousClose*100)
+	}
+
+	if _, err := client.Quote(context.Background(), "NOPE"); err == nil || errors.Is(err, ErrRateLimited) {
+		t.Errorf("unknown symbol: got %v, want a quote error", err)
+	}
+	if _, err := NewAlphaVantageClient("", vendor.url).Quote(context.Background(), "AAPL"); err == nil {
+		t.Error("missing API key: got no error")
+	}
+
+	// Alpha Vantage reports its quota with a 200 and a Note
+	vendor.SetRateLimit(1)
+	if _, err := client.Quote(context.Background(), "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Quote(context.Background(), "AAPL"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("over quota: got %v, want ErrRateLimited", err)
+	}
+}
+
+func TestFinnhubQuote(t *testing.T) {
+	vendor := newStubVendor(t)
+	client := NewFinnhubClient("test-key", vendor.url)
+	want := quotestub.DefaultQuotes["MSFT"]
+
+	update, err := client.Quote(context.Background(), "MSFT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Symbol != "MSFT" || update.CurrentPrice != want.Price || update.DayOpen != want.Open ||
+		update.DayHigh != want.High || update.DayLow != want.Low || update.Timestamp != want.Timestamp {
+		t.Errorf("got %+v, want %+v", update, want)
+	}
+	if !approxEqual(update.Change, want.Price-want.PreviousClose) {
+		t.Errorf("change %.4f, want %.4f", update.Change, want.Price-want.PreviousClose)
+	}
+
+	// Finnhub answers unknown symbols with an all-zero quote
+	if _, err := client.Quote(context.Background(), "NOPE"); err == nil || errors.Is(err, ErrRateLimited) {
+		t.Errorf("unknown symbol: got %v, want a quote error", err)
+	}
+	if _, err := NewFinnhubClient("", vendor.url).Quote(context.Background(), "MSFT"); err == nil {
+		t.Error("missing API key: got no error")
+	}
+
+	vendor.SetRateLimit(1)
+	if _, err := client.Quote(context.Background(), "MSFT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Quote(context.Background(), "MSFT"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("over quota: got %v, want ErrRateLimited", err)
+	}
+}
+
+// runSource runs source for d and returns the updates it produced
+func runSource(source PriceSource, d time.Duration) []*pb.PriceUpdate {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	out := make(chan *pb.PriceUpdate, 1000)
+	source.Run(ctx, out)
+	close(out)
+
+	var updates []*pb.PriceUpdate
+	for update := range out {
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func TestPollingSourceKeepsToRequestRate(t *testing.T) {
+	vendor := newStubVendor(t)
+	// One request every 100ms
+	source := NewPollingSource(NewFinnhubClient("test-key", vendor.url), 600, []string{"AAPL", "MSFT"})
+
+	updates := runSource(source, 350*time.Millisecond)
+
+	// Requests go out at 0, 100, 200 and 300ms
+	if n := vendor.total(); n < 3 || n > 4 {
+		t.Errorf("made %d requests in 350ms at 10 a second, want 3 or 4", n)
+	}
+	if len(updates) != vendor.total() {
+		t.Errorf("got %d updates from %d requests", len(updates), vendor.total())
+	}
+	if vendor.count("AAPL") == 0 || vendor.count("MSFT") == 0 {
+		t.Errorf("rotation skipped a symbol: %d AAPL, %d MSFT requests", vendor.count("AAPL"), vendor.count("MSFT"))
+	}
+}
+
+func TestPollingSourcePausesWhenRateLimited(t *testing.T) {
+	vendor := newStubVendor(t)
+	vendor.SetRateLimit(1)
+	source := NewPollingSource(NewAlphaVantageClient("test-key", vendor.url), 6000, []string{"AAPL", "MSFT"})
+
+	updates := runSource(source, 200*time.Millisecond)
+
+	// The second request is refused, and the source then waits out rateLimitPause
+	if n := vendor.total(); n != 2 {
+		t.Errorf("made %d requests, want 2 before pausing", n)
+	}
+	if len(updates) != 1 {
+		t.Errorf("got %d updates, want 1", len(updates))
+	}
+}
+
+func TestPollingSourceBacksOffFailingSymbols(t *testing.T) {
+	vendor := newStubVendor(t)
+	source := NewPollingSource(NewAlphaVantageClient("test-key", vendor.url), 6000, []string{"AAPL", "NOPE"})
+
+	started := time.Now()
+	updates := runSource(source, 200*time.Millisecond)
+
+	// NOPE fails once and is left alone while AAPL keeps updating
+	if n := vendor.count("NOPE"); n != 1 {
+		t.Errorf("requested NOPE %d times, want 1 before backing off", n)
+	}
+	if vendor.count("AAPL") < 5 {
+		t.Errorf("requested AAPL %d times, want it polled while NOPE backs off", vendor.count("AAPL"))
+	}
+	for _, update := range updates {
+		if update.Symbol != "AAPL" {
+			t.Errorf("got an update for %s", update.Symbol)
+		}
+	}
+	if retry := source.retry["NOPE"]; retry.Before(started.Add(minRetryDelay)) {
+		t.Errorf("NOPE retries at %s, want at least %s after starting", retry.Sub(started), minRetryDelay)
+	}
+
+	// Each further failure doubles the delay, up to maxRetryDelay
+	want := minRetryDelay
+	for range 10 {
+		want = min(want*2, maxRetryDelay)
+		if delay := source.backoff("NOPE"); delay != want {
+			t.Fatalf("backoff %s, want %s", delay, want)
+		}
+	}
+
+	source.recovered("NOPE")
+	if _, ok := source.nextSymbol(time.Now()); !ok || source.delay["NOPE"] != 0 {
+		t.Error("recovered symbol still backing off")
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math/rand"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,137 +16,244 @@ import (
 
 type PriceManager struct {
 	rdb         *redis.Client
-	subscribers map[string][]chan *pb.PriceUpdate
+	source      PriceSource
+	subscribers map[string][]*Subscription
+	history     map[string]*symbolHistory
 	mu          sync.RWMutex
 	prices      map[string]*pb.PriceUpdate
 	pricesMu    sync.RWMutex
 }
 
-func NewPriceManager(rdb *redis.Client) *PriceManager {
+// NewPriceManager creates a PriceManager fed by source. rdb may be nil, in which
+// case prices are only kept in memory. A nil source falls back to a random walk
+// over DefaultBasePrices.
+func NewPriceManager(rdb *redis.Client, source PriceSource) *PriceManager {
+	if source == nil {
+		source = NewRandomWalkSource(DefaultBasePrices, 2*time.Second)
+	}
+
 	return &PriceManager{
 		rdb:         rdb,
-		subscribers: make(map[string][]chan *pb.PriceUpdate),
+		source:      source,
+		subscribers: make(map[string][]*Subscription),
+		history:     make(map[string]*symbolHistory),
 		prices:      make(map[string]*pb.PriceUpdate),
 	}
 }
 
-// Start begins the price update simulation
+// Start runs the price source and publishes its updates until ctx is done
 func (pm *PriceManager) Start(ctx context.Context) {
-	// Initialize some default stocks with base prices
-	baseStocks := map[string]float64{
-		"AAPL":  175.50, // Apple Inc.
-		"GOOGL": 140.25, // Alphabet Inc.
-		"MSFT":  380.75, // Microsoft Corporation
-		"AMZN":  152.30, // Amazon.com Inc.
-		"TSLA":  242.80, // Tesla Inc.
-		"META":  485.20, // Meta Platforms Inc.
-		"NVDA":  495.60, // NVIDIA Corporation
-		"NFLX":  475.90, // Netflix Inc.
-		"JPM":   210.45, // JPMorgan Chase & Co.
-		"JNJ":   165.80, // Johnson & Johnson
-	}
-
-	for symbol, basePrice := range baseStocks {
-		pm.prices[symbol] = &pb.PriceUpdate{
-			Symbol:       symbol,
-			CurrentPrice: basePrice,
-			Timestamp:    time.Now().Unix(),
-		}
-	}
+	updates := make(chan *pb.PriceUpdate, 100)
+	done := make(chan error, 1)
 
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
+	go func() {
+		done <- pm.source.Run(ctx, updates)
+	}()
 
-	log.Println("📊 Price Manager started - simulating price updates")
+	log.Println("📊 Price Manager started")
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			pm.updatePrices()
+		case err := <-done:
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Price source stopped: %v", err)
+			}
+			// Drain whatever the source queued before returning
+			for {
+				select {
+				case update := <-updates:
+					pm.publish(update)
+				default:
+					return
+				}
+			}
+		case update := <-updates:
+			pm.publish(update)
 		}
 	}
 }
 
-// updatePrices simulates realistic price movements
-func (pm *PriceManager) updatePrices() {
-	pm.pricesMu.Lock()
-	defer pm.pricesMu.Unlock()
+// publish numbers an update, records it as the latest price, caches it and fans it out
+func (pm *PriceManager) publish(update *pb.PriceUpdate) {
+	pm.mu.Lock()
+	h, ok := pm.history[update.Symbol]
+	if !ok {
+		h = &symbolHistory{}
+		pm.history[update.Symbol] = h
+	}
+	if !h.record(update) {
+		pm.mu.Unlock()
+		return
+	}
 
-	now := time.Now().Unix()
-
-	for symbol, currentPrice := range pm.prices {
-		// Simulate price change (-2% to +2%)
-		changePercent := (rand.Float64() - 0.5) * 4
-		change := currentPrice.CurrentPrice * (changePercent / 100)
-		newPrice := currentPrice.CurrentPrice + change
-
-		// Calculate day high/low (simulate)
-		dayHigh := newPrice * 1.02
-		dayLow := newPrice * 0.98
-		volume := rand.Float64() * 10000000
-
-		update := &pb.PriceUpdate{
-			Symbol:           symbol,
-			CurrentPrice:     newPrice,
-			Change:           change,
-			ChangePercentage: changePercent,
-			Timestamp:        now,
-			Volume:           volume,
-			DayHigh:          dayHigh,
-			DayLow:           dayLow,
-		}
+	pm.pricesMu.Lock()
+	pm.prices[update.Symbol] = update
+	pm.pricesMu.Unlock()
 
-		pm.prices[symbol] = update
+	// Queue for subscribers while still holding the lock, so a new subscriber's
+	// snapshot and its live updates never overlap or leave a gap
+	slow := pm.broadcast(update.Symbol, update)
+	pm.mu.Unlock()
 
-		// Cache in Redis
-		pm.cachePrice(context.Background(), symbol, update)
+	pm.disconnect(slow)
 
-		// Broadcast to subscribers
-		pm.broadcast(symbol, update)
+	// Cache in Redis, unless the producing instance already did
+	if _, shared := pm.source.(externallyCached); !shared {
+		pm.cachePrice(context.Background(), update.Symbol, update)
 	}
 }
 
-// Subscribe adds a subscriber for a symbol
-func (pm *PriceManager) Subscribe(symbol string) chan *pb.PriceUpdate {
+// Subscribe returns one subscription covering symbols; pass AllSymbols to receive
+// every update. More symbols can be added or removed later. opts.Policy decides
+// what happens when the subscriber falls behind; the zero value drops the newest updates.
+func (pm *PriceManager) Subscribe(opts SubscribeOptions, symbols ...string) *Subscription {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	ch := make(chan *pb.PriceUpdate, 100)
-	pm.subscribers[symbol] = append(pm.subscribers[symbol], ch)
+	sub := newSubscription(pm, opts)
+	if opts.Snapshot || len(opts.Resume) > 0 {
+		sub.preload(pm.backfill(opts, symbols))
+	}
+	for _, symbol := range symbols {
+		pm.addSubscriber(sub, symbol)
+	}
 
-	log.Printf("✓ New subscriber for %s (total: %d)", symbol, len(pm.subscribers[symbol]))
+	log.Printf("✓ New %s subscriber for %v", sub.policy, symbols)
 
-	return ch
+	return sub
 }
 
-// Unsubscribe removes a subscriber
-func (pm *PriceManager) Unsubscribe(symbol string, ch chan *pb.PriceUpdate) {
+// Unsubscribe removes a subscriber from every symbol and closes its channel
+func (pm *PriceManager) Unsubscribe(sub *Subscription) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	if pm.closeSubscriber(sub) {
+		log.Printf("✓ Unsubscribed from %d symbols", len(sub.symbols))
+	}
+}
+
+// addSubscriber registers sub for symbol; pm.mu must be held
+func (pm *PriceManager) addSubscriber(sub *Subscription, symbol string) {
+	if sub.symbols[symbol] {
+		return
+	}
+	sub.symbols[symbol] = true
+	pm.subscribers[symbol] = append(pm.subscribers[symbol], sub)
+}
+
+// removeSubscriber unregisters sub from symbol; pm.mu must be held
+func (pm *PriceManager) removeSubscriber(sub *Subscription, symbol string) {
+	if !sub.symbols[symbol] {
+		return
+	}
+	delete(sub.symbols, symbol)
+
 	subs := pm.subscribers[symbol]
-	for i, sub := range subs {
-		if sub == ch {
-			close(ch)
-			pm.subscribers[symbol] = append(subs[:i], subs[i+1:]...)
-			log.Printf("✓ Unsubscribed from %s (remaining: %d)", symbol, len(pm.subscribers[symbol]))
-			break
+	if i := slices.Index(subs, sub); i >= 0 {
+		pm.subscribers[symbol] = slices.Delete(subs, i, i+1)
+	}
+	if len(pm.subscribers[symbol]) == 0 {
+		delete(pm.subscribers, symbol)
+	}
+}
+
+// closeSubscriber unregisters sub from everything and closes it; pm.mu must be held.
+// It reports false if sub was already closed.
+func (pm *PriceManager) closeSubscriber(sub *Subscription) bool {
+	for symbol := range sub.symbols {
+		subs := pm.subscribers[symbol]
+		if i := slices.Index(subs, sub); i >= 0 {
+			pm.subscribers[symbol] = slices.Delete(subs, i, i+1)
+		}
+		if len(pm.subscribers[symbol]) == 0 {
+			delete(pm.subscribers, symbol)
 		}
 	}
+
+	return sub.close()
 }
 
-// broadcast sends price updates to all subscribers
-func (pm *PriceManager) broadcast(symbol string, update *pb.PriceUpdate) {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+// backfill returns what a new subscriber sees before live updates: the missed
+// updates for symbols in opts.Resume that are still in the history, and the
+// latest price of every other symbol when a snapshot is requested. pm.mu must be held.
+func (pm *PriceManager) backfill(opts SubscribeOptions, symbols []string) []*pb.PriceUpdate {
+	pm.pricesMu.RLock()
+	defer pm.pricesMu.RUnlock()
 
-	for _, ch := range pm.subscribers[symbol] {
-		select {
-		case ch <- update:
-		default:
-			log.Printf("⚠️  Subscriber channel full for %s", symbol)
+	wanted := symbols
+	if slices.Contains(symbols, AllSymbols) {
+		wanted = make([]string, 0, len(pm.prices))
+		for symbol := range pm.prices {
+			wanted = append(wanted, symbol)
+		}
+	}
+	wanted = slices.Clone(wanted)
+	sort.Strings(wanted)
+
+	var updates []*pb.PriceUpdate
+	for _, symbol := range slices.Compact(wanted) {
+		seq, resume := opts.Resume[symbol]
+		if resume {
+			if h, ok := pm.history[symbol]; ok {
+				if missed, ok := h.since(seq); ok {
+					updates = append(updates, missed...)
+					continue
+				}
+			}
+		} else if !opts.Snapshot {
+			continue
+		}
+
+		// Too far behind to replay, or a fresh subscriber: start from the latest price,
+		// unless the client resumed at exactly that price. Prices seeded by AddSymbol have
+		// sequence 0, which a client that never saw the symbol must still be sent.
+		if price, ok := pm.prices[symbol]; ok && (!resume || price.Sequence != seq) {
+			updates = append(updates, price)
+		}
+	}
+
+	return updates
+}
+
+// broadcast queues an update on every subscription for symbol, applying each
+// subscriber's slow-consumer policy, and returns those that fell too far behind.
+// pm.mu must be held.
+func (pm *PriceManager) broadcast(symbol string, update *pb.PriceUpdate) []*Subscription {
+	var slow []*Subscription
+
+	for _, sub := range pm.subscribers[symbol] {
+		// Wildcard subscribers get the update below, exactly once
+		if sub.symbols[AllSymbols] {
+			continue
+		}
+		if !sub.offer(update) {
+			slow = append(slow, sub)
+		}
+	}
+	for _, sub := range pm.subscribers[AllSymbols] {
+		if !sub.offer(update) {
+			slow = append(slow, sub)
+		}
+	}
+
+	return slow
+}
+
+// disconnect closes subscriptions that could not keep up
+func (pm *PriceManager) disconnect(slow []*Subscription) {
+	if len(slow) == 0 {
+		return
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for _, sub := range slow {
+		if pm.closeSubscriber(sub) {
+			log.Printf("⚠️  Disconnected slow subscriber after %d dropped updates", sub.Dropped())
 		}
 	}
 }
@@ -153,11 +261,13 @@ func (pm *PriceManager) broadcast(symbol string, update *pb.PriceUpdate) {
 // GetCurrentPrice retrieves the latest price (from cache or memory)
 func (pm *PriceManager) GetCurrentPrice(ctx context.Context, symbol string) (*pb.PriceUpdate, error) {
 	// Try Redis cache first
-	cached, err := pm.rdb.Get(ctx, fmt.Sprintf("price:%s", symbol)).Result()
-	if err == nil {
-		var price pb.PriceUpdate
-		if err := json.Unmarshal([]byte(cached), &price); err == nil {
-			return &price, nil
+	if pm.rdb != nil {
+		cached, err := pm.rdb.Get(ctx, fmt.Sprintf("price:%s", symbol)).Result()
+		if err == nil {
+			var price pb.PriceUpdate
+			if err := json.Unmarshal([]byte(cached), &price); err == nil {
+				return &price, nil
+			}
 		}
 	}
 
@@ -174,6 +284,10 @@ func (pm *PriceManager) GetCurrentPrice(ctx context.Context, symbol string) (*pb
 
 // cachePrice stores price in Redis
 func (pm *PriceManager) cachePrice(ctx context.Context, symbol string, price *pb.PriceUpdate) {
+	if pm.rdb == nil {
+		return
+	}
+
 	data, err := json.Marshal(price)
 	if err != nil {
 		log.Printf("Failed to marshal price: %v", err)
@@ -186,8 +300,76 @@ func (pm *PriceManager) cachePrice(ctx context.Context, symbol string, price *pb
 	}
 }
 
+// Symbols returns the symbols currently being tracked
+func (pm *PriceManager) Symbols() []string {
+	pm.pricesMu.RLock()
+	defer pm.pricesMu.RUnlock()
+
+	symbols := make([]string, 0, len(pm.prices))
+	for symbol := range pm.prices {
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// ApplySplit restates symbol's latest price in post-split shares, ratio new shares per
+// old share, and has the source do the same if it makes up its own prices. Until the
+// next tick, readers see the adjusted price rather than the pre-split one.
+func (pm *PriceManager) ApplySplit(ctx context.Context, symbol string, ratio float64) {
+	if adjuster, ok := pm.source.(splitAdjuster); ok {
+		adjuster.ApplySplit(symbol, ratio)
+	}
+
+	pm.pricesMu.Lock()
+	price, ok := pm.prices[symbol]
+	if ok {
+		// Subscribers may still hold the old update, so replace it rather than change it
+		price = &pb.PriceUpdate{
+			Symbol:           price.Symbol,
+			CurrentPrice:     price.CurrentPrice / ratio,
+			Change:           price.Change / ratio,
+			ChangePercentage: price.ChangePercentage,
+			Timestamp:        price.Timestamp,
+			Volume:           price.Volume * ratio,
+			DayHigh:          price.DayHigh / ratio,
+			DayLow:           price.DayLow / ratio,
+			DayOpen:          price.DayOpen / ratio,
+			Sequence:         price.Sequence,
+		}
+		pm.prices[symbol] = price
+	}
+	pm.pricesMu.Unlock()
+
+	if ok {
+		pm.cachePrice(ctx, symbol, price)
+		log.Printf("✂️  Adjusted %s for a %g-for-1 split: %.2f", symbol, ratio, price.CurrentPrice)
+	}
+}
+
+// RemoveSymbol stops tracking symbol, as after it changes to another symbol
+func (pm *PriceManager) RemoveSymbol(ctx context.Context, symbol string) {
+	if remover, ok := pm.source.(symbolRemover); ok {
+		remover.RemoveSymbol(symbol)
+	}
+
+	pm.pricesMu.Lock()
+	delete(pm.prices, symbol)
+	pm.pricesMu.Unlock()
+
+	if pm.rdb != nil {
+		if err := pm.rdb.Del(ctx, fmt.Sprintf("price:%s", symbol)).Err(); err != nil {
+			log.Printf("Failed to remove cached price: %v", err)
+		}
+	}
+
+	log.Printf("➖ Removed symbol: %s", symbol)
+}
+
 // AddSymbol adds a new symbol to track
 func (pm *PriceManager) AddSymbol(symbol string, basePrice float64) {
+	pm.source.AddSymbol(symbol, basePrice)
+
 	pm.pricesMu.Lock()
 	defer pm.pricesMu.Unlock()
 
@@ -197,6 +379,6 @@ func (pm *PriceManager) AddSymbol(symbol string, basePrice float64) {
 			CurrentPrice: basePrice,
 			Timestamp:    time.Now().Unix(),
 		}
-		log.Printf("➕ Added new symbol: %s at $%.2f", symbol, basePrice)
+		log.Printf("➕ Added new symbol: %s at %.2f", symbol, basePrice)
 	}
 }
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// idleSource never produces a price, so tests publish exactly what they need
+type idleSource struct{}
+
+func (idleSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (idleSource) AddSymbol(symbol string, basePrice float64) {}
+
+// publishTicks publishes n updates for symbol, numbered from the manager's next sequence
+func publishTicks(pm *PriceManager, symbol string, n int) {
+	for i := range n {
+		pm.publish(&pb.PriceUpdate{Symbol: symbol, CurrentPrice: 100 + float64(i)})
+	}
+}
+
+// receive reads n updates from sub, then checks nothing else arrives
+func receive(t *testing.T, sub *Subscription, n int) []string {
+	t.Helper()
+
+	var got []*pb.PriceUpdate
+	for range n {
+		select {
+		case update := <-sub.C():
+			got = append(got, update)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v, want %d updates", labels(got), n)
+		}
+	}
+	select {
+	case update := <-sub.C():
+		t.Fatalf("got %v, then unexpected %s:%d", labels(got), update.Symbol, update.Sequence)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	return labels(got)
+}
+
+func TestSubscribeResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		published int
+		resume    uint64
+		want      []string
+	}{
+		{"gap inside the history", 10, 7, []string{"AAPL:8", "AAPL:9", "AAPL:10"}},
+		{"up to date", 10, 10, nil},
+		{"gap past the history", historySize + 44, 10, []string{"AAPL:300"}},
+		{"sequence from before a restart", 10, 500, []string{"AAPL:10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPriceManager(nil, idleSource{})
+			publishTicks(pm, "AAPL", tt.published)
+
+			sub := pm.Subscribe(SubscribeOptions{Resume: map[string]uint64{"AAPL": tt.resume}}, "AAPL")
+			defer pm.Unsubscribe(sub)
+
+			if got := receive(t, sub, len(tt.want)); !slices.Equal(got, tt.want) {
+				t.Errorf("backfill %v, want %v", got, tt.want)
+			}
+
+			// Live updates carry on from the backfill without a gap
+			publishTicks(pm, "AAPL", 1)
+			next := []string{fmt.Sprintf("AAPL:%d", tt.published+1)}
+			if got := receive(t, sub, 1); !slices.Equal(got, next) {
+				t.Errorf("live %v, want %v", got, next)
+			}
+		})
+	}
+}
+
+func TestSubscribeResumeSendsUnseenSymbols(t *testing.T) {
+	pm := NewPriceManager(nil, idleSource{})
+	publishTicks(pm, "AAPL", 3)
+	publishTicks(pm, "MSFT", 2)
+	// Seeded with no sequence until the source first prices it
+	pm.AddSymbol("NEWCO", 50)
+
+	sub := pm.Subscribe(SubscribeOptions{Snapshot: true, Resume: map[string]uint64{"AAPL": 3}}, AllSymbols)
+	defer pm.Unsubscribe(sub)
+
+	// The client is current on AAPL and never saw the others, including the seeded
+	// price whose zero sequence matches the zero value of a missing Resume entry
+	if got, want := receive(t, sub, 2), []string{"MSFT:2", "NEWCO:0"}; !slices.Equal(got, want) {
+		t.Errorf("backfill %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeSnapshot(t *testing.T) {
+	pm := NewPriceManager(nil, idleSource{})
+	publishTicks(pm, "AAPL", 3)
+	publishTicks(pm, "MSFT", 2)
+
+	sub := pm.Subscribe(SubscribeOptions{Snapshot: true}, "MSFT", "AAPL", "AAPL")
+	defer pm.Unsubscribe(sub)
+
+	if got, want := receive(t, sub, 2), []string{"AAPL:3", "MSFT:2"}; !slices.Equal(got, want) {
+		t.Errorf("snapshot %v, want %v", got, want)
+	}
+}
+
+func TestSymbolHistory(t *testing.T) {
+	var h symbolHistory
+	for range historySize + 10 {
+		h.record(&pb.PriceUpdate{Symbol: "AAPL"})
+	}
+
+	// The ring wraps, keeping the newest historySize updates in order
+	updates, ok := h.since(10)
+	if !ok || len(updates) != historySize || updates[0].Sequence != 11 || updates[len(updates)-1].Sequence != historySize+10 {
+		t.Errorf("since(10) returned %d updates from %d, ok %v", len(updates), updates[0].Sequence, ok)
+	}
+	if _, ok := h.since(9); ok {
+		t.Error("since(9) claimed to have update 10 after it was evicted")
+	}
+
+	// Numbered updates keep their number and duplicates are rejected
+	if h.record(&pb.PriceUpdate{Symbol: "AAPL", Sequence: historySize + 10}) {
+		t.Error("recorded a duplicate sequence")
+	}
+	if !h.record(&pb.PriceUpdate{Symbol: "AAPL", Sequence: 1000}) || h.seq != 1000 {
+		t.Errorf("sequence %d after recording 1000", h.seq)
+	}
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"context"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// PriceSource produces price ticks for a PriceManager. The manager owns caching,
+// fan-out and subscriptions; a source only decides what the next prices are.
+type PriceSource interface {
+	// Run sends updates on out until ctx is cancelled or the source is exhausted.
+	// It must not close out.
+	Run(ctx context.Context, out chan<- *pb.PriceUpdate) error
+
+	// AddSymbol asks the source to start producing prices for symbol. Sources that
+	// have no quote of their own may use basePrice as the starting point.
+	AddSymbol(symbol string, basePrice float64)
+}
+
+// splitAdjuster is implemented by sources that make up their own prices. A vendor's
+// quotes reflect a split on their own; these sources have to be told about it.
+type splitAdjuster interface {
+	ApplySplit(symbol string, ratio float64)
+}
+
+// symbolRemover is implemented by sources that can stop producing a symbol, such as
+// the old symbol after a symbol change
+type symbolRemover interface {
+	RemoveSymbol(symbol string)
+}
+
+// resumer is implemented by sources that make up their own prices, so a producer
+// taking over from another instance can carry on from the last price it published
+// instead of jumping back to its own stale state
+type resumer interface {
+	Symbols() []string
+	ResumeFrom(last *pb.PriceUpdate)
+}
+
+// externallyCached is implemented by sources whose updates were already written to
+// the Redis price cache by another process, so PriceManager must not write them again
+type externallyCached interface {
+	cachedExternally() bool
+}
@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// DefaultBasePrices are the symbols and starting prices used by the demo feeds
+var DefaultBasePrices = map[string]float64{
+	"AAPL":  175.50, // Apple Inc.
+	"GOOGL": 140.25, // Alphabet Inc.
+	"MSFT":  380.75, // Microsoft Corporation
+	"AMZN":  152.30, // Amazon.com Inc.
+	"TSLA":  242.80, // Tesla Inc.
+	"META":  485.20, // Meta Platforms Inc.
+	"NVDA":  495.60, // NVIDIA Corporation
+	"NFLX":  475.90, // Netflix Inc.
+	"JPM":   210.45, // JPMorgan Chase & Co.
+	"JNJ":   165.80, // Johnson & Johnson
+}
+
+// RandomWalkSource moves every symbol by a uniform ±2% step on each tick
+type RandomWalkSource struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	prices  map[string]float64
+	volumes map[string]float64 // cumulative volume, as a vendor quote reports it
+}
+
+func NewRandomWalkSource(basePrices map[string]float64, interval time.Duration) *RandomWalkSource {
+	prices := make(map[string]float64, len(basePrices))
+	for symbol, price := range basePrices {
+		prices[symbol] = price
+	}
+
+	return &RandomWalkSource{
+		interval: interval,
+		prices:   prices,
+		volumes:  make(map[string]float64, len(prices)),
+	}
+}
+
+// Run emits the base prices straight away, then a step for every symbol on each tick
+func (s *RandomWalkSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	now := time.Now().Unix()
+	s.mu.Lock()
+	initial := make([]*pb.PriceUpdate, 0, len(s.prices))
+	for symbol, price := range s.prices {
+		initial = append(initial, &pb.PriceUpdate{
+			Symbol:       symbol,
+			CurrentPrice: price,
+			Timestamp:    now,
+		})
+	}
+	s.mu.Unlock()
+
+	if err := sendAll(ctx, out, initial); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	log.Println("📊 Random walk price source started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := sendAll(ctx, out, s.step()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// AddSymbol starts walking symbol from basePrice
+func (s *RandomWalkSource) AddSymbol(symbol string, basePrice float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.prices[symbol]; !exists {
+		s.prices[symbol] = basePrice
+	}
+}
+
+// Symbols returns the symbols the source is walking
+func (s *RandomWalkSource) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Keys(s.prices))
+}
+
+// ResumeFrom continues walking last.Symbol from its last published price
+func (s *RandomWalkSource) ResumeFrom(last *pb.PriceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prices[last.Symbol] = last.CurrentPrice
+	s.volumes[last.Symbol] = last.Volume
+}
+
+// ApplySplit restates symbol's price in post-split shares, ratio new shares per old share
+func (s *RandomWalkSource) ApplySplit(symbol string, ratio float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if price, exists := s.prices[symbol]; exists {
+		s.prices[symbol] = price / ratio
+		s.volumes[symbol] *= ratio
+	}
+}
+
+// RemoveSymbol stops walking symbol
+func (s *RandomWalkSource) RemoveSymbol(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.prices, symbol)
+	delete(s.volumes, symbol)
+}
+
+// step simulates price movements for every symbol
+func (s *RandomWalkSource) step() []*pb.PriceUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix()
+	updates := make([]*pb.PriceUpdate, 0, len(s.prices))
+
+	for symbol, currentPrice := range s.prices {
+		// Simulate price change (-2% to +2%)
+		changePercent := (rand.Float64() - 0.5) * 4
+		change := currentPrice * (changePercent / 100)
+		newPrice := currentPrice + change
+
+		// Calculate day high/low (simulate)
+		dayHigh := newPrice * 1.02
+		dayLow := newPrice * 0.98
+		s.volumes[symbol] += math.Round(rand.Float64() * 100000)
+
+		s.prices[symbol] = newPrice
+		updates = append(updates, &pb.PriceUpdate{
+			Symbol:           symbol,
+			CurrentPrice:     newPrice,
+			Change:           change,
+			ChangePercentage: changePercent,
+			Timestamp:        now,
+			Volume:           s.volumes[symbol],
+			DayHigh:          dayHigh,
+			DayLow:           dayLow,
+		})
+	}
+
+	return updates
+}
+
+// sendAll delivers updates in order, giving up if ctx is cancelled
+func sendAll(ctx context.Context, out chan<- *pb.PriceUpdate, updates []*pb.PriceUpdate) error {
+	for _, update := range updates {
+		select {
+		case out <- update:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
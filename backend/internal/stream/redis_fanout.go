@@ -0,0 +1,353 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// Redis keys and channels shared by every instance in cross-instance fan-out mode
+const (
+	// PriceChannel carries every price update as JSON
+	PriceChannel = "prices:updates"
+	// symbolsKey is a hash of symbol -> base price for everything any instance tracks
+	symbolsKey = "prices:symbols"
+	// symbolsChannel announces symbols added on any instance to the producer
+	symbolsChannel = "prices:symbols:added"
+	// adjustmentsChannel carries splits and removed symbols from any instance to the producer
+	adjustmentsChannel = "prices:symbols:adjusted"
+	// sequenceKey is a hash of symbol -> last sequence number, so numbering survives producer failover
+	sequenceKey = "prices:sequence"
+	// producerLockKey holds the ID of the instance currently running the price source
+	producerLockKey = "prices:producer"
+)
+
+const producerLease = 10 * time.Second
+
+// symbolAdjustment is a split or removal published on adjustmentsChannel
+type symbolAdjustment struct {
+	Symbol     string  `json:"symbol"`
+	SplitRatio float64 `json:"split_ratio,omitempty"`
+	Removed    bool    `json:"removed,omitempty"`
+}
+
+var (
+	renewLockScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0`)
+	releaseLockScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0`)
+)
+
+// RedisProducer runs a PriceSource on exactly one instance at a time and publishes
+// its updates to Redis. Every instance runs a RedisProducer; they compete for a
+// lease in Redis and a standby takes over if the leader stops renewing it.
+type RedisProducer struct {
+	rdb        *redis.Client
+	source     PriceSource
+	instanceID string
+}
+
+func NewRedisProducer(rdb *redis.Client, source PriceSource) *RedisProducer {
+	return &RedisProducer{
+		rdb:        rdb,
+		source:     source,
+		instanceID: uuid.New().String(),
+	}
+}
+
+// Run competes for the producer lease until ctx is done
+func (p *RedisProducer) Run(ctx context.Context) {
+	ticker := time.NewTicker(producerLease / 3)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := p.rdb.SetNX(ctx, producerLockKey, p.instanceID, producerLease).Result()
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to acquire price producer lease: %v", err)
+		}
+		if acquired {
+			log.Printf("👑 Instance %s is now the price producer", p.instanceID)
+			p.lead(ctx)
+			log.Printf("Instance %s stopped producing prices", p.instanceID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs the source while this instance holds the lease
+func (p *RedisProducer) lead(ctx context.Context) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		releaseLockScript.Run(context.Background(), p.rdb, []string{producerLockKey}, p.instanceID)
+	}()
+
+	p.loadSymbols(leadCtx)
+	go p.watchSymbols(leadCtx)
+
+	updates := make(chan *pb.PriceUpdate, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.source.Run(leadCtx, updates)
+	}()
+
+	renew := time.NewTicker(producerLease / 3)
+	defer renew.Stop()
+
+	for {
+		select {
+		case <-leadCtx.Done():
+			return
+		case err := <-done:
+			if err != nil && leadCtx.Err() == nil {
+				log.Printf("Price source stopped: %v", err)
+			}
+			return
+		case <-renew.C:
+			ok, err := renewLockScript.Run(leadCtx, p.rdb, []string{producerLockKey}, p.instanceID, producerLease.Milliseconds()).Int()
+			if err != nil || ok == 0 {
+				log.Printf("⚠️  Lost price producer lease: %v", err)
+				return
+			}
+		case update := <-updates:
+			p.publish(leadCtx, update)
+		}
+	}
+}
+
+// publish numbers an update, then caches it and fans it out to every instance in one round trip
+func (p *RedisProducer) publish(ctx context.Context, update *pb.PriceUpdate) {
+	seq, err := p.rdb.HIncrBy(ctx, sequenceKey, update.Symbol, 1).Result()
+	if err != nil {
+		log.Printf("Failed to number price update: %v", err)
+		return
+	}
+	update.Sequence = uint64(seq)
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		log.Printf("Failed to marshal price: %v", err)
+		return
+	}
+
+	_, err = p.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, fmt.Sprintf("price:%s", update.Symbol), data, 10*time.Minute)
+		pipe.Publish(ctx, PriceChannel, data)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to publish price: %v", err)
+	}
+}
+
+// loadSymbols registers every symbol added on any instance with the source. Symbols
+// another producer has published carry on from their last price, so the shared tape
+// doesn't jump on failover.
+func (p *RedisProducer) loadSymbols(ctx context.Context) {
+	symbols, err := p.rdb.HGetAll(ctx, symbolsKey).Result()
+	if err != nil {
+		log.Printf("Failed to load tracked symbols: %v", err)
+		return
+	}
+
+	for symbol, base := range symbols {
+		basePrice, _ := strconv.ParseFloat(base, 64)
+		if last := p.lastPublished(ctx, symbol); last != nil {
+			basePrice = last.CurrentPrice
+		}
+		p.source.AddSymbol(symbol, basePrice)
+	}
+
+	resume, ok := p.source.(resumer)
+	if !ok {
+		return
+	}
+	resumed := 0
+	for _, symbol := range resume.Symbols() {
+		if last := p.lastPublished(ctx, symbol); last != nil {
+			resume.ResumeFrom(last)
+			resumed++
+		}
+	}
+
+	if resumed > 0 {
+		log.Printf("Resumed %d symbols from their last published prices", resumed)
+	}
+}
+
+// lastPublished returns the price a producer last published for symbol, or nil if
+// none is cached
+func (p *RedisProducer) lastPublished(ctx context.Context, symbol string) *pb.PriceUpdate {
+	data, err := p.rdb.Get(ctx, fmt.Sprintf("price:%s", symbol)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Failed to read last price for %s: %v", symbol, err)
+		}
+		return nil
+	}
+
+	var last pb.PriceUpdate
+	if err := json.Unmarshal([]byte(data), &last); err != nil {
+		log.Printf("Failed to unmarshal last price for %s: %v", symbol, err)
+		return nil
+	}
+	return &last
+}
+
+// watchSymbols registers symbols as other instances add them, and applies the splits
+// and removals they publish
+func (p *RedisProducer) watchSymbols(ctx context.Context) {
+	pubsub := p.rdb.Subscribe(ctx, symbolsChannel, adjustmentsChannel)
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	for msg := range pubsub.Channel() {
+		if msg.Channel == adjustmentsChannel {
+			p.adjust(msg.Payload)
+			continue
+		}
+
+		base, err := p.rdb.HGet(ctx, symbolsKey, msg.Payload).Float64()
+		if err != nil {
+			log.Printf("Failed to read base price for %s: %v", msg.Payload, err)
+			continue
+		}
+		p.source.AddSymbol(msg.Payload, base)
+	}
+}
+
+// adjust applies a published symbolAdjustment to the source
+func (p *RedisProducer) adjust(payload string) {
+	var adjustment symbolAdjustment
+	if err := json.Unmarshal([]byte(payload), &adjustment); err != nil {
+		log.Printf("Failed to unmarshal symbol adjustment: %v", err)
+		return
+	}
+
+	switch {
+	case adjustment.Removed:
+		if remover, ok := p.source.(symbolRemover); ok {
+			remover.RemoveSymbol(adjustment.Symbol)
+		}
+	case adjustment.SplitRatio > 0:
+		if adjuster, ok := p.source.(splitAdjuster); ok {
+			adjuster.ApplySplit(adjustment.Symbol, adjustment.SplitRatio)
+		}
+	}
+}
+
+// RedisSource is the PriceSource every instance uses in cross-instance mode. It
+// consumes the updates a RedisProducer publishes, so all replicas see the same tape.
+type RedisSource struct {
+	rdb *redis.Client
+}
+
+func NewRedisSource(rdb *redis.Client) *RedisSource {
+	return &RedisSource{rdb: rdb}
+}
+
+// Run forwards updates published to PriceChannel until ctx is done
+func (s *RedisSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	pubsub := s.rdb.Subscribe(ctx, PriceChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", PriceChannel, err)
+	}
+
+	log.Printf("📡 Consuming prices from Redis channel %s", PriceChannel)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("redis subscription to %s closed", PriceChannel)
+			}
+
+			var update pb.PriceUpdate
+			if err := json.Unmarshal([]byte(msg.Payload), &update); err != nil {
+				log.Printf("Failed to decode price update: %v", err)
+				continue
+			}
+			if err := sendAll(ctx, out, []*pb.PriceUpdate{&update}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// AddSymbol records symbol in Redis and asks the producer, wherever it runs, to track it
+func (s *RedisSource) AddSymbol(symbol string, basePrice float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	added, err := s.rdb.HSetNX(ctx, symbolsKey, symbol, basePrice).Result()
+	if err != nil {
+		log.Printf("Failed to register symbol %s: %v", symbol, err)
+		return
+	}
+	if added {
+		if err := s.rdb.Publish(ctx, symbolsChannel, symbol).Err(); err != nil {
+			log.Printf("Failed to announce symbol %s: %v", symbol, err)
+		}
+	}
+}
+
+// ApplySplit asks the producer, wherever it runs, to restate symbol's prices after a split
+func (s *RedisSource) ApplySplit(symbol string, ratio float64) {
+	s.publishAdjustment(symbolAdjustment{Symbol: symbol, SplitRatio: ratio})
+}
+
+// RemoveSymbol stops tracking symbol on every instance
+func (s *RedisSource) RemoveSymbol(symbol string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.rdb.HDel(ctx, symbolsKey, symbol).Err(); err != nil {
+		log.Printf("Failed to unregister symbol %s: %v", symbol, err)
+	}
+	s.publishAdjustment(symbolAdjustment{Symbol: symbol, Removed: true})
+}
+
+func (s *RedisSource) publishAdjustment(adjustment symbolAdjustment) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(adjustment)
+	if err != nil {
+		log.Printf("Failed to marshal symbol adjustment: %v", err)
+		return
+	}
+	if err := s.rdb.Publish(ctx, adjustmentsChannel, data).Err(); err != nil {
+		log.Printf("Failed to announce adjustment to %s: %v", adjustment.Symbol, err)
+	}
+}
+
+// cachedExternally tells PriceManager the producer already wrote the Redis cache
+func (s *RedisSource) cachedExternally() bool {
+	return true
+}
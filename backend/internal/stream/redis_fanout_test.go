@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// newTestRedis returns a client for a fresh in-process Redis
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+// countingSource emits a price every 10ms and counts how many of its runs are live
+type countingSource struct {
+	running atomic.Int32
+}
+
+func (s *countingSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	s.running.Add(1)
+	defer s.running.Add(-1)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := sendAll(ctx, out, []*pb.PriceUpdate{{Symbol: "AAPL", CurrentPrice: 100}}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *countingSource) AddSymbol(symbol string, basePrice float64) {}
+
+// waitFor polls condition until it holds or timeout passes
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestRedisProducerElectsOneLeader(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+
+	first, second := &countingSource{}, &countingSource{}
+	leader := NewRedisProducer(rdb, first)
+	standby := NewRedisProducer(rdb, second)
+
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	defer stopLeader()
+	go leader.Run(leaderCtx)
+	if !waitFor(time.Second, func() bool { return first.running.Load() == 1 }) {
+		t.Fatal("the only instance never started producing")
+	}
+
+	standbyCtx, stopStandby := context.WithCancel(context.Background())
+	defer stopStandby()
+	go standby.Run(standbyCtx)
+	time.Sleep(100 * time.Millisecond)
+	if second.running.Load() != 0 {
+		t.Fatal("a second instance produced prices while the lease was held")
+	}
+	if holder, _ := mr.Get(producerLockKey); holder != leader.instanceID {
+		t.Errorf("lease held by %q, want the leader", holder)
+	}
+
+	// Published updates are numbered from the shared counter
+	if !waitFor(time.Second, func() bool { return mr.HGet(sequenceKey, "AAPL") != "" }) {
+		t.Fatal("the leader published nothing")
+	}
+
+	// A leader that stops releases the lease and the standby takes over on its next try
+	stopLeader()
+	if !waitFor(time.Second, func() bool { return first.running.Load() == 0 }) {
+		t.Fatal("the old leader kept producing")
+	}
+	if !waitFor(producerLease/3+time.Second, func() bool { return second.running.Load() == 1 }) {
+		t.Fatal("the standby never took over")
+	}
+	if holder, _ := mr.Get(producerLockKey); holder != standby.instanceID {
+		t.Errorf("lease held by %q after failover, want the standby", holder)
+	}
+}
+
+func TestRedisProducerStopsWhenItLosesTheLease(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	source := &countingSource{}
+	producer := NewRedisProducer(rdb, source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go producer.Run(ctx)
+	if !waitFor(time.Second, func() bool { return source.running.Load() == 1 }) {
+		t.Fatal("never started producing")
+	}
+
+	// Another instance took the lease after ours expired, say across a pause
+	mr.Set(producerLockKey, "other-instance")
+	if !waitFor(producerLease/3+time.Second, func() bool { return source.running.Load() == 0 }) {
+		t.Fatal("kept producing without the lease")
+	}
+	if holder, _ := mr.Get(producerLockKey); holder != "other-instance" {
+		t.Errorf("lease now held by %q, want it left with the other instance", holder)
+	}
+}
+
+func TestRedisProducerResumesFromLastPublished(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	// What the previous producer left behind
+	mr.HSet(symbolsKey, "AAPL", "100", "NEWCO", "20")
+	mr.HSet(sequenceKey, "AAPL", "41")
+	last, _ := json.Marshal(&pb.PriceUpdate{
+		Symbol: "AAPL", CurrentPrice: 150, Change: 3, Volume: 5000, DayOpen: 140, DayHigh: 152, DayLow: 139, Sequence: 41,
+	})
+	mr.Set("price:AAPL", string(last))
+
+	config := DefaultSimulatorConfig(7, 0)
+	config.Symbols = map[string]SymbolParams{}
+	source := NewSimulatorSource(config)
+	producer := NewRedisProducer(rdb, source)
+	producer.loadSymbols(ctx)
+
+	updates := make(map[string]*pb.PriceUpdate)
+	for _, update := range source.Step() {
+		updates[update.Symbol] = update
+	}
+
+	// AAPL carries on from 150 within the same session rather than restarting at 100
+	aapl := updates["AAPL"]
+	if aapl == nil || math.Abs(aapl.CurrentPrice/150-1) > 0.05 || math.Abs(aapl.CurrentPrice-aapl.Change-147) > 1e-9 ||
+		aapl.DayOpen != 140 || aapl.Volume < 5000 {
+		t.Errorf("AAPL %+v, want it resumed from 150 after a close of 147, open 140 and volume 5000", aapl)
+	}
+	// A symbol nobody has priced yet starts from its base price
+	if newco := updates["NEWCO"]; newco == nil || math.Abs(newco.CurrentPrice/20-1) > 0.05 {
+		t.Errorf("NEWCO %+v, want it started near 20", newco)
+	}
+
+	// Numbering carries on from the previous producer's last sequence
+	producer.publish(ctx, aapl)
+	if aapl.Sequence != 42 {
+		t.Errorf("published as %d, want 42", aapl.Sequence)
+	}
+	if cached := producer.lastPublished(ctx, "AAPL"); cached == nil || cached.Sequence != 42 || cached.CurrentPrice != aapl.CurrentPrice {
+		t.Errorf("cached %+v, want the update just published", cached)
+	}
+}
@@ -0,0 +1,329 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// Tick is one recorded trade or quote
+type Tick struct {
+	Symbol    string
+	Timestamp time.Time
+	Price     float64
+	Volume    float64
+}
+
+// ReplayConfig configures a ReplaySource
+type ReplayConfig struct {
+	// Path to a .csv file with a symbol,timestamp,price,volume header or a .jsonl/.ndjson file
+	Path string
+	// Speed multiplies the recorded pace: 1 is real time, 10 is ten times faster,
+	// and zero replays as fast as the consumer reads
+	Speed float64
+	// Loop restarts the tape when it ends instead of finishing
+	Loop bool
+	// Start and End limit the replay to ticks in [Start, End); zero values are unbounded
+	Start time.Time
+	End   time.Time
+}
+
+// ReplaySource plays recorded ticks back through PriceManager
+type ReplaySource struct {
+	config ReplayConfig
+	ticks  []Tick
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewReplaySource loads and sorts the ticks in config.Path
+func NewReplaySource(config ReplayConfig) (*ReplaySource, error) {
+	if config.Speed < 0 {
+		return nil, fmt.Errorf("replay speed must not be negative")
+	}
+
+	f, err := os.Open(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer f.Close()
+
+	var ticks []Tick
+	switch strings.ToLower(filepath.Ext(config.Path)) {
+	case ".csv":
+		ticks, err = ReadTicksCSV(f)
+	case ".jsonl", ".ndjson":
+		ticks, err = ReadTicksJSONL(f)
+	default:
+		return nil, fmt.Errorf("unsupported replay file type: %s", config.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newReplaySource(config, ticks)
+}
+
+// NewReplaySourceFromTicks replays ticks that are already in memory
+func NewReplaySourceFromTicks(config ReplayConfig, ticks []Tick) (*ReplaySource, error) {
+	return newReplaySource(config, append([]Tick(nil), ticks...))
+}
+
+func newReplaySource(config ReplayConfig, ticks []Tick) (*ReplaySource, error) {
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].Timestamp.Before(ticks[j].Timestamp)
+	})
+
+	window := ticks[:0]
+	for _, t := range ticks {
+		if !config.Start.IsZero() && t.Timestamp.Before(config.Start) {
+			continue
+		}
+		if !config.End.IsZero() && !t.Timestamp.Before(config.End) {
+			continue
+		}
+		window = append(window, t)
+	}
+	if len(window) == 0 {
+		return nil, errors.New("no ticks to replay in the requested window")
+	}
+
+	return &ReplaySource{
+		config: config,
+		ticks:  window,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Done is closed once the tape has been played to the end. It is never closed when looping.
+func (s *ReplaySource) Done() <-chan struct{} {
+	return s.done
+}
+
+// Run plays the tape, sleeping between ticks according to the configured speed
+func (s *ReplaySource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	log.Printf("📼 Replaying %d ticks from %s (speed %gx, loop %t)", len(s.ticks), s.config.Path, s.config.Speed, s.config.Loop)
+
+	first := s.ticks[0].Timestamp
+	span := s.ticks[len(s.ticks)-1].Timestamp.Sub(first) + time.Second
+
+	for pass := 0; ; pass++ {
+		// Each pass is shifted forward so timestamps keep increasing when looping
+		offset := time.Duration(pass) * span
+		days := make(map[string]*replayDay)
+
+		for i, tick := range s.ticks {
+			if i > 0 && s.config.Speed > 0 {
+				gap := tick.Timestamp.Sub(s.ticks[i-1].Timestamp)
+				if gap > 0 {
+					if err := sleepContext(ctx, time.Duration(float64(gap)/s.config.Speed)); err != nil {
+						return err
+					}
+				}
+			}
+
+			day := advanceDay(days[tick.Symbol], tick)
+			days[tick.Symbol] = day
+			if err := sendAll(ctx, out, []*pb.PriceUpdate{day.priceUpdate(tick, offset)}); err != nil {
+				return err
+			}
+		}
+
+		if !s.config.Loop {
+			break
+		}
+	}
+
+	s.doneOnce.Do(func() { close(s.done) })
+	log.Println("📼 Replay finished")
+	return nil
+}
+
+// AddSymbol is a no-op: a replay only contains the symbols that were recorded
+func (s *ReplaySource) AddSymbol(symbol string, basePrice float64) {}
+
+// replayDay accumulates the session statistics for one symbol
+type replayDay struct {
+	date      string
+	prevClose float64
+	open      float64
+	high      float64
+	low       float64
+	volume    float64
+	last      float64
+}
+
+// advanceDay folds tick into the running day, starting a new day when the UTC date changes
+func advanceDay(d *replayDay, tick Tick) *replayDay {
+	date := tick.Timestamp.UTC().Format("2006-01-02")
+	if d == nil {
+		d = &replayDay{date: date, prevClose: tick.Price, open: tick.Price, high: tick.Price, low: tick.Price}
+	} else if d.date != date {
+		d = &replayDay{date: date, prevClose: d.last, open: tick.Price, high: tick.Price, low: tick.Price}
+	}
+
+	d.high = math.Max(d.high, tick.Price)
+	d.low = math.Min(d.low, tick.Price)
+	d.volume += tick.Volume
+	d.last = tick.Price
+
+	return d
+}
+
+func (d *replayDay) priceUpdate(tick Tick, offset time.Duration) *pb.PriceUpdate {
+	change := tick.Price - d.prevClose
+	changePercent := 0.0
+	if d.prevClose != 0 {
+		changePercent = change / d.prevClose * 100
+	}
+
+	return &pb.PriceUpdate{
+		Symbol:           tick.Symbol,
+		CurrentPrice:     tick.Price,
+		Change:           change,
+		ChangePercentage: changePercent,
+		Timestamp:        tick.Timestamp.Add(offset).Unix(),
+		Volume:           d.volume,
+		DayHigh:          d.high,
+		DayLow:           d.low,
+		DayOpen:          d.open,
+	}
+}
+
+// ReadTicksCSV parses ticks from CSV with a header naming the symbol, timestamp, price and volume columns
+func ReadTicksCSV(r io.Reader) ([]Tick, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"symbol", "timestamp", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %q column", name)
+		}
+	}
+	volumeCol, hasVolume := columns["volume"]
+
+	var ticks []Tick
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		ts, err := parseTickTime(record[columns["timestamp"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		price, err := strconv.ParseFloat(record[columns["price"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %w", line, err)
+		}
+		volume := 0.0
+		if hasVolume && record[volumeCol] != "" {
+			if volume, err = strconv.ParseFloat(record[volumeCol], 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid volume: %w", line, err)
+			}
+		}
+
+		ticks = append(ticks, Tick{
+			Symbol:    strings.ToUpper(record[columns["symbol"]]),
+			Timestamp: ts,
+			Price:     price,
+			Volume:    volume,
+		})
+	}
+
+	return ticks, nil
+}
+
+// ReadTicksJSONL parses one JSON object per line with symbol, timestamp, price and volume fields
+func ReadTicksJSONL(r io.Reader) ([]Tick, error) {
+	scanner := bufio.NewScanner(r)
+
+	var ticks []Tick
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var row struct {
+			Symbol    string          `json:"symbol"`
+			Timestamp json.RawMessage `json:"timestamp"`
+			Price     float64         `json:"price"`
+			Volume    float64         `json:"volume"`
+		}
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		ts, err := parseTickTime(strings.Trim(string(row.Timestamp), `"`))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		ticks = append(ticks, Tick{
+			Symbol:    strings.ToUpper(row.Symbol),
+			Timestamp: ts,
+			Price:     row.Price,
+			Volume:    row.Volume,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ticks, nil
+}
+
+// parseTickTime accepts Unix seconds (optionally fractional) or RFC 3339
+func parseTickTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
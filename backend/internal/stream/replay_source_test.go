@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTicksCSV(t *testing.T) {
+	// Columns in any order and case, with an optional volume
+	input := "Price, SYMBOL ,timestamp,volume\n" +
+		"189.5,aapl,1704205800,100\n" +
+		"190.25,AAPL,1704205800.5,\n" +
+		"402,msft,2024-01-02T14:30:01Z,20\n"
+
+	ticks, err := ReadTicksCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Tick{
+		{Symbol: "AAPL", Timestamp: time.Unix(1704205800, 0).UTC(), Price: 189.5, Volume: 100},
+		{Symbol: "AAPL", Timestamp: time.Unix(1704205800, 5e8).UTC(), Price: 190.25, Volume: 0},
+		{Symbol: "MSFT", Timestamp: time.Date(2024, 1, 2, 14, 30, 1, 0, time.UTC), Price: 402, Volume: 20},
+	}
+	if len(ticks) != len(want) {
+		t.Fatalf("got %d ticks, want %d", len(ticks), len(want))
+	}
+	for i := range want {
+		if ticks[i].Symbol != want[i].Symbol || !ticks[i].Timestamp.Equal(want[i].Timestamp) ||
+			ticks[i].Price != want[i].Price || ticks[i].Volume != want[i].Volume {
+			t.Errorf("tick %d: got %+v, want %+v", i, ticks[i], want[i])
+		}
+	}
+}
+
+func TestReadTicksCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing column", "symbol,price\nAAPL,1\n", `missing the "timestamp" column`},
+		{"bad timestamp", "symbol,timestamp,price\nAAPL,1704205800,1\nAAPL,yesterday,1\n", "line 3: invalid timestamp"},
+		{"bad price", "symbol,timestamp,price\nAAPL,1704205800,abc\n", "line 2: invalid price"},
+		{"bad volume", "symbol,timestamp,price,volume\nAAPL,1704205800,1,many\n", "line 2: invalid volume"},
+		{"short row", "symbol,timestamp,price\nAAPL,1704205800\n", "line 2:"},
+		{"empty", "", "failed to read CSV header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadTicksCSV(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTicksJSONL(t *testing.T) {
+	input := `{"symbol":"aapl","timestamp":1704205800,"price":189.5,"volume":100}
+
+{"symbol":"MSFT","timestamp":"2024-01-02T14:30:01Z","price":402}
+`
+	ticks, err := ReadTicksJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticks) != 2 || ticks[0].Symbol != "AAPL" || ticks[0].Timestamp.Unix() != 1704205800 || ticks[0].Volume != 100 ||
+		ticks[1].Symbol != "MSFT" || !ticks[1].Timestamp.Equal(time.Date(2024, 1, 2, 14, 30, 1, 0, time.UTC)) || ticks[1].Price != 402 {
+		t.Errorf("got %+v", ticks)
+	}
+
+	// Line numbers count the blank lines that were skipped
+	_, err = ReadTicksJSONL(strings.NewReader("{\"symbol\":\"AAPL\",\"timestamp\":1,\"price\":1}\n\n{\"symbol\":\"AAPL\",\"timestamp\":\"noon\",\"price\":1}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3: invalid timestamp") {
+		t.Errorf("got %v, want an invalid timestamp on line 3", err)
+	}
+}
+
+func TestNewReplaySource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	csvPath := write("ticks.CSV", "symbol,timestamp,price\nAAPL,1704205800,1\n")
+	if _, err := NewReplaySource(ReplayConfig{Path: csvPath}); err != nil {
+		t.Errorf("csv: %v", err)
+	}
+	jsonPath := write("ticks.ndjson", `{"symbol":"AAPL","timestamp":1704205800,"price":1}`)
+	if _, err := NewReplaySource(ReplayConfig{Path: jsonPath}); err != nil {
+		t.Errorf("ndjson: %v", err)
+	}
+
+	for name, config := range map[string]ReplayConfig{
+		"unsupported type": {Path: write("ticks.txt", "AAPL 1")},
+		"negative speed":   {Path: csvPath, Speed: -1},
+		"empty window":     {Path: csvPath, Start: time.Unix(1704205801, 0)},
+		"missing file":     {Path: filepath.Join(dir, "missing.csv")},
+	} {
+		if _, err := NewReplaySource(config); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
+
+func TestReplaySourcePlaysTheWindowInOrder(t *testing.T) {
+	day := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	ticks := []Tick{
+		{Symbol: "AAPL", Timestamp: day.Add(2 * time.Minute), Price: 103, Volume: 5},
+		{Symbol: "AAPL", Timestamp: day, Price: 100, Volume: 10},
+		{Symbol: "AAPL", Timestamp: day.Add(time.Minute), Price: 98, Volume: 20},
+		// The next UTC day starts a new session from the previous close
+		{Symbol: "AAPL", Timestamp: day.Add(24 * time.Hour), Price: 105, Volume: 7},
+		// Outside the window
+		{Symbol: "AAPL", Timestamp: day.Add(-time.Minute), Price: 1},
+		{Symbol: "AAPL", Timestamp: day.Add(48 * time.Hour), Price: 1},
+	}
+	source, err := NewReplaySourceFromTicks(ReplayConfig{Start: day, End: day.Add(48 * time.Hour)}, ticks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := runSource(source, time.Second)
+	if len(updates) != 4 {
+		t.Fatalf("got %d updates, want the 4 in the window", len(updates))
+	}
+
+	first, last := updates[2], updates[3]
+	if first.CurrentPrice != 103 || first.DayOpen != 100 || first.DayHigh != 103 || first.DayLow != 98 || first.Volume != 35 || first.Change != 3 {
+		t.Errorf("end of the first day %+v, want open 100, range 98-103, volume 35 and change 3", first)
+	}
+	if last.DayOpen != 105 || last.Volume != 7 || last.Change != 2 || last.Timestamp != day.Add(24*time.Hour).Unix() {
+		t.Errorf("second day %+v, want a new session up 2 on the close of 103", last)
+	}
+
+	select {
+	case <-source.Done():
+	default:
+		t.Error("Done not closed after the tape ended")
+	}
+}
+
+func TestReplaySourceLoops(t *testing.T) {
+	start := time.Unix(1704205800, 0)
+	ticks := []Tick{
+		{Symbol: "AAPL", Timestamp: start, Price: 100},
+		{Symbol: "AAPL", Timestamp: start.Add(10 * time.Second), Price: 101},
+	}
+	source, err := NewReplaySourceFromTicks(ReplayConfig{Loop: true}, ticks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := runSource(source, 50*time.Millisecond)
+	if len(updates) < 6 {
+		t.Fatalf("got %d updates, want the tape repeated", len(updates))
+	}
+	// Each pass is shifted by the tape's span plus a second so time keeps moving forward
+	for i, want := range []int64{0, 10, 11, 21, 22, 32} {
+		if got := updates[i].Timestamp - start.Unix(); got != want {
+			t.Errorf("update %d at +%ds, want +%ds", i, got, want)
+		}
+	}
+
+	select {
+	case <-source.Done():
+		t.Error("Done closed while looping")
+	default:
+	}
+}
@@ -0,0 +1,333 @@
+package stream
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+const (
+	tradingDaysPerYear = 252
+	sessionLength      = 6*time.Hour + 30*time.Minute
+)
+
+// SymbolParams describes how a simulated symbol moves
+type SymbolParams struct {
+	BasePrice       float64
+	Drift           float64 // annualized expected return (mu)
+	Volatility      float64 // annualized volatility (sigma)
+	JumpProbability float64 // chance of a jump on each step
+	JumpSize        float64 // standard deviation of the log return of a jump
+	DailyVolume     float64 // average shares traded per session
+}
+
+// SimulatorConfig configures a SimulatorSource
+type SimulatorConfig struct {
+	Seed int64
+	// Interval is the wall-clock time between ticks; zero replays as fast as the consumer reads
+	Interval time.Duration
+	// Step is the simulated time that passes on each tick, at most one session
+	Step time.Duration
+	// Start is the simulated time of the first tick; it is moved to the open of its trading day
+	Start time.Time
+	// Live stamps updates with the wall clock rather than the simulated one, for a
+	// feed served as live prices instead of a fixed window of history
+	Live    bool
+	Symbols map[string]SymbolParams
+}
+
+// DefaultSymbolParams returns moderate parameters for a symbol starting at basePrice
+func DefaultSymbolParams(basePrice float64) SymbolParams {
+	return SymbolParams{
+		BasePrice:       basePrice,
+		Drift:           0.07,
+		Volatility:      0.25,
+		JumpProbability: 0.0005,
+		JumpSize:        0.03,
+		DailyVolume:     5000000,
+	}
+}
+
+// DefaultSimulatorConfig simulates DefaultBasePrices on one-minute steps from 2024-01-02
+func DefaultSimulatorConfig(seed int64, interval time.Duration) SimulatorConfig {
+	symbols := make(map[string]SymbolParams, len(DefaultBasePrices))
+	for symbol, price := range DefaultBasePrices {
+		symbols[symbol] = DefaultSymbolParams(price)
+	}
+
+	// Give the more volatile names more room to move
+	for symbol, vol := range map[string]float64{"TSLA": 0.55, "NVDA": 0.45, "META": 0.38, "NFLX": 0.38, "JNJ": 0.15, "JPM": 0.2} {
+		params := symbols[symbol]
+		params.Volatility = vol
+		symbols[symbol] = params
+	}
+
+	return SimulatorConfig{
+		Seed:     seed,
+		Interval: interval,
+		Step:     time.Minute,
+		Start:    time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Symbols:  symbols,
+	}
+}
+
+// simSymbol is the running state of one simulated symbol
+type simSymbol struct {
+	params    SymbolParams
+	rng       *rand.Rand
+	price     float64
+	prevClose float64
+	open      float64
+	high      float64
+	low       float64
+	volume    float64
+	resumed   bool // keep the session ResumeFrom restored when the next step opens one
+}
+
+// SimulatorSource produces a reproducible tape using geometric Brownian motion with
+// jumps. The same seed and config always produce the same sequence of updates,
+// timestamped on a simulated clock that only advances during trading sessions, or
+// on the wall clock when Live.
+type SimulatorSource struct {
+	config SimulatorConfig
+
+	mu           sync.Mutex
+	clock        time.Time // simulated time of the next tick
+	sessionOpen  time.Time
+	sessionStart bool
+	symbols      map[string]*simSymbol
+}
+
+func NewSimulatorSource(config SimulatorConfig) *SimulatorSource {
+	if config.Step <= 0 {
+		config.Step = time.Minute
+	}
+	if config.Step > sessionLength {
+		log.Printf("Simulator step %s is longer than a session, using %s", config.Step, sessionLength)
+		config.Step = sessionLength
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	}
+
+	s := &SimulatorSource{
+		config:  config,
+		symbols: make(map[string]*simSymbol),
+	}
+	s.openSession(nextTradingDay(config.Start.Add(-24 * time.Hour)))
+
+	for symbol, params := range config.Symbols {
+		s.addSymbol(symbol, params)
+	}
+
+	return s
+}
+
+// Run emits one update per symbol on each step of the simulated clock
+func (s *SimulatorSource) Run(ctx context.Context, out chan<- *pb.PriceUpdate) error {
+	log.Printf("📊 Market simulator started (seed %d)", s.config.Seed)
+
+	var tick <-chan time.Time
+	if s.config.Interval > 0 {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if err := sendAll(ctx, out, s.Step()); err != nil {
+			return err
+		}
+
+		if tick == nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+		}
+	}
+}
+
+// AddSymbol starts simulating symbol from basePrice with DefaultSymbolParams
+func (s *SimulatorSource) AddSymbol(symbol string, basePrice float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.symbols[symbol]; !exists {
+		s.addSymbol(symbol, DefaultSymbolParams(basePrice))
+	}
+}
+
+// Symbols returns the symbols the source is simulating
+func (s *SimulatorSource) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Keys(s.symbols))
+}
+
+// ResumeFrom continues simulating last.Symbol from its last published price and the
+// session statistics that came with it
+func (s *SimulatorSource) ResumeFrom(last *pb.PriceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sym, exists := s.symbols[last.Symbol]
+	if !exists {
+		s.addSymbol(last.Symbol, DefaultSymbolParams(last.CurrentPrice))
+		sym = s.symbols[last.Symbol]
+	}
+	sym.price = last.CurrentPrice
+	sym.prevClose = last.CurrentPrice - last.Change
+	sym.open, sym.high, sym.low = last.DayOpen, last.DayHigh, last.DayLow
+	if sym.open == 0 {
+		sym.open, sym.high, sym.low = last.CurrentPrice, last.CurrentPrice, last.CurrentPrice
+	}
+	sym.volume = last.Volume
+	sym.resumed = true
+}
+
+// ApplySplit restates symbol's prices and volume in post-split shares, ratio new shares per old share
+func (s *SimulatorSource) ApplySplit(symbol string, ratio float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sym, exists := s.symbols[symbol]
+	if !exists {
+		return
+	}
+	sym.price /= ratio
+	sym.prevClose /= ratio
+	sym.open /= ratio
+	sym.high /= ratio
+	sym.low /= ratio
+	sym.volume *= ratio
+	sym.params.BasePrice /= ratio
+	sym.params.DailyVolume *= ratio
+}
+
+// RemoveSymbol stops simulating symbol
+func (s *SimulatorSource) RemoveSymbol(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.symbols, symbol)
+}
+
+// Step advances the simulated clock by one step and returns the new prices in symbol order
+func (s *SimulatorSource) Step() []*pb.PriceUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.clock.Before(s.sessionOpen.Add(sessionLength)) {
+		s.closeSession()
+		s.openSession(nextTradingDay(s.sessionOpen))
+	}
+
+	dt := s.config.Step.Hours() / (sessionLength.Hours() * tradingDaysPerYear)
+	stepsPerSession := float64(sessionLength / s.config.Step)
+	first := s.sessionStart
+	s.sessionStart = false
+
+	names := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		names = append(names, symbol)
+	}
+	sort.Strings(names)
+
+	timestamp := s.clock.Unix()
+	if s.config.Live {
+		timestamp = time.Now().Unix()
+	}
+
+	updates := make([]*pb.PriceUpdate, 0, len(names))
+	for _, symbol := range names {
+		sym := s.symbols[symbol]
+		p := sym.params
+
+		logReturn := (p.Drift-p.Volatility*p.Volatility/2)*dt + p.Volatility*math.Sqrt(dt)*sym.rng.NormFloat64()
+		if p.JumpProbability > 0 && sym.rng.Float64() < p.JumpProbability {
+			logReturn += p.JumpSize * sym.rng.NormFloat64()
+		}
+		sym.price *= math.Exp(logReturn)
+
+		if first && !sym.resumed {
+			sym.open, sym.high, sym.low, sym.volume = sym.price, sym.price, sym.price, 0
+		}
+		sym.resumed = false
+		sym.high = math.Max(sym.high, sym.price)
+		sym.low = math.Min(sym.low, sym.price)
+
+		// Volume clusters around big moves
+		tickVolume := p.DailyVolume / stepsPerSession * math.Exp(0.5*sym.rng.NormFloat64()) * (1 + 50*math.Abs(logReturn))
+		sym.volume += math.Round(tickVolume)
+
+		change := sym.price - sym.prevClose
+		updates = append(updates, &pb.PriceUpdate{
+			Symbol:           symbol,
+			CurrentPrice:     sym.price,
+			Change:           change,
+			ChangePercentage: change / sym.prevClose * 100,
+			Timestamp:        timestamp,
+			Volume:           sym.volume,
+			DayHigh:          sym.high,
+			DayLow:           sym.low,
+			DayOpen:          sym.open,
+		})
+	}
+
+	s.clock = s.clock.Add(s.config.Step)
+	return updates
+}
+
+func (s *SimulatorSource) addSymbol(symbol string, params SymbolParams) {
+	// Each symbol gets its own stream so adding a symbol never changes the others' tapes
+	h := fnv.New64a()
+	h.Write([]byte(symbol))
+
+	s.symbols[symbol] = &simSymbol{
+		params:    params,
+		rng:       rand.New(rand.NewPCG(uint64(s.config.Seed), h.Sum64())),
+		price:     params.BasePrice,
+		prevClose: params.BasePrice,
+		open:      params.BasePrice,
+		high:      params.BasePrice,
+		low:       params.BasePrice,
+	}
+}
+
+// openSession moves the clock to the 09:30 UTC open of day
+func (s *SimulatorSource) openSession(day time.Time) {
+	s.sessionOpen = time.Date(day.Year(), day.Month(), day.Day(), 9, 30, 0, 0, time.UTC)
+	s.clock = s.sessionOpen
+	s.sessionStart = true
+}
+
+func (s *SimulatorSource) closeSession() {
+	for _, sym := range s.symbols {
+		sym.prevClose = sym.price
+	}
+}
+
+// nextTradingDay returns the first weekday after day
+func nextTradingDay(day time.Time) time.Time {
+	next := day.AddDate(0, 0, 1)
+	for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// simulate steps a new source from config n times and returns every update
+func simulate(config SimulatorConfig, n int) []*pb.PriceUpdate {
+	source := NewSimulatorSource(config)
+	var updates []*pb.PriceUpdate
+	for range n {
+		updates = append(updates, source.Step()...)
+	}
+	return updates
+}
+
+func sameTape(a, b []*pb.PriceUpdate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Symbol != b[i].Symbol || a[i].CurrentPrice != b[i].CurrentPrice || a[i].Volume != b[i].Volume ||
+			a[i].DayHigh != b[i].DayHigh || a[i].DayLow != b[i].DayLow || a[i].Timestamp != b[i].Timestamp {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSimulatorIsReproducible(t *testing.T) {
+	// Long enough to cross several session boundaries at one-minute steps
+	steps := 1200
+
+	first := simulate(DefaultSimulatorConfig(7, 0), steps)
+	second := simulate(DefaultSimulatorConfig(7, 0), steps)
+	if !sameTape(first, second) {
+		t.Fatal("two sources with the same seed produced different tapes")
+	}
+
+	if sameTape(first, simulate(DefaultSimulatorConfig(8, 0), steps)) {
+		t.Error("a different seed produced the same tape")
+	}
+
+	// Adding a symbol doesn't change the others' paths
+	config := DefaultSimulatorConfig(7, 0)
+	config.Symbols["NEWCO"] = DefaultSymbolParams(20)
+	var withExtra []*pb.PriceUpdate
+	for _, update := range simulate(config, steps) {
+		if update.Symbol != "NEWCO" {
+			withExtra = append(withExtra, update)
+		}
+	}
+	if !sameTape(first, withExtra) {
+		t.Error("adding a symbol changed the other symbols' tapes")
+	}
+}
+
+func TestSimulatorSessions(t *testing.T) {
+	config := DefaultSimulatorConfig(7, 0)
+	config.Symbols = map[string]SymbolParams{"AAPL": DefaultSymbolParams(100)}
+	// Start on a Friday: the second session is on Monday
+	config.Start = time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	config.Step = 30 * time.Minute
+
+	updates := simulate(config, 27)
+	open := time.Date(2024, 1, 5, 9, 30, 0, 0, time.UTC)
+	for i, update := range updates {
+		session, step := i/13, i%13
+		want := open.AddDate(0, 0, []int{0, 3, 4}[session]).Add(time.Duration(step) * config.Step)
+		if got := time.Unix(update.Timestamp, 0).UTC(); !got.Equal(want) {
+			t.Fatalf("tick %d at %s, want %s", i, got, want)
+		}
+	}
+
+	// Each session opens where it starts and closes into the next one's previous close
+	lastFriday := updates[12]
+	monday := updates[13]
+	if prevClose := monday.CurrentPrice - monday.Change; math.Abs(prevClose-lastFriday.CurrentPrice) > 1e-9 {
+		t.Errorf("Monday's previous close %.4f, want Friday's last price %.4f", prevClose, lastFriday.CurrentPrice)
+	}
+	if monday.DayOpen != monday.CurrentPrice || monday.Volume > lastFriday.Volume {
+		t.Errorf("Monday's first tick %+v didn't start a new session", monday)
+	}
+}
+
+func TestSimulatorClampsStepToASession(t *testing.T) {
+	config := DefaultSimulatorConfig(7, 0)
+	config.Symbols = map[string]SymbolParams{"AAPL": DefaultSymbolParams(100)}
+	config.Step = 8 * time.Hour
+
+	updates := simulate(config, 3)
+	for i, update := range updates {
+		if update.CurrentPrice <= 0 || update.Volume <= 0 || math.IsInf(update.Volume, 0) || math.IsNaN(update.Volume) {
+			t.Fatalf("tick %d %+v, want a finite price and volume", i, update)
+		}
+		// One tick per session, on consecutive trading days
+		if i > 0 && update.Timestamp-updates[i-1].Timestamp < int64(24*time.Hour/time.Second) {
+			t.Errorf("ticks %d and %d in the same session", i-1, i)
+		}
+	}
+}
+
+func TestSimulatorLiveTimestamps(t *testing.T) {
+	config := DefaultSimulatorConfig(7, 0)
+	config.Live = true
+
+	before := time.Now().Unix()
+	updates := simulate(config, 2)
+	after := time.Now().Unix()
+	for _, update := range updates {
+		if update.Timestamp < before || update.Timestamp > after {
+			t.Fatalf("live tick at %d, want the wall clock between %d and %d", update.Timestamp, before, after)
+		}
+	}
+
+	// Live mode only changes the timestamps, not the path
+	config.Live = false
+	for i, update := range simulate(config, 2) {
+		if update.CurrentPrice != updates[i].CurrentPrice {
+			t.Fatalf("tick %d at %.4f live and %.4f replayed", i, updates[i].CurrentPrice, update.CurrentPrice)
+		}
+	}
+}
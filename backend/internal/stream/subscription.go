@@ -0,0 +1,290 @@
+package stream
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// SlowConsumerPolicy decides what happens when a subscriber falls behind and its buffer is full
+type SlowConsumerPolicy int
+
+const (
+	// DropNewest discards the incoming update and keeps what is already queued
+	DropNewest SlowConsumerPolicy = iota
+	// DropOldest discards the oldest queued update to make room for the incoming one
+	DropOldest
+	// Conflate replaces a queued update for the same symbol with the incoming one,
+	// so a slow consumer always sees the latest price for every symbol
+	Conflate
+	// Disconnect closes the subscription; Err then returns ErrSlowConsumer
+	Disconnect
+)
+
+func (p SlowConsumerPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case Conflate:
+		return "conflate"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrSlowConsumer is reported by a Disconnect subscription that could not keep up
+var ErrSlowConsumer = errors.New("subscriber too slow, disconnected")
+
+const defaultSubscriptionBuffer = 100
+
+// SubscribeOptions configures a Subscription; the zero value is DropNewest with a
+// 100-update buffer that starts with the next live update
+type SubscribeOptions struct {
+	Policy SlowConsumerPolicy
+	Buffer int
+	// Snapshot starts the subscription with the latest price of every subscribed symbol
+	Snapshot bool
+	// Resume maps symbols to the last sequence number the subscriber saw. Missed
+	// updates still in the history are replayed first; if they have been evicted
+	// the subscriber gets the latest price instead and sees the sequence jump.
+	Resume map[string]uint64
+}
+
+// AllSymbols subscribes to every symbol PriceManager publishes, including ones added later
+const AllSymbols = "*"
+
+// Subscription delivers price updates for a set of symbols on one channel, in
+// order for each symbol. Updates are queued per subscription so one slow
+// consumer never holds up the others.
+type Subscription struct {
+	pm     *PriceManager
+	policy SlowConsumerPolicy
+	buffer int
+
+	// symbols is guarded by pm.mu
+	symbols map[string]bool
+
+	mu     sync.Mutex
+	queue  []*pb.PriceUpdate
+	closed bool
+	err    error
+
+	notify  chan struct{}
+	done    chan struct{}
+	out     chan *pb.PriceUpdate
+	dropped atomic.Uint64
+}
+
+func newSubscription(pm *PriceManager, opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultSubscriptionBuffer
+	}
+
+	s := &Subscription{
+		pm:      pm,
+		symbols: make(map[string]bool),
+		policy:  opts.Policy,
+		buffer:  opts.Buffer,
+		queue:   make([]*pb.PriceUpdate, 0, opts.Buffer),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		out:     make(chan *pb.PriceUpdate),
+	}
+	go s.pump()
+
+	return s
+}
+
+// C returns the channel updates are delivered on. It is closed when the subscription ends.
+func (s *Subscription) C() <-chan *pb.PriceUpdate {
+	return s.out
+}
+
+// Add subscribes to more symbols; AllSymbols subscribes to everything
+func (s *Subscription) Add(symbols ...string) {
+	s.pm.mu.Lock()
+	defer s.pm.mu.Unlock()
+
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return
+	}
+
+	for _, symbol := range symbols {
+		s.pm.addSubscriber(s, symbol)
+	}
+}
+
+// Remove stops delivery of symbols and discards any of their updates still queued.
+// Removing AllSymbols leaves the explicitly added symbols in place.
+func (s *Subscription) Remove(symbols ...string) {
+	s.pm.mu.Lock()
+	for _, symbol := range symbols {
+		s.pm.removeSubscriber(s, symbol)
+	}
+	s.pm.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.queue[:0]
+	for _, update := range s.queue {
+		if !slices.Contains(symbols, update.Symbol) {
+			kept = append(kept, update)
+		}
+	}
+	clear(s.queue[len(kept):])
+	s.queue = kept
+}
+
+// Symbols returns the subscribed symbols in sorted order, AllSymbols included
+func (s *Subscription) Symbols() []string {
+	s.pm.mu.RLock()
+	defer s.pm.mu.RUnlock()
+
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	return symbols
+}
+
+// Dropped returns how many updates this subscriber has missed because it fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Err returns ErrSlowConsumer if the subscription was disconnected for falling behind
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// preload queues the backfill a subscriber starts with. It bypasses the buffer
+// limit so a resume is never cut short.
+func (s *Subscription) preload(updates []*pb.PriceUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, updates...)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// offer queues an update according to the policy. It returns false if the
+// subscriber must be disconnected.
+func (s *Subscription) offer(update *pb.PriceUpdate) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return true
+	}
+
+	// Superseding a queued price loses nothing the subscriber needs, so it isn't a drop
+	if s.policy == Conflate {
+		for i, queued := range s.queue {
+			if queued.Symbol == update.Symbol {
+				s.queue[i] = update
+				return true
+			}
+		}
+	}
+
+	if len(s.queue) >= s.buffer {
+		switch s.policy {
+		case DropOldest, Conflate:
+			s.recordDrop(s.queue[0].Symbol)
+			s.queue = append(s.queue[:0], s.queue[1:]...)
+		case Disconnect:
+			s.dropped.Add(1)
+			s.err = ErrSlowConsumer
+			return false
+		default:
+			s.recordDrop(update.Symbol)
+			return true
+		}
+	}
+
+	s.queue = append(s.queue, update)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// recordDrop counts a missed update, logging the first and then every thousandth
+func (s *Subscription) recordDrop(symbol string) {
+	n := s.dropped.Add(1)
+	if n == 1 || n%1000 == 0 {
+		log.Printf("⚠️  Slow %s subscriber has missed %d updates (latest %s)", s.policy, n, symbol)
+	}
+}
+
+// close ends the subscription; the pump closes C once it stops.
+// It reports false if the subscription was already closed.
+func (s *Subscription) close() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.closed = true
+	close(s.done)
+
+	return true
+}
+
+// pump moves queued updates onto C, in order, as fast as the consumer reads them
+func (s *Subscription) pump() {
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		var next *pb.PriceUpdate
+		if len(s.queue) > 0 {
+			next = s.queue[0]
+			s.queue = append(s.queue[:0], s.queue[1:]...)
+		}
+		s.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case s.out <- next:
+		case <-s.done:
+			return
+		}
+	}
+}
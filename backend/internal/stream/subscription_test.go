@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/chinnareddy578/realtime-portfolio-tracker/backend/pkg/pb"
+)
+
+// queueOnly returns a subscription with nothing moving its queue onto C, so what
+// offer keeps and drops can be read straight off the queue
+func queueOnly(policy SlowConsumerPolicy, buffer int) *Subscription {
+	return &Subscription{
+		policy: policy,
+		buffer: buffer,
+		queue:  make([]*pb.PriceUpdate, 0, buffer),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// ticks parses "AAPL:1" style labels into updates
+func ticks(labels ...string) []*pb.PriceUpdate {
+	updates := make([]*pb.PriceUpdate, len(labels))
+	for i, label := range labels {
+		symbol, seq, _ := strings.Cut(label, ":")
+		sequence, _ := strconv.ParseUint(seq, 10, 64)
+		updates[i] = &pb.PriceUpdate{Symbol: symbol, Sequence: sequence}
+	}
+	return updates
+}
+
+func labels(updates []*pb.PriceUpdate) []string {
+	out := make([]string, len(updates))
+	for i, update := range updates {
+		out[i] = fmt.Sprintf("%s:%d", update.Symbol, update.Sequence)
+	}
+	return out
+}
+
+func TestSlowConsumerPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SlowConsumerPolicy
+		offered []string
+		queued  []string
+		dropped uint64
+	}{
+		{
+			name:    "drop newest",
+			policy:  DropNewest,
+			offered: []string{"A:1", "B:1", "C:1", "D:1", "A:2"},
+			queued:  []string{"A:1", "B:1", "C:1"},
+			dropped: 2,
+		},
+		{
+			name:    "drop oldest",
+			policy:  DropOldest,
+			offered: []string{"A:1", "B:1", "C:1", "D:1", "A:2"},
+			queued:  []string{"C:1", "D:1", "A:2"},
+			dropped: 2,
+		},
+		{
+			name:   "conflate within the buffer",
+			policy: Conflate,
+			// Newer prices replace queued ones in place and nothing is lost
+			offered: []string{"A:1", "B:1", "A:2", "A:3", "B:2"},
+			queued:  []string{"A:3", "B:2"},
+			dropped: 0,
+		},
+		{
+			name:   "conflate past the buffer",
+			policy: Conflate,
+			// D:1 finds no queued D and a full buffer, so A's price is lost
+			offered: []string{"A:1", "B:1", "A:2", "C:1", "D:1", "C:2"},
+			queued:  []string{"B:1", "C:2", "D:1"},
+			dropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := queueOnly(tt.policy, 3)
+			for _, update := range ticks(tt.offered...) {
+				if !sub.offer(update) {
+					t.Fatalf("offer of %s:%d asked for a disconnect", update.Symbol, update.Sequence)
+				}
+			}
+
+			if got := labels(sub.queue); !slices.Equal(got, tt.queued) {
+				t.Errorf("queued %v, want %v", got, tt.queued)
+			}
+			if got := sub.Dropped(); got != tt.dropped {
+				t.Errorf("dropped %d, want %d", got, tt.dropped)
+			}
+			if sub.Err() != nil {
+				t.Errorf("err %v, want none", sub.Err())
+			}
+		})
+	}
+}
+
+func TestDisconnectPolicy(t *testing.T) {
+	sub := queueOnly(Disconnect, 3)
+	for _, update := range ticks("A:1", "B:1", "C:1") {
+		if !sub.offer(update) {
+			t.Fatalf("disconnected with room in the buffer")
+		}
+	}
+
+	if sub.offer(ticks("D:1")[0]) {
+		t.Fatal("offer into a full buffer didn't ask for a disconnect")
+	}
+	if !errors.Is(sub.Err(), ErrSlowConsumer) {
+		t.Errorf("err %v, want ErrSlowConsumer", sub.Err())
+	}
+	if sub.Dropped() != 1 {
+		t.Errorf("dropped %d, want 1", sub.Dropped())
+	}
+	if got := labels(sub.queue); !slices.Equal(got, []string{"A:1", "B:1", "C:1"}) {
+		t.Errorf("queued %v, want the updates before the disconnect", got)
+	}
+}
+
+func TestSubscriptionDeliversInOrder(t *testing.T) {
+	sub := newSubscription(nil, SubscribeOptions{Policy: DropNewest, Buffer: 10})
+	sub.preload(ticks("A:1", "A:2"))
+	for _, update := range ticks("B:1", "A:3") {
+		sub.offer(update)
+	}
+
+	var got []*pb.PriceUpdate
+	for range 4 {
+		select {
+		case update := <-sub.C():
+			got = append(got, update)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v, then nothing", labels(got))
+		}
+	}
+	if want := []string{"A:1", "A:2", "B:1", "A:3"}; !slices.Equal(labels(got), want) {
+		t.Errorf("delivered %v, want %v", labels(got), want)
+	}
+
+	sub.close()
+	if _, ok := <-sub.C(); ok {
+		t.Error("C still open after close")
+	}
+}
@@ -0,0 +1,27 @@
+-- Applied migrations. Every migration records itself here as its last statement, and
+-- `make db-migrate` only runs the files that are not recorded yet, each exactly once.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(255) PRIMARY KEY, -- the file name without .sql
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Databases created before migrations were recorded have already run some of them.
+-- Record each one whose schema is in place, so it is not run a second time.
+INSERT INTO schema_migrations (version)
+SELECT version
+FROM (VALUES
+    ('001_init', to_regclass('users') IS NOT NULL),
+    ('002_price_history', to_regclass('price_bars') IS NOT NULL),
+    ('003_transactions', to_regclass('transactions') IS NOT NULL),
+    ('004_cost_basis', EXISTS (SELECT 1 FROM information_schema.columns
+                               WHERE table_name = 'transactions' AND column_name = 'lot_method')),
+    ('005_cash', to_regclass('cash_entries') IS NOT NULL),
+    ('006_corporate_actions', to_regclass('corporate_actions') IS NOT NULL),
+    ('007_currencies', EXISTS (SELECT 1 FROM information_schema.columns
+                               WHERE table_name = 'users' AND column_name = 'base_currency')),
+    ('008_portfolios', to_regclass('portfolios') IS NOT NULL)
+) AS existing (version, applied)
+WHERE applied
+ON CONFLICT (version) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES ('000_schema_migrations') ON CONFLICT (version) DO NOTHING;
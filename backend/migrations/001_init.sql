@@ -71,4 +71,6 @@ VALUES
     ('stock-10', 'demo-user-1', 'NFLX', 'Netflix Inc.', 22, 380.00, EXTRACT(EPOCH FROM NOW())::BIGINT),
     ('stock-11', 'demo-user-1', 'V', 'Visa Inc.', 40, 220.00, EXTRACT(EPOCH FROM NOW())::BIGINT),
     ('stock-12', 'demo-user-1', 'WMT', 'Walmart Inc.', 35, 140.00, EXTRACT(EPOCH FROM NOW())::BIGINT)
-ON CONFLICT (id) DO NOTHING;
\ No newline at end of file
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES ('001_init') ON CONFLICT (version) DO NOTHING;
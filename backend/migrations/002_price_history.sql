@@ -0,0 +1,33 @@
+-- Raw price ticks recorded from the live price stream
+CREATE TABLE IF NOT EXISTS price_ticks (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(10) NOT NULL,
+    sequence BIGINT NOT NULL,
+    price DECIMAL(18, 4) NOT NULL,
+    volume DECIMAL(20, 2) NOT NULL DEFAULT 0, -- shares traded since the previous tick
+    timestamp BIGINT NOT NULL,
+    -- Every replica records the same shared stream; keep one copy of each tick
+    UNIQUE (symbol, timestamp, sequence)
+);
+
+-- OHLCV bars (matching HistoricalPrice) built from the ticks.
+-- 1m bars are built from ticks, 5m from 1m, 1h from 5m and 1d from 1h.
+CREATE TABLE IF NOT EXISTS price_bars (
+    symbol VARCHAR(10) NOT NULL,
+    timeframe VARCHAR(3) NOT NULL CHECK (timeframe IN ('1m', '5m', '1h', '1d')),
+    timestamp BIGINT NOT NULL, -- start of the bar
+    open DECIMAL(18, 4) NOT NULL,
+    high DECIMAL(18, 4) NOT NULL,
+    low DECIMAL(18, 4) NOT NULL,
+    close DECIMAL(18, 4) NOT NULL,
+    volume DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (symbol, timeframe, timestamp)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_ticks_timestamp ON price_ticks(timestamp);
+CREATE INDEX IF NOT EXISTS idx_price_bars_timeframe_timestamp ON price_bars(timeframe, timestamp);
+
+GRANT ALL PRIVILEGES ON price_ticks, price_bars TO portfolio_user;
+GRANT USAGE, SELECT ON SEQUENCE price_ticks_id_seq TO portfolio_user;
+
+INSERT INTO schema_migrations (version) VALUES ('002_price_history') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,30 @@
+-- Buy and sell ledger. Positions and average cost are derived from it; each BUY opens a lot.
+CREATE TABLE IF NOT EXISTS transactions (
+    id VARCHAR(100) PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    name VARCHAR(255),
+    type VARCHAR(4) NOT NULL CHECK (type IN ('BUY', 'SELL')),
+    quantity DECIMAL(18, 8) NOT NULL CHECK (quantity > 0),
+    price DECIMAL(18, 4) NOT NULL CHECK (price >= 0), -- per share, before fees
+    fees DECIMAL(18, 4) NOT NULL DEFAULT 0 CHECK (fees >= 0),
+    timestamp BIGINT NOT NULL, -- Unix time of the trade
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_symbol ON transactions(user_id, symbol, timestamp);
+
+-- Move every stocks row into the ledger as the BUY that opened it. Like every
+-- migration this runs once; moved rows are deleted all the same, so stocks is left
+-- empty rather than disagreeing with the ledger.
+INSERT INTO transactions (id, user_id, symbol, name, type, quantity, price, fees, timestamp, created_at)
+SELECT id, user_id, symbol, name, 'BUY', quantity, purchase_price, 0, purchase_date, created_at
+FROM stocks
+ON CONFLICT (id) DO NOTHING;
+
+DELETE FROM stocks WHERE id IN (SELECT id FROM transactions);
+
+GRANT ALL PRIVILEGES ON transactions TO portfolio_user;
+
+INSERT INTO schema_migrations (version) VALUES ('003_transactions') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,7 @@
+-- How each sell picks the buy lots it closes, for realized gains
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS lot_method VARCHAR(12) NOT NULL DEFAULT 'FIFO'
+        CHECK (lot_method IN ('FIFO', 'LIFO', 'HIFO', 'SPECIFIC_LOT')),
+    ADD COLUMN IF NOT EXISTS lot_id VARCHAR(100); -- the BUY a SPECIFIC_LOT sell draws from
+
+INSERT INTO schema_migrations (version) VALUES ('004_cost_basis') ON CONFLICT (version) DO NOTHING;
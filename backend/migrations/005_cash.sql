@@ -0,0 +1,34 @@
+-- Cash movements other than trades. Buys and sells settle against the same balance,
+-- which is derived from these entries and the transactions ledger.
+CREATE TABLE IF NOT EXISTS cash_entries (
+    id VARCHAR(100) PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('DEPOSIT', 'WITHDRAWAL', 'DIVIDEND', 'FEE', 'INTEREST')),
+    amount DECIMAL(18, 4) NOT NULL CHECK (amount > 0), -- the type gives the direction
+    symbol VARCHAR(10), -- the paying stock, for dividends
+    description VARCHAR(255),
+    timestamp BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_entries_user ON cash_entries(user_id, timestamp);
+
+GRANT ALL PRIVILEGES ON cash_entries TO portfolio_user;
+
+-- Trades recorded before the cash account were paid for with money it never saw.
+-- Give each such user an opening deposit covering them, so balances start at zero.
+-- Users who already have cash entries are using the account, and their later trades
+-- were paid from it, so running this again must leave them alone.
+INSERT INTO cash_entries (id, user_id, type, amount, description, timestamp)
+SELECT 'opening-' || user_id, user_id, 'DEPOSIT',
+       SUM(CASE WHEN type = 'BUY' THEN quantity * price + fees ELSE fees - quantity * price END),
+       'Opening balance for trades recorded before the cash account',
+       MIN(timestamp)
+FROM transactions t
+WHERE NOT EXISTS (SELECT 1 FROM cash_entries c WHERE c.user_id = t.user_id)
+GROUP BY user_id
+HAVING SUM(CASE WHEN type = 'BUY' THEN quantity * price + fees ELSE fees - quantity * price END) > 0
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES ('005_cash') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,27 @@
+-- Splits and symbol changes. Once applied_at is set the action has been folded into
+-- transactions, price_alerts, cash_entries, price_ticks and price_bars, and the counts
+-- record what it changed.
+CREATE TABLE IF NOT EXISTS corporate_actions (
+    id VARCHAR(100) PRIMARY KEY,
+    symbol VARCHAR(10) NOT NULL,
+    type VARCHAR(13) NOT NULL CHECK (type IN ('SPLIT', 'SYMBOL_CHANGE')),
+    ex_date BIGINT NOT NULL, -- Unix time; trades before it are pre-split
+    split_to DECIMAL(18, 8) CHECK (split_to > 0), -- split_to new shares for every split_from held
+    split_from DECIMAL(18, 8) CHECK (split_from > 0),
+    cash_in_lieu_price DECIMAL(18, 4) CHECK (cash_in_lieu_price >= 0),
+    new_symbol VARCHAR(10),
+    applied_at BIGINT,
+    transactions_adjusted BIGINT NOT NULL DEFAULT 0,
+    alerts_adjusted BIGINT NOT NULL DEFAULT 0,
+    history_adjusted BIGINT NOT NULL DEFAULT 0,
+    cash_in_lieu_sales BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (type <> 'SPLIT' OR (split_to IS NOT NULL AND split_from IS NOT NULL)),
+    CHECK (type <> 'SYMBOL_CHANGE' OR new_symbol IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_pending ON corporate_actions(ex_date) WHERE applied_at IS NULL;
+
+GRANT ALL PRIVILEGES ON corporate_actions TO portfolio_user;
+
+INSERT INTO schema_migrations (version) VALUES ('006_corporate_actions') ON CONFLICT (version) DO NOTHING;
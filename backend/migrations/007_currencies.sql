@@ -0,0 +1,13 @@
+-- Multi-currency: trades are priced in their symbol's listing currency, cash entries in
+-- the currency they were paid in, and each user's totals are reported in a base currency.
+-- Everything recorded so far was in USD.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS base_currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE cash_entries ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Alert prices get the four decimals trade prices have; two are too few for some
+-- currencies' quotes and for split-adjusted targets
+ALTER TABLE price_alerts ALTER COLUMN target_price TYPE DECIMAL(18, 4);
+ALTER TABLE price_alerts ALTER COLUMN triggered_price TYPE DECIMAL(18, 4);
+
+INSERT INTO schema_migrations (version) VALUES ('007_currencies') ON CONFLICT (version) DO NOTHING;
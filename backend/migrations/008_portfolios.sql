@@ -0,0 +1,41 @@
+-- Named portfolios. Every trade, cash entry and alert belongs to one of its user's
+-- portfolios; each user has a default portfolio, 'default-' || user_id, that takes
+-- whatever is recorded without naming one.
+CREATE TABLE IF NOT EXISTS portfolios (
+    id VARCHAR(100) PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255),
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_portfolios_default ON portfolios(user_id) WHERE is_default;
+
+GRANT ALL PRIVILEGES ON portfolios TO portfolio_user;
+
+-- Everything recorded so far moves into its user's default portfolio
+INSERT INTO portfolios (id, user_id, name, is_default)
+SELECT 'default-' || id, id, 'Main', TRUE
+FROM users
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS portfolio_id VARCHAR(100) REFERENCES portfolios(id);
+ALTER TABLE cash_entries ADD COLUMN IF NOT EXISTS portfolio_id VARCHAR(100) REFERENCES portfolios(id);
+ALTER TABLE price_alerts ADD COLUMN IF NOT EXISTS portfolio_id VARCHAR(100) REFERENCES portfolios(id) ON DELETE CASCADE;
+
+UPDATE transactions SET portfolio_id = 'default-' || user_id WHERE portfolio_id IS NULL;
+UPDATE cash_entries SET portfolio_id = 'default-' || user_id WHERE portfolio_id IS NULL;
+UPDATE price_alerts SET portfolio_id = 'default-' || user_id WHERE portfolio_id IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE cash_entries ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE price_alerts ALTER COLUMN portfolio_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_portfolio ON transactions(portfolio_id, symbol, timestamp);
+CREATE INDEX IF NOT EXISTS idx_cash_entries_portfolio ON cash_entries(portfolio_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_alerts_portfolio ON price_alerts(portfolio_id);
+
+INSERT INTO schema_migrations (version) VALUES ('008_portfolios') ON CONFLICT (version) DO NOTHING;
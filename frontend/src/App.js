@@ -548,7 +548,7 @@ const apiClient = {
     }
   },
 
-  removeStock: async (userId, stockId) => {
+  removeStock: async (userId, symbol) => {
     try {
       // Mock API call
       await new Promise(resolve => setTimeout(resolve, 300));
@@ -688,9 +688,9 @@ function App() {
   };
 
   // Remove stock
-  const removeStock = async (stockId) => {
+  const removeStock = async (symbol) => {
     try {
-      const response = await apiClient.removeStock(userId, stockId);
+      const response = await apiClient.removeStock(userId, symbol);
 
       if (response.success) {
         alert('Stock removed successfully!');
@@ -952,7 +952,7 @@ function App() {
                       </div>
                     </div>
                     <button
-                      onClick={() => removeStock(id)}
+                      onClick={() => removeStock(symbol)}
                       className="btn btn-danger btn-sm"
                     >
                       Remove